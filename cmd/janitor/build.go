@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/wdm0006/janitor/pkg/expr"
+	j "github.com/wdm0006/janitor/pkg/janitor"
+	"github.com/wdm0006/janitor/pkg/runner"
+)
+
+// built is a pipeline built from the steps of a config.
+type built struct {
+	p          *j.Pipeline
+	origin     []int    // config index of each pipeline step
+	names      []string // label of each pipeline step
+	quarantine bool     // a validator quarantines rows
+}
+
+// buildPipeline builds the steps of cfg. Problems in a step's config are
+// reported to problems with its index. Column patterns resolve against the
+// schema each step sees: inputSchema's, reshaped by the steps before it.
+func buildPipeline(cfg runner.Config, inputSchema func() (j.Schema, error), problems *configProblems) *built {
+	b := &built{p: j.NewPipeline()}
+	schemaAt := func() (j.Schema, error) {
+		in, err := inputSchema()
+		if err != nil {
+			return j.Schema{}, err
+		}
+		return b.p.OutputSchema(in)
+	}
+	for i, raw := range cfg.Steps {
+		var probe map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &probe); err != nil {
+			problems.add(i, "", fmt.Errorf("want an object of step name to params: %v", err), true)
+			continue
+		}
+		// "when" limits the step to rows matching an expression
+		var where j.Predicate
+		var whenSrc string
+		if w, ok := probe["when"]; ok {
+			delete(probe, "when")
+			if err := json.Unmarshal(w, &whenSrc); err != nil {
+				problems.add(i, "when", fmt.Errorf("want an expression string: %v", err), true)
+				continue
+			}
+			e, err := expr.Parse(whenSrc)
+			if err != nil {
+				problems.add(i, "when", err, true)
+				continue
+			}
+			where = e
+		}
+		for k, v := range probe {
+			spec, ok := j.LookupStep(k)
+			if !ok {
+				problems.add(i, k, fmt.Errorf("unknown step"), false)
+				continue
+			}
+			sb := &j.StepBuilder{
+				Schema: schemaAt,
+				Add: func(t j.Transform, label string) {
+					if v, ok := t.(j.Validator); ok && v.FailAction() == j.ActionQuarantine {
+						b.quarantine = true
+					}
+					if where != nil {
+						t = j.When(where, t)
+						label += " when " + whenSrc
+					}
+					b.p.Add(t)
+					b.origin = append(b.origin, i)
+					b.names = append(b.names, label)
+				},
+				Warn: func(err error) { problems.add(i, k, err, false) },
+			}
+			if err := spec.Build(v, sb); err != nil {
+				problems.add(i, k, err, true)
+			}
+		}
+	}
+	if problems.errors > 0 {
+		// check the steps that were built too, so every problem is reported at once
+		if in, err := inputSchema(); err == nil {
+			problems.check(b, in)
+		}
+	}
+	return b
+}
+
+// cachedSchema returns a function reading the input schema on its first call
+// only, so the input is opened early only when something needs its schema.
+func cachedSchema(read func() (j.Schema, error)) func() (j.Schema, error) {
+	var s *j.Schema
+	return func() (j.Schema, error) {
+		if s == nil {
+			in, err := read()
+			if err != nil {
+				return j.Schema{}, err
+			}
+			s = &in
+		}
+		return *s, nil
+	}
+}
+
+// configProblems reports problems in the steps of a config, each once, with
+// the index of the step in the config. Fatal problems are errors; the others
+// are warnings unless strict.
+type configProblems struct {
+	strict bool
+	seen   map[string]bool
+	errors int
+}
+
+func newConfigProblems(strict bool) *configProblems {
+	return &configProblems{strict: strict, seen: map[string]bool{}}
+}
+
+func (c *configProblems) add(step int, name string, err error, fatal bool) {
+	msg := fmt.Sprintf("steps[%d]", step)
+	if name != "" {
+		msg += " " + name
+	}
+	msg += ": " + err.Error()
+	if c.seen[msg] {
+		return
+	}
+	c.seen[msg] = true
+	if fatal || c.strict {
+		c.errors++
+		fmt.Fprintf(os.Stderr, "error: %s\n", msg)
+	} else {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", msg)
+	}
+}
+
+// check validates the built steps against in.
+func (c *configProblems) check(b *built, in j.Schema) {
+	for _, e := range b.p.Validate(in) {
+		c.add(b.origin[e.Step], e.Name, e.Err, e.Fatal)
+	}
+}
+
+// failed reports whether any problem was an error.
+func (c *configProblems) failed() bool { return c.errors > 0 }
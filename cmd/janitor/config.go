@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/wdm0006/janitor/pkg/runner"
+)
+
+func configCmd(args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "schema":
+			return configSchemaCmd(args[1:])
+		case "check":
+			return configCheckCmd(args[1:])
+		}
+	}
+	w, code := os.Stderr, 2
+	if len(args) > 0 && isHelp(args[0]) {
+		w, code = os.Stdout, 0
+	}
+	fmt.Fprintln(w, "usage: janitor config schema\n       janitor config check <file>...")
+	return code
+}
+
+func configSchemaCmd(args []string) int {
+	fs := newFlagSet("config schema", "janitor config schema",
+		"Print the JSON Schema of config files, with every registered step and its\n"+
+			"params, for editors to complete and check configs with.")
+	if code, ok := parseFlags(fs, args, 0); !ok {
+		return code
+	}
+	b, err := json.MarshalIndent(runner.ConfigSchema(), "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println(string(b))
+	return 0
+}
+
+func configCheckCmd(args []string) int {
+	fs := newFlagSet("config check", "janitor config check <file>...",
+		"Check JSON, YAML or TOML config files against the config schema, and the\n"+
+			"step files they include, printing each problem with its line and column.\n"+
+			"Files that pass are then loaded, resolving ${name} references, and their\n"+
+			"input and output settings are checked. Nothing is read from the input.\n\n"+
+			"Exits 0 when every file passes and 2 otherwise.")
+	if code, ok := parseFlags(fs, args, -1); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	code := 0
+	for _, path := range fs.Args() {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 2
+			continue
+		}
+		if errs := runner.CheckConfig(path, b); len(errs) > 0 {
+			for _, e := range errs {
+				fmt.Fprintln(os.Stderr, e)
+			}
+			code = 2
+			continue
+		}
+		var cfg runner.Config
+		if err := runner.ParseConfig(path, b, &cfg); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 2
+			continue
+		}
+		if err := cfg.CheckInput(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			code = 2
+			continue
+		}
+		if err := cfg.CheckOutput(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			code = 2
+			continue
+		}
+		fmt.Printf("%s: ok\n", path)
+	}
+	return code
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
+	"github.com/wdm0006/janitor/pkg/runner"
+)
+
+func diffCmd(args []string) int {
+	fs := newFlagSet("diff", "janitor diff [flags] <old> <new>",
+		"Compare two datasets: their columns and types, their row counts and, row\n"+
+			"by row, the values of the columns they share. Input types come from the\n"+
+			"file extensions (.csv, .jsonl, .ndjson, .parquet, optionally .gz) unless\n"+
+			"--type is given.\n\n"+
+			"Exits 0 when the datasets match, 1 when they differ and 2 on errors.")
+	typ := fs.String("type", "", "Input type of both files: csv, jsonl or parquet")
+	hasHeader := fs.Bool("has-header", true, "CSV files have a header row")
+	delim := fs.String("delimiter", "", "CSV field delimiter (default ,)")
+	schemaOnly := fs.Bool("schema-only", false, "Compare the inferred schemas only, without reading every row")
+	maxValues := fs.Int("max-values", 10, "Differing values to print")
+	if code, ok := parseFlags(fs, args, 2); !ok {
+		return code
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+	var cfgs [2]runner.Config
+	for i, path := range fs.Args() {
+		cfg := &cfgs[i]
+		cfg.Input.Path = path
+		cfg.Input.Type = *typ
+		if cfg.Input.Type == "" {
+			if cfg.Input.Type = runner.TypeFromPath(path); cfg.Input.Type == "" {
+				fmt.Fprintf(os.Stderr, "%s: cannot tell the input type from the extension; use --type\n", path)
+				return 2
+			}
+		}
+		cfg.Input.HasHeader = *hasHeader
+		cfg.Input.Delimiter = *delim
+		if err := cfg.CheckInput(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+
+	var frames [2]*j.Frame
+	var schemas [2]j.Schema
+	for i, cfg := range cfgs {
+		var err error
+		if *schemaOnly {
+			schemas[i], err = runner.InferSchema(cfg, 1000)
+		} else if frames[i], err = runner.ReadFrame(cfg, nil, nil, nil); err == nil {
+			schemas[i] = frames[i].Schema()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", cfg.Input.Path, err)
+			return 2
+		}
+	}
+
+	fmt.Printf("--- %s\n+++ %s\n", cfgs[0].Input.Path, cfgs[1].Input.Path)
+	differ := false
+	for _, cs := range schemas[0].Columns {
+		if schemas[1].Index(cs.Name) < 0 {
+			fmt.Printf("- column %s (%s)\n", cs.Name, cs.Type)
+			differ = true
+		}
+	}
+	var shared []string
+	for _, cs := range schemas[1].Columns {
+		i := schemas[0].Index(cs.Name)
+		switch {
+		case i < 0:
+			fmt.Printf("+ column %s (%s)\n", cs.Name, cs.Type)
+			differ = true
+		case schemas[0].Columns[i].Type != cs.Type:
+			fmt.Printf("~ column %s: %s -> %s\n", cs.Name, schemas[0].Columns[i].Type, cs.Type)
+			differ = true
+		default:
+			shared = append(shared, cs.Name)
+		}
+	}
+	if *schemaOnly {
+		return diffExit(differ)
+	}
+
+	rows := [2]int{frames[0].Rows(), frames[1].Rows()}
+	if rows[0] != rows[1] {
+		fmt.Printf("~ rows: %d -> %d\n", rows[0], rows[1])
+		differ = true
+	}
+	// values are compared by position, over the rows both datasets have
+	n := min(rows[0], rows[1])
+	changed, shown := 0, 0
+	for r := 0; r < n; r++ {
+		rowChanged := false
+		for _, name := range shared {
+			a, b := cellString(frames[0], name, r), cellString(frames[1], name, r)
+			if a == b {
+				continue
+			}
+			rowChanged = true
+			if shown < *maxValues {
+				fmt.Printf("~ row %d %s: %s -> %s\n", r, name, a, b)
+				shown++
+			}
+		}
+		if rowChanged {
+			changed++
+		}
+	}
+	if changed > 0 {
+		fmt.Printf("~ values: %d of %d rows differ\n", changed, n)
+		differ = true
+	}
+	return diffExit(differ)
+}
+
+func diffExit(differ bool) int {
+	if differ {
+		return 1
+	}
+	fmt.Println("no differences")
+	return 0
+}
+
+// cellString formats row r of the named column for comparison and display.
+func cellString(f *j.Frame, name string, r int) string {
+	col, _ := f.ColumnByName(name)
+	if col.IsNull(r) {
+		return "null"
+	}
+	switch c := col.(type) {
+	case *j.BoolColumn:
+		v, _ := c.Get(r)
+		return strconv.FormatBool(v)
+	case *j.IntColumn:
+		v, _ := c.Get(r)
+		return strconv.FormatInt(v, 10)
+	case *j.FloatColumn:
+		v, _ := c.Get(r)
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case *j.StringColumn:
+		v, _ := c.Get(r)
+		return strconv.Quote(v)
+	case *j.TimeColumn:
+		v, _ := c.Get(r)
+		return v.Format(time.RFC3339Nano)
+	}
+	return "?"
+}
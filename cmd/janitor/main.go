@@ -1,732 +1,239 @@
 package main
 
 import (
-    "context"
-    "encoding/json"
-    "flag"
-    "fmt"
-    "io"
-    "log"
-    "net/http"
-    _ "net/http/pprof"
-    "os"
-    "path/filepath"
-    "runtime/pprof"
-    "strings"
-    "time"
-
-    csvio "github.com/wdm0006/janitor/pkg/io/csvio"
-	jsonlio "github.com/wdm0006/janitor/pkg/io/jsonlio"
-	parquetio "github.com/wdm0006/janitor/pkg/io/parquetio"
-    j "github.com/wdm0006/janitor/pkg/janitor"
-    profpkg "github.com/wdm0006/janitor/pkg/profile"
-    imp "github.com/wdm0006/janitor/pkg/transform/impute"
-    outl "github.com/wdm0006/janitor/pkg/transform/outliers"
-    std "github.com/wdm0006/janitor/pkg/transform/standardize"
-    val "github.com/wdm0006/janitor/pkg/transform/validate"
-    toml "github.com/pelletier/go-toml/v2"
-    yaml "gopkg.in/yaml.v3"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	iox "github.com/wdm0006/janitor/pkg/io/ioutils"
+	"github.com/wdm0006/janitor/pkg/runner"
+	// built-in steps register themselves with the step registry
+	_ "github.com/wdm0006/janitor/pkg/transform/columns"
+	_ "github.com/wdm0006/janitor/pkg/transform/impute"
+	_ "github.com/wdm0006/janitor/pkg/transform/outliers"
+	_ "github.com/wdm0006/janitor/pkg/transform/rows"
+	_ "github.com/wdm0006/janitor/pkg/transform/standardize"
+	_ "github.com/wdm0006/janitor/pkg/transform/validate"
 )
 
 var (
 	version = "0.1.0-dev"
 )
 
-type Config struct {
-    Input struct {
-        Path      string `json:"path"`
-        Type      string `json:"type"` // csv|jsonl (default csv)
-        HasHeader bool   `json:"has_header"`
-        Delimiter string `json:"delimiter"`
-        CSVStrict bool   `json:"csv_strict"`
-    } `json:"input"`
-    Output struct {
-        Path      string `json:"path"`
-        Type      string `json:"type"` // csv|jsonl (default csv)
-        Delimiter string `json:"delimiter"`
-        PartitionBy []string `json:"partition_by"`
-    } `json:"output"`
-    Steps []json.RawMessage `json:"steps"`
+// command is a janitor subcommand; run returns the exit code.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
 }
 
-func main() {
-    showVersion := flag.Bool("version", false, "Print version and exit")
-    configPath := flag.String("config", "", "Path to cleaning config (JSON/YAML/TOML)")
-    chunkSize := flag.Int("chunk-size", 0, "Enable streaming with chunk size (rows per chunk). 0 disables streaming.")
-    verbose := flag.Bool("verbose", false, "Print progress and a summary")
-    prof := flag.Bool("profile", false, "Profile the input: print column stats and exit")
-    profTopK := flag.Int("profile-topk", 5, "Top-K frequent values to show for string/time columns")
-    profJSON := flag.Bool("profile-json", false, "Emit profile in JSON format")
-    expectedRows := flag.Int("expected-rows", 0, "Optional expected total rows for ETA in streaming progress")
-    cpuProfile := flag.String("cpu-profile", "", "Write CPU profile to file (pprof)")
-    memProfile := flag.String("mem-profile", "", "Write heap profile to file on exit (pprof)")
-    pprofAddr := flag.String("pprof-addr", "", "Serve net/http/pprof on this address (e.g., :6060)")
-    metricsAddr := flag.String("metrics-addr", "", "Serve expvar metrics and /healthz on this address (e.g., :9090)")
-    logJSON := flag.Bool("log-json", false, "Emit progress logs as JSON lines")
-    dryRun := flag.Bool("dry-run", false, "Infer schema and print planned steps, without reading/writing data")
-    flag.Parse()
+func commands() []command {
+	return []command{
+		{"run", "Clean the input of a config and write it to its output", runCmd},
+		{"profile", "Print column statistics of the input of a config", profileCmd},
+		{"schema", "Infer the schema of the input of a config (schema infer)", schemaCmd},
+		{"validate", "Check a config's steps, and optionally its data, without writing output", validateCmd},
+		{"steps", "List the steps configs can use", stepsCmd},
+		{"diff", "Compare the schemas and values of two datasets", diffCmd},
+		{"config", "Print the config JSON Schema or check config files against it", configCmd},
+	}
+}
 
-	if *showVersion {
+func main() {
+	args := os.Args[1:]
+	if len(args) == 0 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	switch {
+	case args[0] == "version" || args[0] == "-version" || args[0] == "--version":
 		fmt.Println("janitor", version)
 		return
+	case isHelp(args[0]) && len(args) > 1:
+		// "janitor help run" is "janitor run -h"
+		args = []string{args[1], "-h"}
+	case isHelp(args[0]):
+		usage(os.Stdout)
+		return
 	}
-
-	if *configPath == "" {
-		fmt.Fprintln(os.Stderr, "no config provided; nothing to do. try --config <file> or --version")
-		os.Exit(2)
+	if strings.HasPrefix(args[0], "-") {
+		// flags without a command are a run, as before there were commands
+		os.Exit(runCmd(args))
 	}
-
-    b, err := os.ReadFile(*configPath)
-    if err != nil {
-        fmt.Fprintln(os.Stderr, err)
-        os.Exit(1)
-    }
-    var cfg Config
-    if err := parseConfig(*configPath, b, &cfg); err != nil {
-        fmt.Fprintln(os.Stderr, err)
-        os.Exit(1)
-    }
-
-    // Observability: pprof + expvar servers
-    if *pprofAddr != "" {
-        go func() {
-            log.Printf("pprof listening on %s", *pprofAddr)
-            _ = http.ListenAndServe(*pprofAddr, nil)
-        }()
-    }
-    if *metricsAddr != "" {
-        go func() {
-            http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("ok")) })
-            log.Printf("metrics listening on %s", *metricsAddr)
-            _ = http.ListenAndServe(*metricsAddr, nil)
-        }()
-    }
-    if *cpuProfile != "" {
-        f, err := os.Create(*cpuProfile)
-        if err != nil { log.Fatalf("cpu profile: %v", err) }
-        _ = pprof.StartCPUProfile(f)
-        defer func() { pprof.StopCPUProfile(); _ = f.Close() }()
-    }
-    if *memProfile != "" {
-        defer func() {
-            f, err := os.Create(*memProfile)
-            if err != nil { log.Printf("mem profile: %v", err); return }
-            defer func() { _ = f.Close() }()
-            _ = pprof.WriteHeapProfile(f)
-        }()
-    }
-
-    var frame *j.Frame
-    var stepNames []string
-	useStream := *chunkSize > 0
-	if !useStream {
-		switch cfg.Input.Type {
-		case "", "csv":
-            delim := rune(0)
-            if cfg.Input.Delimiter != "" {
-                delim = rune(cfg.Input.Delimiter[0])
-            }
-            rdr, file, err := csvio.Open(cfg.Input.Path, csvio.ReaderOptions{HasHeader: cfg.Input.HasHeader, Delimiter: delim, SampleRows: 100, Strict: cfg.Input.CSVStrict})
-            if err != nil {
-                fmt.Fprintln(os.Stderr, err)
-                os.Exit(1)
-            }
-            if file != nil { defer func() { _ = file.Close() }() }
-            schema, _, err := rdr.InferSchema()
-            if err != nil {
-                fmt.Fprintln(os.Stderr, err)
-                os.Exit(1)
-            }
-            frame, err = rdr.ReadAll(schema)
-            if err != nil {
-                fmt.Fprintln(os.Stderr, err)
-                os.Exit(1)
-            }
-            if *verbose {
-                fmt.Fprintf(os.Stderr, "read csv: rows=%d cols=%d from %s\n", frame.Rows(), len(schema.Columns), cfg.Input.Path)
-                if w := rdr.Warnings(); w != "" { fmt.Fprintf(os.Stderr, "csv repair summary: %s\n", w) }
-            }
-		case "jsonl":
-            jr, jf, err := jsonlio.Open(cfg.Input.Path, jsonlio.ReaderOptions{SampleRows: 100})
-            if err != nil {
-                fmt.Fprintln(os.Stderr, err)
-                os.Exit(1)
-            }
-            if jf != nil { defer func() { _ = jf.Close() }() }
-			schema, err := jr.InferSchema()
-			if err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				os.Exit(1)
-			}
-            frame, err = jr.ReadAll(schema)
-            if err != nil {
-                fmt.Fprintln(os.Stderr, err)
-                os.Exit(1)
-            }
-            if *verbose {
-                fmt.Fprintf(os.Stderr, "read jsonl: rows=%d cols=%d from %s\n", frame.Rows(), len(schema.Columns), cfg.Input.Path)
-            }
-		case "parquet":
-			fmt.Fprintln(os.Stderr, "parquet input not yet supported; please use CSV/JSONL input.")
-			os.Exit(2)
-		default:
-			fmt.Fprintf(os.Stderr, "unsupported input type %q\n", cfg.Input.Type)
-			os.Exit(2)
+	for _, c := range commands() {
+		if c.name == args[0] {
+			os.Exit(c.run(args[1:]))
 		}
 	}
+	fmt.Fprintf(os.Stderr, "janitor: unknown command %q\n", args[0])
+	usage(os.Stderr)
+	os.Exit(2)
+}
 
-    // Dry-run: print inferred schema and steps, then exit
-    if *dryRun {
-        // parse steps to stepNames (without building pipeline)
-        for _, raw := range cfg.Steps {
-            var probe map[string]json.RawMessage
-            _ = json.Unmarshal(raw, &probe)
-            for k := range probe { stepNames = append(stepNames, k) }
-        }
-        switch cfg.Input.Type {
-        case "", "csv":
-            delim := rune(0)
-            if cfg.Input.Delimiter != "" { delim = rune(cfg.Input.Delimiter[0]) }
-            rdr, f, err := csvio.Open(cfg.Input.Path, csvio.ReaderOptions{HasHeader: cfg.Input.HasHeader, Delimiter: delim, SampleRows: 50})
-            if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
-            if f != nil { defer func() { _ = f.Close() }() }
-            schema, _, err := rdr.InferSchema()
-            if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
-            fmt.Fprintf(os.Stderr, "dry-run schema (csv): %v\nsteps: %v\n", schema, stepNames)
-        case "jsonl":
-            jr, jf, err := jsonlio.Open(cfg.Input.Path, jsonlio.ReaderOptions{SampleRows: 50})
-            if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
-            if jf != nil { defer func() { _ = jf.Close() }() }
-            schema, err := jr.InferSchema()
-            if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
-            fmt.Fprintf(os.Stderr, "dry-run schema (jsonl): %v\nsteps: %v\n", schema, stepNames)
-        case "parquet":
-            pr, err := parquetio.OpenReader(cfg.Input.Path, 50)
-            if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
-        defer func() { _ = pr.Close() }()
-            schema := pr.Schema()
-            fmt.Fprintf(os.Stderr, "dry-run schema (parquet): %v\nsteps: %v\n", schema, stepNames)
-        default:
-            fmt.Fprintf(os.Stderr, "unsupported input type %q for dry-run\n", cfg.Input.Type)
-            os.Exit(2)
-        }
-        return
-    }
-
-    // Profile-only path
-    if *prof {
-        if *chunkSize <= 0 { *chunkSize = 10000 }
-        switch cfg.Input.Type {
-        case "", "csv":
-            delim := rune(0)
-            if cfg.Input.Delimiter != "" { delim = rune(cfg.Input.Delimiter[0]) }
-            sr, f, err := csvio.NewStreamReader(cfg.Input.Path, csvio.ReaderOptions{HasHeader: cfg.Input.HasHeader, Delimiter: delim, SampleRows: 200}, *chunkSize)
-            if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
-            defer func() { if f != nil { _ = f.Close() } }()
-            // create collector
-            col := profpkg.NewCollector(sr.Schema(), *profTopK)
-            // iterate
-            for {
-                fr, err := sr.Next()
-                if err == io.EOF { break }
-                if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
-                col.ConsumeFrame(fr)
-            }
-            if *profJSON {
-                out := col.ReportJSON()
-                b, _ := json.MarshalIndent(out, "", "  ")
-                fmt.Println(string(b))
-            } else {
-                fmt.Println(col.ReportText())
-            }
-            return
-        case "jsonl":
-            sr, f, err := jsonlio.NewStreamReader(cfg.Input.Path, *chunkSize)
-            if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
-            defer func() { _ = f.Close() }()
-            col := profpkg.NewCollector(sr.Schema(), *profTopK)
-            for {
-                fr, err := sr.Next()
-                if err == io.EOF { break }
-                if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
-                col.ConsumeFrame(fr)
-            }
-            if *profJSON {
-                out := col.ReportJSON()
-                b, _ := json.MarshalIndent(out, "", "  ")
-                fmt.Println(string(b))
-            } else {
-                fmt.Println(col.ReportText())
-            }
-            return
-        case "parquet":
-            pr, err := parquetio.OpenReader(cfg.Input.Path, 200)
-            if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
-            defer func() { _ = pr.Close() }()
-            fr, err := pr.ReadAll()
-            if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
-            col := profpkg.NewCollector(fr.Schema(), *profTopK)
-            col.ConsumeFrame(fr)
-            if *profJSON {
-                out := col.ReportJSON()
-                b, _ := json.MarshalIndent(out, "", "  ")
-                fmt.Println(string(b))
-            } else {
-                fmt.Println(col.ReportText())
-            }
-            return
-        default:
-            fmt.Fprintf(os.Stderr, "unsupported input type %q\n", cfg.Input.Type)
-            os.Exit(2)
-        }
-    }
-
-    // Build pipeline from steps
-    p := j.NewPipeline()
-    for _, raw := range cfg.Steps {
-        var probe map[string]json.RawMessage
-        if err := json.Unmarshal(raw, &probe); err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
-        for k, v := range probe {
-            switch k {
-            case "impute_constant":
-                var s struct{ Column string `json:"column"`; Value any `json:"value"` }
-                _ = json.Unmarshal(v, &s)
-                p.Add(&imp.Constant{Column: s.Column, Value: s.Value})
-                stepNames = append(stepNames, "impute_constant:"+s.Column)
-            case "impute_mean":
-                var s struct{ Column string `json:"column"` }
-                _ = json.Unmarshal(v, &s)
-                p.Add(&imp.Mean{Column: s.Column})
-                stepNames = append(stepNames, "impute_mean:"+s.Column)
-            case "trim":
-                var s struct{ Column string `json:"column"` }
-                _ = json.Unmarshal(v, &s)
-                p.Add(&std.Trim{Column: s.Column})
-                stepNames = append(stepNames, "trim:"+s.Column)
-            case "lower":
-                var s struct{ Column string `json:"column"` }
-                _ = json.Unmarshal(v, &s)
-                p.Add(&std.Lower{Column: s.Column})
-                stepNames = append(stepNames, "lower:"+s.Column)
-            case "regex_replace":
-                var s struct{ Column string `json:"column"`; Pattern string `json:"pattern"`; Replace string `json:"replace"` }
-                _ = json.Unmarshal(v, &s)
-                p.Add(&std.RegexReplace{Column: s.Column, Pattern: s.Pattern, Replace: s.Replace})
-                stepNames = append(stepNames, "regex_replace:"+s.Column)
-            case "map_values":
-                var s struct{ Column string `json:"column"`; Map map[string]string `json:"map"` }
-                _ = json.Unmarshal(v, &s)
-                p.Add(&std.MapValues{Column: s.Column, Map: s.Map})
-                stepNames = append(stepNames, "map_values:"+s.Column)
-            case "impute_median":
-                var s struct{ Column string `json:"column"` }
-                _ = json.Unmarshal(v, &s)
-                p.Add(&imp.Median{Column: s.Column})
-                stepNames = append(stepNames, "impute_median:"+s.Column)
-            case "validate_in":
-                var s struct{ Column string `json:"column"`; Values []string `json:"values"` }
-                _ = json.Unmarshal(v, &s)
-                p.Add(val.NewInSet(s.Column, s.Values))
-                stepNames = append(stepNames, "validate_in:"+s.Column)
-            case "validate_range":
-                var s struct{ Column string `json:"column"`; Min *float64 `json:"min"`; Max *float64 `json:"max"` }
-                _ = json.Unmarshal(v, &s)
-                p.Add(&val.Range{Column: s.Column, Min: s.Min, Max: s.Max})
-                stepNames = append(stepNames, "validate_range:"+s.Column)
-            case "cap_range":
-                var s struct{ Column string `json:"column"`; Min *float64 `json:"min"`; Max *float64 `json:"max"` }
-                _ = json.Unmarshal(v, &s)
-                p.Add(&outl.Cap{Column: s.Column, Min: s.Min, Max: s.Max})
-                stepNames = append(stepNames, "cap_range:"+s.Column)
-            default:
-                fmt.Fprintf(os.Stderr, "warning: unknown step %q ignored\n", k)
-            }
-        }
-    }
-
-    if useStream {
-        // streaming path
-        switch cfg.Input.Type {
-        case "", "csv":
-            delim := rune(0)
-            if cfg.Input.Delimiter != "" { delim = rune(cfg.Input.Delimiter[0]) }
-            // expand globs
-            paths := []string{cfg.Input.Path}
-            if hasWildcards(cfg.Input.Path) {
-                matches, _ := filepath.Glob(cfg.Input.Path)
-                if len(matches) == 0 { fmt.Fprintln(os.Stderr, "no files matched input path pattern"); os.Exit(2) }
-                paths = matches
-            }
-            if len(paths) > 1 && !strings.Contains(cfg.Output.Path, "{basename}") {
-                fmt.Fprintln(os.Stderr, "multiple input files require output.path to include {basename} placeholder")
-                os.Exit(2)
-            }
-            for _, in := range paths {
-                sr, f, err := csvio.NewStreamReader(in, csvio.ReaderOptions{HasHeader: cfg.Input.HasHeader, Delimiter: delim, SampleRows: 100, Strict: cfg.Input.CSVStrict}, *chunkSize)
-                if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
-                if f != nil { defer func() { _ = f.Close() }() }
-                switch cfg.Output.Type {
-                case "", "csv":
-                    outDelim := ','
-                    if cfg.Output.Delimiter != "" { outDelim = rune(cfg.Output.Delimiter[0]) }
-                    outPath := cfg.Output.Path
-                    if strings.Contains(outPath, "{basename}") {
-                        base := filepath.Base(in)
-                        outPath = strings.ReplaceAll(outPath, "{basename}", strings.TrimSuffix(base, filepath.Ext(base)))
-                    }
-                    if len(cfg.Output.PartitionBy) > 0 {
-                        makeSink := func(path string, schema j.Schema) (j.ChunkSink, error) {
-                            return csvio.NewStreamWriter(path, schema, csvio.WriterOptions{Delimiter: outDelim})
-                        }
-                        if err := runStreamPartitioned(context.Background(), p, sr, outPath, makeSink, sr.Schema(), cfg.Output.PartitionBy, *verbose, *expectedRows, *logJSON); err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
-                    } else {
-                        sw, err := csvio.NewStreamWriter(outPath, sr.Schema(), csvio.WriterOptions{Delimiter: outDelim})
-                        if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
-                    if err := runStreamWithProgress(context.Background(), p, sr, sw, *verbose, *expectedRows, *logJSON); err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
-                    }
-                case "jsonl":
-                    outPath := cfg.Output.Path
-                    if strings.Contains(outPath, "{basename}") {
-                        base := filepath.Base(in)
-                        outPath = strings.ReplaceAll(outPath, "{basename}", strings.TrimSuffix(base, filepath.Ext(base)))
-                    }
-                    if len(cfg.Output.PartitionBy) > 0 {
-                        makeSink := func(path string, schema j.Schema) (j.ChunkSink, error) { return jsonlio.NewStreamWriter(path) }
-                        if err := runStreamPartitioned(context.Background(), p, sr, outPath, makeSink, sr.Schema(), cfg.Output.PartitionBy, *verbose, *expectedRows, *logJSON); err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
-                    } else {
-                        sw, err := jsonlio.NewStreamWriter(outPath)
-                        if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
-                        if err := runStreamWithProgress(context.Background(), p, sr, sw, *verbose, *expectedRows, *logJSON); err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
-                    }
-                default:
-                    fmt.Fprintf(os.Stderr, "unsupported output type %q for streaming\n", cfg.Output.Type)
-                    os.Exit(2)
-                }
-            }
-        case "jsonl":
-			sr, f, err := jsonlio.NewStreamReader(cfg.Input.Path, *chunkSize)
-			if err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				os.Exit(1)
-			}
-            defer func() { _ = f.Close() }()
-                switch cfg.Output.Type {
-                case "jsonl":
-				sw, err := jsonlio.NewStreamWriter(cfg.Output.Path)
-				if err != nil {
-					fmt.Fprintln(os.Stderr, err)
-					os.Exit(1)
-				}
-                    if err := runStreamWithProgress(context.Background(), p, sr, sw, *verbose, *expectedRows, *logJSON); err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
-                case "", "csv":
-                outDelim := ','
-                if cfg.Output.Delimiter != "" {
-                    outDelim = rune(cfg.Output.Delimiter[0])
-                }
-                sw, err := csvio.NewStreamWriter(cfg.Output.Path, sr.Schema(), csvio.WriterOptions{Delimiter: outDelim})
-                if err != nil {
-                    fmt.Fprintln(os.Stderr, err)
-                    os.Exit(1)
-                }
-                    if err := runStreamWithProgress(context.Background(), p, sr, sw, *verbose, *expectedRows, *logJSON); err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
-            default:
-                fmt.Fprintf(os.Stderr, "unsupported output type %q for streaming\n", cfg.Output.Type)
-                os.Exit(2)
-            }
-        default:
-            fmt.Fprintf(os.Stderr, "unsupported input type %q\n", cfg.Input.Type)
-            os.Exit(2)
-        }
-        return
-    }
+// isHelp reports whether arg asks for help.
+func isHelp(arg string) bool {
+	switch arg {
+	case "help", "-h", "-help", "--help":
+		return true
+	}
+	return false
+}
 
-	// batch path
-	outFrame, err := p.Run(context.Background(), frame)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: janitor <command> [flags]\n\ncommands:\n")
+	for _, c := range commands() {
+		fmt.Fprintf(w, "  %-9s %s\n", c.name, c.summary)
 	}
-	switch cfg.Output.Type {
-	case "", "csv":
-		outDelim := ','
-		if cfg.Output.Delimiter != "" {
-			outDelim = rune(cfg.Output.Delimiter[0])
-		}
-        if err := csvio.WriteAll(cfg.Output.Path, outFrame, csvio.WriterOptions{Delimiter: outDelim}); err != nil {
-            fmt.Fprintln(os.Stderr, err)
-            os.Exit(1)
-        }
-    case "jsonl":
-        if err := jsonlio.WriteAll(cfg.Output.Path, outFrame); err != nil {
-            fmt.Fprintln(os.Stderr, err)
-            os.Exit(1)
-        }
-    case "parquet":
-        if err := parquetio.WriteAll(cfg.Output.Path, outFrame); err != nil {
-            fmt.Fprintln(os.Stderr, err)
-            os.Exit(1)
-        }
-    default:
-        fmt.Fprintf(os.Stderr, "unsupported output type %q\n", cfg.Output.Type)
-        os.Exit(2)
-    }
-    if *verbose {
-        fmt.Fprintf(os.Stderr, "batch complete: rows=%d cols=%d steps=%v -> %s\n", outFrame.Rows(), len(outFrame.Schema().Columns), stepNames, cfg.Output.Path)
-    }
+	fmt.Fprintf(w, "\nRun \"janitor <command> -h\" for the flags of a command.\n")
 }
 
-// parseConfig detects format from extension (.json, .yaml/.yml, .toml) and unmarshals into cfg.
-func parseConfig(path string, b []byte, cfg *Config) error {
-    ext := strings.ToLower(filepath.Ext(path))
-    switch ext {
-    case ".json", "":
-        return json.Unmarshal(b, cfg)
-    case ".yaml", ".yml":
-        return yaml.Unmarshal(b, cfg)
-    case ".toml":
-        return toml.Unmarshal(b, cfg)
-    default:
-        return json.Unmarshal(b, cfg)
-    }
+// newFlagSet returns the flag set of a command; synopsis and doc head its
+// help.
+func newFlagSet(name, synopsis, doc string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s\n\n%s\n", synopsis, doc)
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintf(fs.Output(), "\nflags:\n")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
 }
 
-// runStreamWithProgress processes chunks and prints periodic progress when verbose.
-func runStreamWithProgress(ctx context.Context, p *j.Pipeline, src j.ChunkSource, sink j.ChunkSink, verbose bool, expected int, logJSON bool) error {
-    if !verbose { return j.RunStream(ctx, p, src, sink) }
-    ticker := time.NewTicker(1 * time.Second)
-    defer ticker.Stop()
-    done := make(chan error, 1)
-    var rows int
-    start := time.Now()
-    var rates []float64
-    go func() {
-        for {
-            f, err := src.Next()
-            if err == io.EOF { done <- nil; return }
-            if err != nil { done <- err; return }
-            rows += f.Rows()
-            out, err := p.Run(ctx, f)
-            if err != nil { done <- err; return }
-            if err := sink.Write(out); err != nil { done <- err; return }
-        }
-    }()
-    for {
-        select {
-        case err := <-done:
-            return err
-        case <-ticker.C:
-            elapsed := time.Since(start).Seconds()
-            instRate := float64(rows) / (elapsed + 1e-9)
-            rates = append(rates, instRate)
-            if len(rates) > 5 { rates = rates[len(rates)-5:] }
-            var sum float64
-            for _, r := range rates { sum += r }
-            rate := sum / float64(len(rates))
-            if expected > 0 {
-                remaining := expected - rows
-                if remaining < 0 { remaining = 0 }
-                eta := time.Duration(float64(remaining)/(rate+1e-9)) * time.Second
-                pct := float64(rows) / float64(expected)
-                if pct > 1 { pct = 1 }
-                width := 30
-                filled := int(pct * float64(width))
-                if logJSON {
-                    evt := map[string]any{"type": "progress", "rows": rows, "expected": expected, "rate_rps": rate, "eta": int(eta.Truncate(time.Second).Seconds())}
-                    b, _ := json.Marshal(evt)
-                    fmt.Fprintln(os.Stderr, string(b))
-                } else {
-                    bar := strings.Repeat("=", filled) + strings.Repeat(" ", width-filled)
-                    fmt.Fprintf(os.Stderr, "[%s] %5.1f%% rows=%d/%d (%.1f r/s) ETA=%s\n", bar, pct*100, rows, expected, rate, eta.Truncate(time.Second))
-                }
-            } else {
-                if logJSON {
-                    evt := map[string]any{"type": "progress", "rows": rows, "rate_rps": rate}
-                    b, _ := json.Marshal(evt)
-                    fmt.Fprintln(os.Stderr, string(b))
-                } else {
-                    fmt.Fprintf(os.Stderr, "processed rows=%d (%.1f rows/s) ...\n", rows, rate)
-                }
-            }
-        }
-    }
+// parseFlags parses args into fs. A command exits with code when ok is
+// false: 0 after -h, 2 for bad flags or arguments beyond nargs (any number
+// when nargs < 0).
+func parseFlags(fs *flag.FlagSet, args []string, nargs int) (code int, ok bool) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0, false
+		}
+		return 2, false
+	}
+	if nargs >= 0 && fs.NArg() > nargs {
+		fmt.Fprintf(fs.Output(), "unexpected argument %q\n", fs.Arg(nargs))
+		fs.Usage()
+		return 2, false
+	}
+	return 0, true
 }
 
-// runStreamPartitioned applies p to each chunk from src, splits rows by partition columns,
-// and writes each partition to a sink keyed by the expanded outPath template. outPath must
-// include placeholders like {col:Name} which will be replaced with the row's column value.
-func runStreamPartitioned(ctx context.Context, p *j.Pipeline, src j.ChunkSource, outPath string, makeSink func(path string, schema j.Schema) (j.ChunkSink, error), schema j.Schema, partCols []string, verbose bool, expected int, logJSON bool) error {
-    sinks := map[string]j.ChunkSink{}
-    closeAll := func() {
-        for _, s := range sinks { _ = s.Close() }
-    }
-    defer closeAll()
-    ticker := time.NewTicker(1 * time.Second)
-    defer ticker.Stop()
-    done := make(chan error, 1)
-    var rows int
-    start := time.Now()
-    var rates []float64
-    go func() {
-        for {
-            f, err := src.Next()
-            if err == io.EOF { done <- nil; return }
-            if err != nil { done <- err; return }
-            out, err := p.Run(ctx, f)
-            if err != nil { done <- err; return }
-            // split by partition keys
-            parts := splitFrameByPartitions(out, partCols)
-            for key, pf := range parts {
-                path := expandOutPath(outPath, schema, partCols, pf, key)
-                s, ok := sinks[path]
-                if !ok {
-                    ss, err := makeSink(path, schema)
-                    if err != nil { done <- err; return }
-                    sinks[path] = ss
-                    s = ss
-                }
-                if err := s.Write(pf); err != nil { done <- err; return }
-                rows += pf.Rows()
-            }
-        }
-    }()
-    if !verbose {
-        return <-done
-    }
-    for {
-        select {
-        case err := <-done:
-            return err
-        case <-ticker.C:
-            elapsed := time.Since(start).Seconds()
-            instRate := float64(rows) / (elapsed + 1e-9)
-            rates = append(rates, instRate)
-            if len(rates) > 5 { rates = rates[len(rates)-5:] }
-            var sum float64
-            for _, r := range rates { sum += r }
-            rate := sum / float64(len(rates))
-            if expected > 0 {
-                remaining := expected - rows
-                if remaining < 0 { remaining = 0 }
-                eta := time.Duration(float64(remaining)/(rate+1e-9)) * time.Second
-                pct := float64(rows) / float64(expected)
-                if pct > 1 { pct = 1 }
-                width := 30
-                filled := int(pct * float64(width))
-                if logJSON {
-                    evt := map[string]any{"type": "progress", "rows": rows, "expected": expected, "rate_rps": rate, "eta": int(eta.Truncate(time.Second).Seconds())}
-                    b, _ := json.Marshal(evt)
-                    fmt.Fprintln(os.Stderr, string(b))
-                } else {
-                    bar := strings.Repeat("=", filled) + strings.Repeat(" ", width-filled)
-                    fmt.Fprintf(os.Stderr, "[%s] %5.1f%% rows=%d/%d (%.1f r/s) ETA=%s\n", bar, pct*100, rows, expected, rate, eta.Truncate(time.Second))
-                }
-            } else {
-                if logJSON {
-                    evt := map[string]any{"type": "progress", "rows": rows, "rate_rps": rate}
-                    b, _ := json.Marshal(evt)
-                    fmt.Fprintln(os.Stderr, string(b))
-                } else {
-                    fmt.Fprintf(os.Stderr, "processed rows=%d (%.1f rows/s) ...\n", rows, rate)
-                }
-            }
-        }
-    }
+// configFlags are the flags of the commands that read a config. The input,
+// output and steps can be given by flags as well, replacing or adding to the
+// config's, or instead of a config file.
+type configFlags struct {
+	fs         *flag.FlagSet
+	path       *string
+	schema     *string
+	input      *string
+	inputType  *string
+	hasHeader  *bool
+	output     *string // nil unless withOutput
+	outputType *string
+	steps      *stepList // nil unless withSteps
 }
 
-func splitFrameByPartitions(f *j.Frame, cols []string) map[string]*j.Frame {
-    res := map[string]*j.Frame{}
-    for r := 0; r < f.Rows(); r++ {
-        key := partitionKeyForRow(f, r, cols)
-        pf, ok := res[key]
-        if !ok {
-            pf = j.NewFrame(f.Schema())
-            res[key] = pf
-        }
-        pf.AppendNullRow()
-        row := pf.Rows() - 1
-        // copy all columns
-        for _, cs := range f.Schema().Columns {
-            col, _ := f.ColumnByName(cs.Name)
-            switch cs.Type {
-            case j.KindFloat:
-                if v, ok := col.(*j.FloatColumn).Get(r); ok { _ = pf.SetCell(row, cs.Name, v) }
-            case j.KindInt:
-                if v, ok := col.(*j.IntColumn).Get(r); ok { _ = pf.SetCell(row, cs.Name, v) }
-            case j.KindBool:
-                if v, ok := col.(*j.BoolColumn).Get(r); ok { _ = pf.SetCell(row, cs.Name, v) }
-            case j.KindString:
-                if v, ok := col.(*j.StringColumn).Get(r); ok { _ = pf.SetCell(row, cs.Name, v) }
-            case j.KindTime:
-                if v, ok := col.(*j.TimeColumn).Get(r); ok { _ = pf.SetCell(row, cs.Name, v) }
-            }
-        }
-    }
-    return res
+func addConfigFlags(fs *flag.FlagSet) *configFlags {
+	c := &configFlags{
+		fs:        fs,
+		path:      fs.String("config", "", "Path to cleaning config (JSON/YAML/TOML)"),
+		schema:    fs.String("schema", "", "JSON schema file pinning input column types (replaces input.schema)"),
+		input:     fs.String("input", "", "Input path, glob or - for stdin (replaces input.path); the type comes from the extension"),
+		inputType: fs.String("input-type", "", "Input type for paths without a known extension: csv, jsonl or parquet"),
+		hasHeader: fs.Bool("has-header", true, "CSV input has a header row (replaces input.has_header when set or without --config)"),
+	}
+	fs.StringVar(c.input, "i", "", "Shorthand for --input")
+	return c
 }
 
-func partitionKeyForRow(f *j.Frame, r int, cols []string) string {
-    parts := make([]string, len(cols))
-    for i, name := range cols {
-        col, _ := f.ColumnByName(name)
-        switch col := col.(type) {
-        case *j.StringColumn:
-            if v, ok := col.Get(r); ok { parts[i] = sanitizePartition(v) } else { parts[i] = "_null" }
-        case *j.FloatColumn:
-            if v, ok := col.Get(r); ok { parts[i] = fmt.Sprintf("%.6g", v) } else { parts[i] = "_null" }
-        case *j.IntColumn:
-            if v, ok := col.Get(r); ok { parts[i] = fmt.Sprintf("%d", v) } else { parts[i] = "_null" }
-        case *j.BoolColumn:
-            if v, ok := col.Get(r); ok { if v { parts[i] = "true" } else { parts[i] = "false" } } else { parts[i] = "_null" }
-        case *j.TimeColumn:
-            if v, ok := col.Get(r); ok { parts[i] = sanitizePartition(v.Format("2006-01-02")) } else { parts[i] = "_null" }
-        default:
-            parts[i] = "_"
-        }
-    }
-    return strings.Join(parts, "/")
+// withOutput adds the output flags.
+func (c *configFlags) withOutput() *configFlags {
+	c.output = c.fs.String("output", "", "Output path or - for stdout (replaces output.path); the type comes from the extension")
+	c.fs.StringVar(c.output, "o", "", "Shorthand for --output")
+	c.outputType = c.fs.String("output-type", "", "Output type for paths without a known extension: csv, jsonl or parquet")
+	return c
 }
 
-func sanitizePartition(s string) string {
-    // replace path separators and spaces
-    s = strings.ReplaceAll(s, "/", "-")
-    s = strings.ReplaceAll(s, "\\", "-")
-    s = strings.ReplaceAll(s, " ", "_")
-    return s
+// withSteps adds --step.
+func (c *configFlags) withSteps() *configFlags {
+	c.steps = &stepList{}
+	c.fs.Var(c.steps, "step", "Step to add after the config's, e.g. trim:name or impute_constant:age,value=0 (repeatable)")
+	return c
 }
 
-func expandOutPath(tmpl string, schema j.Schema, cols []string, f *j.Frame, key string) string {
-    out := tmpl
-    // Support {basename} (handled earlier for CSV/JSONL multi-file) and {col:Name}
-    for _, name := range cols {
-        placeholder := "{col:" + name + "}"
-        if strings.Contains(out, placeholder) {
-            // compute value from the first row (all rows in partition share it)
-            val := ""
-            if f.Rows() > 0 {
-                col, _ := f.ColumnByName(name)
-                switch col := col.(type) {
-                case *j.StringColumn:
-                    if v, ok := col.Get(0); ok { val = sanitizePartition(v) }
-                case *j.FloatColumn:
-                    if v, ok := col.Get(0); ok { val = fmt.Sprintf("%.6g", v) }
-                case *j.IntColumn:
-                    if v, ok := col.Get(0); ok { val = fmt.Sprintf("%d", v) }
-                case *j.BoolColumn:
-                    if v, ok := col.Get(0); ok { if v { val = "true" } else { val = "false" } }
-                case *j.TimeColumn:
-                    if v, ok := col.Get(0); ok { val = sanitizePartition(v.Format("2006-01-02")) }
-                }
-            }
-            out = strings.ReplaceAll(out, placeholder, val)
-        }
-    }
-    // if no {col:} placeholders, fall back to the joined key
-    if out == tmpl {
-        out = strings.ReplaceAll(out, "{basename}", key)
-    }
-    return out
+// stepList collects repeated --step flags.
+type stepList []string
+
+func (s *stepList) String() string { return strings.Join(*s, " ") }
+
+func (s *stepList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
 }
 
-func hasWildcards(path string) bool {
-    return strings.ContainsAny(path, "*?[")
+// load reads the config, if any, and applies the flags to it. On failure it
+// prints the error and returns the exit code.
+func (c *configFlags) load() (runner.Config, int) {
+	var cfg runner.Config
+	if *c.path == "" && *c.input == "" && (c.output == nil || *c.output == "") && (c.steps == nil || len(*c.steps) == 0) {
+		fmt.Fprintln(os.Stderr, "no config provided; nothing to do. try --config <file> or -i <file>")
+		return cfg, 2
+	}
+	if *c.path != "" {
+		var err error
+		if cfg, err = runner.LoadConfig(*c.path); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return cfg, 1
+		}
+	}
+	set := map[string]bool{}
+	c.fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if *c.path == "" || set["has-header"] {
+		cfg.Input.HasHeader = *c.hasHeader
+	}
+	if *c.input != "" {
+		cfg.Input.Path = *c.input
+		if t := runner.TypeFromPath(*c.input); t != "" {
+			cfg.Input.Type = t
+		}
+	}
+	if *c.inputType != "" {
+		cfg.Input.Type = *c.inputType
+	}
+	if c.output != nil {
+		if *c.output != "" {
+			cfg.Output.Path = *c.output
+			if t := runner.TypeFromPath(*c.output); t != "" {
+				cfg.Output.Type = t
+			}
+		}
+		if *c.outputType != "" {
+			cfg.Output.Type = *c.outputType
+		}
+	}
+	if c.steps != nil {
+		// flag steps are decoded like a config's, after its steps
+		for _, spec := range *c.steps {
+			raw, err := runner.ParseStep(spec)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return cfg, 2
+			}
+			cfg.Steps = append(cfg.Steps, raw)
+		}
+	}
+	if *c.schema != "" {
+		o, err := iox.ReadSchemaOverride(*c.schema)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return cfg, 1
+		}
+		cfg.Input.Schema = o
+	}
+	return cfg, 0
 }
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
+	profpkg "github.com/wdm0006/janitor/pkg/profile"
+	"github.com/wdm0006/janitor/pkg/runner"
+)
+
+func profileCmd(args []string) int {
+	fs := newFlagSet("profile", "janitor profile [--config <file>] [-i <input>] [flags]",
+		"Read the input of a config and print statistics of each column: nulls,\nranges, means and the most frequent values.")
+	cf := addConfigFlags(fs)
+	chunkSize := fs.Int("chunk-size", 10000, "Rows per chunk read")
+	topK := fs.Int("topk", 5, "Top-K frequent values to show for string/time columns")
+	asJSON := fs.Bool("json", false, "Emit the profile as JSON")
+	if code, ok := parseFlags(fs, args, 0); !ok {
+		return code
+	}
+	cfg, code := cf.load()
+	if code != 0 {
+		return code
+	}
+	if err := cfg.CheckInput(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return profileInput(cfg, *chunkSize, *topK, *asJSON)
+}
+
+// profileInput prints the profile of every input file, taken as one dataset.
+func profileInput(cfg runner.Config, chunkSize, topK int, asJSON bool) int {
+	if chunkSize <= 0 {
+		chunkSize = 10000
+	}
+	// drifting files are profiled with the merged schema
+	cfg.Input.DriftLog = ""
+	merged, err := runner.ScanDrift(cfg, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	paths, err := runner.InputPaths(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	var col *profpkg.Collector
+	var first j.Schema
+	for _, in := range paths {
+		sr, closeIn, err := runner.OpenStream(cfg, in, chunkSize, nil, merged)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if col == nil {
+			first = sr.Schema()
+			col = profpkg.NewCollector(first, topK)
+		} else if !sameColumns(first, sr.Schema()) {
+			_ = closeIn()
+			fmt.Fprintf(os.Stderr, "%s: schema differs from %s; set input.schema_drift to profile the files together\n", in, paths[0])
+			return 1
+		}
+		for {
+			fr, err := sr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				_ = closeIn()
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			col.ConsumeFrame(fr)
+		}
+		_ = closeIn()
+	}
+	if asJSON {
+		b, _ := json.MarshalIndent(col.ReportJSON(), "", "  ")
+		fmt.Println(string(b))
+	} else {
+		fmt.Println(col.ReportText())
+	}
+	return 0
+}
+
+// sameColumns reports whether a and b have the same column names and types,
+// in order.
+func sameColumns(a, b j.Schema) bool {
+	if len(a.Columns) != len(b.Columns) {
+		return false
+	}
+	for i, c := range a.Columns {
+		if b.Columns[i].Name != c.Name || b.Columns[i].Type != c.Type {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"runtime/pprof"
+	"strings"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
+	"github.com/wdm0006/janitor/pkg/runner"
+)
+
+// runOptions are the run flags the batch and streaming paths share.
+type runOptions struct {
+	chunkSize  int
+	verbose    bool
+	fitOut     string
+	applyState string
+	reportPath string
+	progress   runner.Progress
+}
+
+func runCmd(args []string) int {
+	fs := newFlagSet("run", "janitor run [--config <file>] [-i <input>] [-o <output>] [--step <step>]... [flags]",
+		"Read the input of a config, apply its steps and write the output. The\n"+
+			"input, output and steps can also be given by flags, with or without a\n"+
+			"config; -i and -o take their types from the file extensions and - is\n"+
+			"stdin or stdout.")
+	cf := addConfigFlags(fs).withOutput().withSteps()
+	chunkSize := fs.Int("chunk-size", 0, "Enable streaming with chunk size (rows per chunk). 0 disables streaming.")
+	verbose := fs.Bool("verbose", false, "Print progress and a summary")
+	expectedRows := fs.Int("expected-rows", 0, "Optional expected total rows for ETA in streaming progress")
+	logJSON := fs.Bool("log-json", false, "Emit progress logs as JSON lines")
+	fitOut := fs.String("fit-out", "", "Write learned step parameters (e.g., imputer statistics) to this JSON file")
+	applyState := fs.String("apply-state", "", "Load learned step parameters from a --fit-out file instead of fitting")
+	reportPath := fs.String("report", "", "Write a JSON validation report (per-rule counts and failure samples) to this file ('-' for stdout)")
+	reportSamples := fs.Int("report-samples", 5, "Failing values to sample per rule and column in --report")
+	strictConfig := fs.Bool("strict-config", false, "Treat every step config problem (unknown steps or params, missing columns, unsupported types) as an error")
+	cpuProfile := fs.String("cpu-profile", "", "Write CPU profile to file (pprof)")
+	memProfile := fs.String("mem-profile", "", "Write heap profile to file on exit (pprof)")
+	pprofAddr := fs.String("pprof-addr", "", "Serve net/http/pprof on this address (e.g., :6060)")
+	metricsAddr := fs.String("metrics-addr", "", "Serve expvar metrics and /healthz on this address (e.g., :9090)")
+	// kept from before profile and schema were commands of their own
+	prof := fs.Bool("profile", false, "Deprecated: use janitor profile")
+	profTopK := fs.Int("profile-topk", 5, "Deprecated: use janitor profile --topk")
+	profJSON := fs.Bool("profile-json", false, "Deprecated: use janitor profile --json")
+	dryRun := fs.Bool("dry-run", false, "Deprecated: use janitor schema infer or janitor validate")
+	if code, ok := parseFlags(fs, args, 0); !ok {
+		return code
+	}
+	if *fitOut != "" && *applyState != "" {
+		fmt.Fprintln(os.Stderr, "--fit-out and --apply-state are mutually exclusive")
+		return 2
+	}
+	cfg, code := cf.load()
+	if code != 0 {
+		return code
+	}
+	if *prof {
+		return profileInput(cfg, *chunkSize, *profTopK, *profJSON)
+	}
+	if *dryRun {
+		schema, err := runner.InferSchema(cfg, 50)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		typ := cfg.Input.Type
+		if typ == "" {
+			typ = "csv"
+		}
+		fmt.Fprintf(os.Stderr, "dry-run schema (%s): %v\nsteps: %v\n", typ, schema, stepKeys(cfg))
+		return 0
+	}
+
+	// Observability: pprof + expvar servers
+	if *pprofAddr != "" {
+		go func() {
+			log.Printf("pprof listening on %s", *pprofAddr)
+			_ = http.ListenAndServe(*pprofAddr, nil)
+		}()
+	}
+	if *metricsAddr != "" {
+		go func() {
+			http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("ok")) })
+			log.Printf("metrics listening on %s", *metricsAddr)
+			_ = http.ListenAndServe(*metricsAddr, nil)
+		}()
+	}
+	if *cpuProfile != "" {
+		f, err := os.Create(*cpuProfile)
+		if err != nil {
+			log.Fatalf("cpu profile: %v", err)
+		}
+		_ = pprof.StartCPUProfile(f)
+		defer func() { pprof.StopCPUProfile(); _ = f.Close() }()
+	}
+	if *memProfile != "" {
+		defer func() {
+			f, err := os.Create(*memProfile)
+			if err != nil {
+				log.Printf("mem profile: %v", err)
+				return
+			}
+			defer func() { _ = f.Close() }()
+			_ = pprof.WriteHeapProfile(f)
+		}()
+	}
+
+	if err := cfg.CheckInput(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := cfg.CheckOutput(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	opt := runOptions{chunkSize: *chunkSize, verbose: *verbose, fitOut: *fitOut, applyState: *applyState, reportPath: *reportPath}
+	var logw io.Writer
+	if *verbose {
+		logw = os.Stderr
+		opt.progress = runner.Progress{Log: os.Stderr, Expected: *expectedRows, JSON: *logJSON}
+	}
+	// under a drift policy every input file is scanned first so that all of
+	// them are read with one merged schema
+	merged, err := runner.ScanDrift(cfg, logw)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	useStream := *chunkSize > 0
+	var frame *j.Frame
+	// the batch reject sink is shared by the reader (cast rejects) and the validators
+	var batchRejects j.ChunkSink
+	if !useStream {
+		if runner.HasWildcards(cfg.Input.Path) && (strings.Contains(cfg.Output.Path, "{basename}") || strings.Contains(cfg.Output.Rejects.Path, "{basename}")) {
+			fmt.Fprintln(os.Stderr, "batch runs read a glob as one dataset, so {basename} cannot be used; stream with --chunk-size for one output per file")
+			return 2
+		}
+		batchRejects = runner.RejectSink(cfg, cfg.Input.Path)
+		if frame, err = runner.ReadFrame(cfg, merged, batchRejects, logw); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	// Build pipeline from steps. Problems in a step's config are reported
+	// with its index: ones that keep the step from running stop the run, the
+	// others (unknown steps and params, missing columns) only with --strict-config
+	problems := newConfigProblems(*strictConfig)
+	inputSchema := cachedSchema(func() (j.Schema, error) {
+		switch {
+		case !useStream:
+			return frame.Schema(), nil
+		case cfg.IsStdin():
+			return j.Schema{}, fmt.Errorf("column patterns need the input schema before streaming; stdin cannot be used")
+		}
+		return runner.PeekSchema(cfg, *chunkSize, merged)
+	})
+	b := buildPipeline(cfg, inputSchema, problems)
+	if problems.failed() {
+		return 2
+	}
+	p := b.p
+	if *applyState != "" {
+		if err := p.Load(*applyState); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if b.quarantine && cfg.Output.Rejects.Path == "" {
+		fmt.Fprintln(os.Stderr, "on_fail \"quarantine\" requires output.rejects.path")
+		return 2
+	}
+	var report *j.ValidationReport
+	if *reportPath != "" {
+		report = j.NewValidationReport(*reportSamples)
+		p.OnViolation(report.Observe)
+	}
+	p.OnViolation(func(step int, v j.Validator, f *j.Frame, vs []j.Violation) {
+		if len(vs) > 0 && v.FailAction() == j.ActionWarn {
+			fmt.Fprintf(os.Stderr, "warning: %s: %d of %d rows failed (first: column %s value %q: %s)\n", v.Name(), len(vs), f.Rows(), vs[0].Column, vs[0].Value, vs[0].Reason)
+		}
+	})
+
+	if useStream {
+		return runStreaming(cfg, b, problems, merged, report, opt)
+	}
+
+	problems.check(b, frame.Schema())
+	if problems.failed() {
+		return 2
+	}
+	// batch path; fitting on the full frame matches Run unless state was loaded
+	run := p.FitRun
+	if *applyState != "" {
+		run = p.Run
+	}
+	if batchRejects != nil {
+		p.SetRejectSink(batchRejects)
+		defer func() { _ = batchRejects.Close() }()
+	}
+	outFrame, err := run(context.Background(), frame)
+	_ = p.Close()
+	writeReport(*reportPath, report)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *fitOut != "" {
+		if err := p.Save(*fitOut); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if err := runner.WriteFrame(cfg, outFrame); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *verbose {
+		fmt.Fprintf(os.Stderr, "batch complete: rows=%d cols=%d steps=%v -> %s\n", outFrame.Rows(), len(outFrame.Schema().Columns), b.names, cfg.Output.Path)
+	}
+	return 0
+}
+
+// runStreaming is the streaming path of run; every input type reads through
+// the same chunked loop.
+func runStreaming(cfg runner.Config, b *built, problems *configProblems, merged *runner.Merged, report *j.ValidationReport, opt runOptions) int {
+	p := b.p
+	ctx := context.Background()
+	if p.NeedsFit() && opt.applyState == "" {
+		// stateful steps (imputers) are fitted over the whole input first so every
+		// chunk is filled with the same dataset-wide statistics
+		if cfg.IsStdin() {
+			fmt.Fprintln(os.Stderr, "streaming with stateful steps reads the input twice; stdin cannot be used")
+			return 2
+		}
+		// check the steps against the first file before the fit pass reads any data
+		in, err := runner.PeekSchema(cfg, opt.chunkSize, merged)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		problems.check(b, in)
+		if problems.failed() {
+			return 2
+		}
+		if err := runner.FitStream(ctx, p, cfg, opt.chunkSize, merged); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if opt.verbose {
+			fmt.Fprintln(os.Stderr, "fit pass complete")
+		}
+	}
+	if opt.fitOut != "" {
+		if err := p.Save(opt.fitOut); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	paths, err := runner.InputPaths(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if len(paths) > 1 && !strings.Contains(cfg.Output.Path, "{basename}") {
+		fmt.Fprintln(os.Stderr, "multiple input files require output.path to include {basename} placeholder")
+		return 2
+	}
+	if len(paths) > 1 && cfg.Output.Rejects.Path != "" && !strings.Contains(cfg.Output.Rejects.Path, "{basename}") {
+		fmt.Fprintln(os.Stderr, "multiple input files require output.rejects.path to include {basename} placeholder")
+		return 2
+	}
+	makeSink, err := runner.SinkFactory(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	fail := func(err error) int {
+		_ = p.Close()
+		writeReport(opt.reportPath, report)
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for _, in := range paths {
+		rs := runner.RejectSink(cfg, in)
+		p.SetRejectSink(rs)
+		sr, closeIn, err := runner.OpenStream(cfg, in, opt.chunkSize, rs, merged)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer func() { _ = closeIn() }()
+		outPath := runner.ExpandBasename(cfg.Output.Path, in)
+		problems.check(b, sr.Schema())
+		if problems.failed() {
+			return 2
+		}
+		// sinks are opened with the schema the steps produce, not the input's
+		outSchema, err := p.OutputSchema(sr.Schema())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		if len(cfg.Output.PartitionBy) > 0 {
+			if err := runner.RunStreamPartitioned(ctx, p, sr, outPath, makeSink, outSchema, cfg.Output.PartitionBy, opt.progress); err != nil {
+				return fail(err)
+			}
+		} else {
+			sw, err := makeSink(outPath, outSchema)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			if err := runner.RunStream(ctx, p, sr, sw, opt.progress); err != nil {
+				return fail(err)
+			}
+		}
+		if opt.verbose {
+			if c := runner.CastSummary(sr); c != "" {
+				fmt.Fprintf(os.Stderr, "cast failures in %s: %s\n", in, c)
+			}
+		}
+		if rs != nil {
+			if err := rs.Close(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+		}
+	}
+	writeReport(opt.reportPath, report)
+	if err := p.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// writeReport writes the validation report if one was requested. It is
+// called on success and before exiting on a run error.
+func writeReport(path string, r *j.ValidationReport) {
+	if err := runner.WriteReport(path, r); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// stepKeys returns the step names a config lists, without building them.
+func stepKeys(cfg runner.Config) []string {
+	var names []string
+	for _, raw := range cfg.Steps {
+		var probe map[string]json.RawMessage
+		_ = json.Unmarshal(raw, &probe)
+		for k := range probe {
+			if k != "when" {
+				names = append(names, k)
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	iox "github.com/wdm0006/janitor/pkg/io/ioutils"
+	j "github.com/wdm0006/janitor/pkg/janitor"
+	"github.com/wdm0006/janitor/pkg/runner"
+)
+
+func schemaCmd(args []string) int {
+	if len(args) > 0 && args[0] == "infer" {
+		return schemaInferCmd(args[1:])
+	}
+	w, code := os.Stderr, 2
+	if len(args) > 0 && isHelp(args[0]) {
+		w, code = os.Stdout, 0
+	}
+	fmt.Fprintln(w, "usage: janitor schema infer [--config <file>] [-i <input>] [flags]")
+	return code
+}
+
+func schemaInferCmd(args []string) int {
+	fs := newFlagSet("schema infer", "janitor schema infer [--config <file>] [-i <input>] [--step <step>]... [flags]",
+		"Print the schema the input of a config is read with, as JSON. The output\nis a valid --schema file, so it can be edited and pinned.")
+	cf := addConfigFlags(fs).withSteps()
+	sampleRows := fs.Int("sample-rows", 1000, "Rows of the first input file to infer column types from")
+	afterSteps := fs.Bool("steps", false, "Print the schema the config's steps produce instead of the input's")
+	if code, ok := parseFlags(fs, args, 0); !ok {
+		return code
+	}
+	cfg, code := cf.load()
+	if code != 0 {
+		return code
+	}
+	if err := cfg.CheckInput(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	// under a drift policy the schema is the one every file is read with
+	cfg.Input.DriftLog = ""
+	merged, err := runner.ScanDrift(cfg, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var schema j.Schema
+	if merged != nil {
+		schema = merged.Schema()
+	} else if schema, err = runner.InferSchema(cfg, *sampleRows); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	// columns without a value in the sample are read as strings
+	schema = iox.FillUnknownKinds(schema)
+	if *afterSteps {
+		problems := newConfigProblems(false)
+		b := buildPipeline(cfg, func() (j.Schema, error) { return schema, nil }, problems)
+		if problems.failed() {
+			return 2
+		}
+		if schema, err = b.p.OutputSchema(schema); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println(string(out))
+	return 0
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+// stepsCmd prints the registered steps, with their params under --list.
+func stepsCmd(args []string) int {
+	fs := newFlagSet("steps", "janitor steps [--list]",
+		"List the steps configs can use, with their params under --list.")
+	list := fs.Bool("list", false, "Show each step's params")
+	if code, ok := parseFlags(fs, args, 0); !ok {
+		return code
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, s := range j.Steps() {
+		fmt.Fprintf(w, "%s\t%s\n", s.Name, s.Doc)
+		if !*list {
+			continue
+		}
+		for _, p := range s.Params {
+			doc := p.Doc
+			if p.Required {
+				doc += " (required)"
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", p.Name, p.JSONType(), doc)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
+	"github.com/wdm0006/janitor/pkg/runner"
+)
+
+func validateCmd(args []string) int {
+	fs := newFlagSet("validate", "janitor validate [--config <file>] [-i <input>] [--step <step>]... [flags]",
+		"Check a config without writing anything: its input and output settings,\n"+
+			"and its steps against the input schema. With --data the steps also run\n"+
+			"over the input, discarding the output, and rows failing validators are\n"+
+			"reported.\n\n"+
+			"Exits 0 when the checks pass, 1 when the data cannot be read or fails a\n"+
+			"validator whose on_fail is \"fail\", and 2 for config problems.")
+	cf := addConfigFlags(fs).withSteps()
+	strictConfig := fs.Bool("strict-config", false, "Treat every step config problem (unknown steps or params, missing columns, unsupported types) as an error")
+	data := fs.Bool("data", false, "Also run the steps over the input and report rows failing validators")
+	chunkSize := fs.Int("chunk-size", 10000, "Rows per chunk read")
+	reportPath := fs.String("report", "", "With --data, write a JSON validation report to this file ('-' for stdout)")
+	reportSamples := fs.Int("report-samples", 5, "Failing values to sample per rule and column in --report")
+	if code, ok := parseFlags(fs, args, 0); !ok {
+		return code
+	}
+	cfg, code := cf.load()
+	if code != 0 {
+		return code
+	}
+	if err := cfg.CheckInput(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := cfg.CheckOutput(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *chunkSize <= 0 {
+		*chunkSize = 10000
+	}
+	// nothing is written, not even the drift log
+	cfg.Input.DriftLog = ""
+	merged, err := runner.ScanDrift(cfg, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	paths, err := runner.InputPaths(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	// stdin can only be read once, so its stream is kept for --data
+	var stdin runner.StreamSource
+	problems := newConfigProblems(*strictConfig)
+	inputSchema := cachedSchema(func() (j.Schema, error) {
+		if !cfg.IsStdin() {
+			return runner.PeekSchema(cfg, *chunkSize, merged)
+		}
+		sr, _, err := runner.OpenStream(cfg, paths[0], *chunkSize, nil, merged)
+		if err != nil {
+			return j.Schema{}, err
+		}
+		stdin = sr
+		return sr.Schema(), nil
+	})
+	b := buildPipeline(cfg, inputSchema, problems)
+	in, err := inputSchema()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	problems.check(b, in)
+	if problems.failed() {
+		return 2
+	}
+	if !*data {
+		return 0
+	}
+
+	p := b.p
+	ctx := context.Background()
+	report := j.NewValidationReport(*reportSamples)
+	p.OnViolation(report.Observe)
+	if p.NeedsFit() {
+		if cfg.IsStdin() {
+			fmt.Fprintln(os.Stderr, "validating data with stateful steps reads the input twice; stdin cannot be used")
+			return 2
+		}
+		if err := runner.FitStream(ctx, p, cfg, *chunkSize, merged); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	var runErr error
+	for _, path := range paths {
+		sr := stdin
+		if sr == nil {
+			var closeIn func() error
+			if sr, closeIn, err = runner.OpenStream(cfg, path, *chunkSize, nil, merged); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			defer func() { _ = closeIn() }()
+			problems.check(b, sr.Schema())
+			if problems.failed() {
+				return 2
+			}
+		}
+		if runErr = runner.RunStream(ctx, p, sr, runner.Discard, runner.Progress{}); runErr != nil {
+			break
+		}
+	}
+	_ = p.Close()
+	if *reportPath != "" {
+		writeReport(*reportPath, report)
+	}
+	// rows failing a validator that does not stop a run are only reported;
+	// ones failing "fail" validators stop this run as they would a real one
+	for _, r := range report.Rules {
+		if r.RowsFailed > 0 && r.Action != j.ActionFail {
+			fmt.Fprintf(os.Stderr, "warning: steps[%d] %s: %d of %d rows failed (%s)\n", b.origin[r.Step], b.names[r.Step], r.RowsFailed, r.RowsChecked, r.Action)
+		}
+	}
+	if runErr != nil {
+		fmt.Fprintln(os.Stderr, runErr)
+		return 1
+	}
+	return 0
+}
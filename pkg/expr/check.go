@@ -0,0 +1,253 @@
+package expr
+
+import (
+	"fmt"
+
+	iox "github.com/wdm0006/janitor/pkg/io/ioutils"
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+// checker types nodes against a schema. A null literal has kind
+// j.KindInvalid, which fits any operand.
+type checker struct {
+	schema j.Schema
+	times  *iox.TimeParser
+	kinds  map[node]j.Kind
+	consts map[*lit]any // literals converted for this schema (strings compared with times)
+}
+
+func newChecker(schema j.Schema) *checker {
+	times, _ := iox.NewTimeParser(nil, "")
+	return &checker{schema: schema, times: times, kinds: map[node]j.Kind{}, consts: map[*lit]any{}}
+}
+
+func (c *checker) typeOf(n node) (j.Kind, error) {
+	k, err := c.kindOf(n)
+	if err != nil {
+		return j.KindInvalid, err
+	}
+	c.kinds[n] = k
+	return k, nil
+}
+
+func (c *checker) kindOf(n node) (j.Kind, error) {
+	switch n := n.(type) {
+	case *colRef:
+		i := c.schema.Index(n.name)
+		if i < 0 {
+			return 0, &Error{Pos: n.p, Msg: fmt.Sprintf("unknown column %q", n.name)}
+		}
+		return c.schema.Columns[i].Type, nil
+	case *lit:
+		return litKind(n.v), nil
+	case *unary:
+		x, err := c.typeOf(n.x)
+		if err != nil {
+			return 0, err
+		}
+		if n.op == "!" {
+			if x != j.KindBool && x != j.KindInvalid {
+				return 0, &Error{Pos: n.p, Msg: fmt.Sprintf("! needs a bool, got %s", x)}
+			}
+			return j.KindBool, nil
+		}
+		if !numeric(x) {
+			return 0, &Error{Pos: n.p, Msg: fmt.Sprintf("- needs a number, got %s", x)}
+		}
+		return x, nil
+	case *binary:
+		return c.binaryKind(n)
+	case *inList:
+		x, err := c.typeOf(n.x)
+		if err != nil {
+			return 0, err
+		}
+		for _, item := range n.list {
+			k, err := c.typeOf(item)
+			if err != nil {
+				return 0, err
+			}
+			if err := c.comparable(n.p, n.x, item, x, k, "=="); err != nil {
+				return 0, err
+			}
+		}
+		return j.KindBool, nil
+	case *call:
+		return c.callKind(n)
+	}
+	return 0, fmt.Errorf("unknown node %T", n)
+}
+
+func (c *checker) binaryKind(n *binary) (j.Kind, error) {
+	l, err := c.typeOf(n.l)
+	if err != nil {
+		return 0, err
+	}
+	r, err := c.typeOf(n.r)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case "&&", "||":
+		if (l != j.KindBool && l != j.KindInvalid) || (r != j.KindBool && r != j.KindInvalid) {
+			return 0, &Error{Pos: n.p, Msg: fmt.Sprintf("%s needs bools, got %s and %s", n.op, l, r)}
+		}
+		return j.KindBool, nil
+	case "==", "!=", "<", "<=", ">", ">=":
+		if err := c.comparable(n.p, n.l, n.r, l, r, n.op); err != nil {
+			return 0, err
+		}
+		return j.KindBool, nil
+	case "+":
+		if (l == j.KindString || l == j.KindInvalid) && (r == j.KindString || r == j.KindInvalid) && (l == j.KindString || r == j.KindString) {
+			return j.KindString, nil
+		}
+	case "%":
+		if (l == j.KindInt || l == j.KindInvalid) && (r == j.KindInt || r == j.KindInvalid) {
+			return j.KindInt, nil
+		}
+		return 0, &Error{Pos: n.p, Msg: fmt.Sprintf("%% needs ints, got %s and %s", l, r)}
+	}
+	if !numeric(l) || !numeric(r) {
+		return 0, &Error{Pos: n.p, Msg: fmt.Sprintf("cannot apply %s to %s and %s", n.op, l, r)}
+	}
+	if n.op == "/" {
+		return j.KindFloat, nil
+	}
+	return widen(l, r), nil
+}
+
+// comparable checks that values of kinds a and b (nodes l and r) can be
+// compared with op. A string literal compared with a time is parsed as a time
+// here, once.
+func (c *checker) comparable(pos int, l, r node, a, b j.Kind, op string) error {
+	if a == j.KindTime && b == j.KindString {
+		return c.timeLiteral(r)
+	}
+	if a == j.KindString && b == j.KindTime {
+		return c.timeLiteral(l)
+	}
+	switch {
+	case a == j.KindInvalid || b == j.KindInvalid:
+		return nil
+	case numeric(a) && numeric(b), a == b:
+		if a == j.KindBool && op != "==" && op != "!=" {
+			return &Error{Pos: pos, Msg: fmt.Sprintf("%s does not apply to bools", op)}
+		}
+		return nil
+	}
+	return &Error{Pos: pos, Msg: fmt.Sprintf("cannot compare %s with %s", a, b)}
+}
+
+func (c *checker) timeLiteral(n node) error {
+	l, ok := n.(*lit)
+	if ok {
+		_, ok = l.v.(string)
+	}
+	if !ok {
+		return &Error{Pos: n.pos(), Msg: "cannot compare time with string"}
+	}
+	t, ok := c.times.Parse(l.v.(string))
+	if !ok {
+		return &Error{Pos: n.pos(), Msg: fmt.Sprintf("%q is not a time", l.v)}
+	}
+	c.consts[l] = t
+	c.kinds[l] = j.KindTime
+	return nil
+}
+
+func (c *checker) callKind(n *call) (j.Kind, error) {
+	args := make([]j.Kind, len(n.args))
+	for i, a := range n.args {
+		k, err := c.typeOf(a)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = k
+	}
+	arity := func(want int) error {
+		if len(args) != want {
+			return &Error{Pos: n.p, Msg: fmt.Sprintf("%s takes %d argument(s), got %d", n.fn, want, len(args))}
+		}
+		return nil
+	}
+	switch n.fn {
+	case "trim", "lower", "upper", "len":
+		if err := arity(1); err != nil {
+			return 0, err
+		}
+		if args[0] != j.KindString && args[0] != j.KindInvalid {
+			return 0, &Error{Pos: n.p, Msg: fmt.Sprintf("%s needs a string, got %s", n.fn, args[0])}
+		}
+		if n.fn == "len" {
+			return j.KindInt, nil
+		}
+		return j.KindString, nil
+	case "abs":
+		if err := arity(1); err != nil {
+			return 0, err
+		}
+		if !numeric(args[0]) {
+			return 0, &Error{Pos: n.p, Msg: fmt.Sprintf("abs needs a number, got %s", args[0])}
+		}
+		return args[0], nil
+	case "str":
+		if err := arity(1); err != nil {
+			return 0, err
+		}
+		return j.KindString, nil
+	case "is_null":
+		if err := arity(1); err != nil {
+			return 0, err
+		}
+		return j.KindBool, nil
+	case "coalesce":
+		if len(args) == 0 {
+			return 0, &Error{Pos: n.p, Msg: "coalesce needs at least one argument"}
+		}
+		out := j.KindInvalid
+		for _, k := range args {
+			switch {
+			case k == j.KindInvalid || k == out:
+			case out == j.KindInvalid:
+				out = k
+			case numeric(out) && numeric(k):
+				out = j.KindFloat
+			default:
+				return 0, &Error{Pos: n.p, Msg: fmt.Sprintf("coalesce mixes %s and %s", out, k)}
+			}
+		}
+		return out, nil
+	}
+	return 0, &Error{Pos: n.p, Msg: fmt.Sprintf("unknown function %q", n.fn)}
+}
+
+func litKind(v any) j.Kind {
+	switch v.(type) {
+	case int64:
+		return j.KindInt
+	case float64:
+		return j.KindFloat
+	case string:
+		return j.KindString
+	case bool:
+		return j.KindBool
+	case nil:
+		return j.KindInvalid
+	}
+	return j.KindTime
+}
+
+func numeric(k j.Kind) bool { return k == j.KindInt || k == j.KindFloat || k == j.KindInvalid }
+
+// widen returns the kind of arithmetic on a and b: float if either is,
+// otherwise int.
+func widen(a, b j.Kind) j.Kind {
+	if a == j.KindFloat || b == j.KindFloat {
+		return j.KindFloat
+	}
+	if a == j.KindInvalid && b == j.KindInvalid {
+		return j.KindInvalid
+	}
+	return j.KindInt
+}
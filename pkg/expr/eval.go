@@ -0,0 +1,295 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	iox "github.com/wdm0006/janitor/pkg/io/ioutils"
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+// evaluator computes each node as a whole column over the frame. Any null
+// operand makes the result null, except in &&, || (three-valued logic),
+// is_null and coalesce.
+type evaluator struct {
+	f *j.Frame
+	c *checker
+	n int
+}
+
+func (e *evaluator) eval(n node) (j.Column, error) {
+	switch n := n.(type) {
+	case *colRef:
+		col, _ := e.f.ColumnByName(n.name)
+		return col, nil
+	case *lit:
+		v, ok := e.c.consts[n]
+		if !ok {
+			v = n.v
+		}
+		out := e.column(e.c.kinds[n])
+		if v != nil {
+			for i := 0; i < e.n; i++ {
+				set(out, i, v)
+			}
+		}
+		return out, nil
+	case *unary:
+		x, err := e.eval(n.x)
+		if err != nil {
+			return nil, err
+		}
+		out := e.column(e.c.kinds[n])
+		for i := 0; i < e.n; i++ {
+			switch v := j.Value(x, i).(type) {
+			case bool:
+				set(out, i, !v)
+			case int64:
+				set(out, i, -v)
+			case float64:
+				set(out, i, -v)
+			}
+		}
+		return out, nil
+	case *binary:
+		return e.binary(n)
+	case *inList:
+		return e.in(n)
+	case *call:
+		return e.call(n)
+	}
+	return nil, fmt.Errorf("unknown node %T", n)
+}
+
+// column returns an all-null column of kind; the null type becomes string.
+func (e *evaluator) column(kind j.Kind) j.Column {
+	if kind == j.KindInvalid {
+		kind = j.KindString
+	}
+	return j.NewNullColumn("", kind, e.n)
+}
+
+func (e *evaluator) binary(n *binary) (j.Column, error) {
+	l, err := e.eval(n.l)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.eval(n.r)
+	if err != nil {
+		return nil, err
+	}
+	kind := e.c.kinds[n]
+	out := e.column(kind)
+	for i := 0; i < e.n; i++ {
+		a, b := j.Value(l, i), j.Value(r, i)
+		switch n.op {
+		case "&&":
+			if a == false || b == false {
+				set(out, i, false)
+			} else if a != nil && b != nil {
+				set(out, i, true)
+			}
+			continue
+		case "||":
+			if a == true || b == true {
+				set(out, i, true)
+			} else if a != nil && b != nil {
+				set(out, i, false)
+			}
+			continue
+		}
+		if a == nil || b == nil {
+			continue
+		}
+		switch n.op {
+		case "==", "!=", "<", "<=", ">", ">=":
+			d := compare(a, b)
+			set(out, i, n.op == "==" && d == 0 || n.op == "!=" && d != 0 || n.op == "<" && d < 0 ||
+				n.op == "<=" && d <= 0 || n.op == ">" && d > 0 || n.op == ">=" && d >= 0)
+		case "+":
+			if kind == j.KindString {
+				set(out, i, a.(string)+b.(string))
+				continue
+			}
+			arith(out, i, kind, a, b, func(x, y int64) int64 { return x + y }, func(x, y float64) float64 { return x + y })
+		case "-":
+			arith(out, i, kind, a, b, func(x, y int64) int64 { return x - y }, func(x, y float64) float64 { return x - y })
+		case "*":
+			arith(out, i, kind, a, b, func(x, y int64) int64 { return x * y }, func(x, y float64) float64 { return x * y })
+		case "/":
+			// division by zero is null
+			if y := toFloat(b); y != 0 {
+				set(out, i, toFloat(a)/y)
+			}
+		case "%":
+			if y := b.(int64); y != 0 {
+				set(out, i, a.(int64)%y)
+			}
+		}
+	}
+	return out, nil
+}
+
+func arith(out j.Column, i int, kind j.Kind, a, b any, fi func(x, y int64) int64, ff func(x, y float64) float64) {
+	if kind == j.KindInt {
+		set(out, i, fi(a.(int64), b.(int64)))
+		return
+	}
+	set(out, i, ff(toFloat(a), toFloat(b)))
+}
+
+func (e *evaluator) in(n *inList) (j.Column, error) {
+	x, err := e.eval(n.x)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]j.Column, len(n.list))
+	for k, item := range n.list {
+		if items[k], err = e.eval(item); err != nil {
+			return nil, err
+		}
+	}
+	out := e.column(j.KindBool)
+	for i := 0; i < e.n; i++ {
+		v := j.Value(x, i)
+		if v == nil {
+			continue
+		}
+		found := false
+		for _, item := range items {
+			if w := j.Value(item, i); w != nil && compare(v, w) == 0 {
+				found = true
+				break
+			}
+		}
+		set(out, i, found != n.not)
+	}
+	return out, nil
+}
+
+func (e *evaluator) call(n *call) (j.Column, error) {
+	args := make([]j.Column, len(n.args))
+	for k, a := range n.args {
+		var err error
+		if args[k], err = e.eval(a); err != nil {
+			return nil, err
+		}
+	}
+	out := e.column(e.c.kinds[n])
+	for i := 0; i < e.n; i++ {
+		if n.fn == "coalesce" {
+			for _, a := range args {
+				if v := j.Value(a, i); v != nil {
+					if out.Kind() == j.KindFloat {
+						v = toFloat(v)
+					}
+					set(out, i, v)
+					break
+				}
+			}
+			continue
+		}
+		v := j.Value(args[0], i)
+		if n.fn == "is_null" {
+			set(out, i, v == nil)
+			continue
+		}
+		if v == nil {
+			continue
+		}
+		switch n.fn {
+		case "trim":
+			set(out, i, strings.TrimSpace(v.(string)))
+		case "lower":
+			set(out, i, strings.ToLower(v.(string)))
+		case "upper":
+			set(out, i, strings.ToUpper(v.(string)))
+		case "len":
+			set(out, i, int64(len([]rune(v.(string)))))
+		case "abs":
+			if x, ok := v.(int64); ok {
+				if x < 0 {
+					x = -x
+				}
+				set(out, i, x)
+			} else {
+				set(out, i, math.Abs(v.(float64)))
+			}
+		case "str":
+			set(out, i, toString(v))
+		}
+	}
+	return out, nil
+}
+
+// compare orders two non-null values of comparable kinds; ints and floats
+// compare as numbers.
+func compare(a, b any) int {
+	switch x := a.(type) {
+	case int64:
+		if y, ok := b.(int64); ok {
+			return cmp3(x < y, x > y)
+		}
+	case string:
+		return strings.Compare(x, b.(string))
+	case time.Time:
+		return x.Compare(b.(time.Time))
+	case bool:
+		y := b.(bool)
+		return cmp3(!x && y, x && !y)
+	}
+	x, y := toFloat(a), toFloat(b)
+	return cmp3(x < y, x > y)
+}
+
+func cmp3(less, more bool) int {
+	switch {
+	case less:
+		return -1
+	case more:
+		return 1
+	}
+	return 0
+}
+
+func toFloat(v any) float64 {
+	if x, ok := v.(int64); ok {
+		return float64(x)
+	}
+	return v.(float64)
+}
+
+func toString(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	case time.Time:
+		return iox.FormatTime(x)
+	}
+	return ""
+}
+
+// set stores v, already of the column's kind, in row i of c.
+func set(c j.Column, i int, v any) {
+	switch c := c.(type) {
+	case *j.BoolColumn:
+		c.Set(i, v.(bool))
+	case *j.IntColumn:
+		c.Set(i, v.(int64))
+	case *j.FloatColumn:
+		c.Set(i, v.(float64))
+	case *j.StringColumn:
+		c.Set(i, v.(string))
+	case *j.TimeColumn:
+		c.Set(i, v.(time.Time))
+	}
+}
@@ -0,0 +1,180 @@
+// Package expr parses and evaluates small typed expressions over the columns
+// of a janitor.Frame, for derived columns and row predicates:
+//
+//	price * qty
+//	trim(first) + " " + trim(last)
+//	age >= 18 && country in ("US", "CA")
+//
+// Operands are column names (backticks quote any other name), int, float,
+// string ("..." or '...'), true, false and null literals. Operators, loosest
+// first: ||; &&; == != < <= > >= in (...) not in (...); + -; * / %; unary !
+// and -. + also joins strings; / always gives a float and dividing by zero
+// gives null. A string literal compared with a time column is parsed as a
+// time. Functions: trim, lower, upper, len, abs, str (to string), is_null and
+// coalesce.
+//
+// A null operand makes the result null, except that && and || follow
+// three-valued logic (false && null is false, true || null is true), is_null
+// never returns null and coalesce returns its first non-null argument.
+// Expressions are evaluated a column at a time.
+package expr
+
+import (
+	"fmt"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+// Expr is a parsed expression. It is typed against a schema by Type, Check or
+// Eval, so one Expr can be used with frames of different schemas.
+type Expr struct {
+	src  string
+	root node
+}
+
+// Parse parses src. Errors carry the byte offset of the problem.
+func Parse(src string) (*Expr, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %w", src, err)
+	}
+	p := &parser{toks: toks}
+	root, err := p.parseLevel(0)
+	if err == nil && p.peek().kind != tEOF {
+		err = p.unexpected(p.peek(), "an operator or end of expression")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %w", src, err)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// String returns the source text.
+func (e *Expr) String() string { return e.src }
+
+// Columns returns the names of the columns e refers to, in order of first use.
+func (e *Expr) Columns() []string {
+	var names []string
+	seen := map[string]bool{}
+	var walk func(n node)
+	walk = func(n node) {
+		switch n := n.(type) {
+		case *colRef:
+			if !seen[n.name] {
+				seen[n.name] = true
+				names = append(names, n.name)
+			}
+		case *unary:
+			walk(n.x)
+		case *binary:
+			walk(n.l)
+			walk(n.r)
+		case *inList:
+			walk(n.x)
+			for _, item := range n.list {
+				walk(item)
+			}
+		case *call:
+			for _, a := range n.args {
+				walk(a)
+			}
+		}
+	}
+	walk(e.root)
+	return names
+}
+
+// Type returns the kind of the values e yields over frames of schema, or a
+// type error. An expression that is always null (such as `null`) has kind
+// string.
+func (e *Expr) Type(schema j.Schema) (j.Kind, error) {
+	c, err := e.check(schema)
+	if err != nil {
+		return j.KindInvalid, err
+	}
+	return e.kind(c), nil
+}
+
+// Check reports whether e is a valid predicate over schema: it must type
+// check and yield bools.
+func (e *Expr) Check(schema j.Schema) error {
+	c, err := e.check(schema)
+	if err != nil {
+		return err
+	}
+	return e.predicate(c)
+}
+
+// Eval computes e for every row of f and returns the result as a new column
+// of the kind Type reports, with an empty name.
+func (e *Expr) Eval(f *j.Frame) (j.Column, error) {
+	c, err := e.check(f.Schema())
+	if err != nil {
+		return nil, err
+	}
+	return e.eval(f, c)
+}
+
+func (e *Expr) eval(f *j.Frame, c *checker) (j.Column, error) {
+	ev := &evaluator{f: f, c: c, n: f.Rows()}
+	out, err := ev.eval(e.root)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := e.root.(*colRef); ok {
+		// never hand out the frame's own column
+		cp := ev.column(out.Kind())
+		for i := 0; i < f.Rows(); i++ {
+			if v := j.Value(out, i); v != nil {
+				set(cp, i, v)
+			}
+		}
+		out = cp
+	}
+	return out, nil
+}
+
+// Mask evaluates e as a predicate: rows where it is true match; false and
+// null do not. e is type checked once per call, as Eval does.
+func (e *Expr) Mask(f *j.Frame) ([]bool, error) {
+	c, err := e.check(f.Schema())
+	if err != nil {
+		return nil, err
+	}
+	if err := e.predicate(c); err != nil {
+		return nil, err
+	}
+	col, err := e.eval(f, c)
+	if err != nil {
+		return nil, err
+	}
+	mask := make([]bool, f.Rows())
+	for i := range mask {
+		mask[i] = j.Value(col, i) == true
+	}
+	return mask, nil
+}
+
+// kind is the kind e yields under c; always-null expressions count as
+// strings.
+func (e *Expr) kind(c *checker) j.Kind {
+	if k := c.kinds[e.root]; k != j.KindInvalid {
+		return k
+	}
+	return j.KindString
+}
+
+func (e *Expr) predicate(c *checker) error {
+	if k := e.kind(c); k != j.KindBool {
+		return fmt.Errorf("expression %q: is %s, want a bool condition", e.src, k)
+	}
+	return nil
+}
+
+func (e *Expr) check(schema j.Schema) (*checker, error) {
+	c := newChecker(schema)
+	if _, err := c.typeOf(e.root); err != nil {
+		return nil, fmt.Errorf("expression %q: %w", e.src, err)
+	}
+	return c, nil
+}
@@ -0,0 +1,140 @@
+package expr
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+func testFrame(t *testing.T) *j.Frame {
+	t.Helper()
+	f := j.NewFrame(j.Schema{Columns: []j.ColumnSchema{
+		{Name: "price", Type: j.KindFloat, Nullable: true},
+		{Name: "qty", Type: j.KindInt, Nullable: true},
+		{Name: "first", Type: j.KindString, Nullable: true},
+		{Name: "last", Type: j.KindString, Nullable: true},
+		{Name: "country", Type: j.KindString, Nullable: true},
+		{Name: "at", Type: j.KindTime, Nullable: true},
+		{Name: "user.age", Type: j.KindInt, Nullable: true},
+	}})
+	rows := [][]any{
+		{2.5, int64(4), " Ann ", "Lee", "US", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), int64(30)},
+		{1.0, nil, "Bob", " Ray", "FR", time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), int64(17)},
+		{nil, int64(2), nil, "Kim", "CA", nil, nil},
+	}
+	for r, row := range rows {
+		f.AppendNullRow()
+		for c, v := range row {
+			if err := f.SetCell(r, f.Schema().Columns[c].Name, v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return f
+}
+
+func eval(t *testing.T, f *j.Frame, src string) []any {
+	t.Helper()
+	e, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	col, err := e.Eval(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]any, col.Len())
+	for i := range out {
+		out[i] = j.Value(col, i)
+	}
+	return out
+}
+
+func TestEval(t *testing.T) {
+	f := testFrame(t)
+	cases := []struct {
+		src  string
+		want []any
+	}{
+		{`price * qty`, []any{10.0, nil, nil}},
+		{`qty * 2 + 1`, []any{int64(9), nil, int64(5)}},
+		{`qty / 4`, []any{1.0, nil, 0.5}},
+		{`trim(first) + " " + trim(last)`, []any{"Ann Lee", "Bob Ray", nil}},
+		{`qty >= 3 || country == "CA"`, []any{true, nil, true}},
+		{`user.age >= 18 && country in ("US", "CA")`, []any{true, false, nil}},
+		{`country not in ('US')`, []any{false, true, true}},
+		{`at >= "2024-01-01"`, []any{true, false, nil}},
+		{`coalesce(price, qty, -1)`, []any{2.5, 1.0, 2.0}},
+		{`is_null(first) || !(len(last) > 3)`, []any{true, false, true}},
+		{"`country`", []any{"US", "FR", "CA"}},
+		{`str(qty) + "x"`, []any{"4x", nil, "2x"}},
+		{`qty % 0`, []any{nil, nil, nil}},
+	}
+	for _, c := range cases {
+		if got := eval(t, f, c.src); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s = %v, want %v", c.src, got, c.want)
+		}
+	}
+}
+
+func TestTypeErrors(t *testing.T) {
+	s := testFrame(t).Schema()
+	cases := map[string]string{
+		`price * "x"`:          "cannot apply *",
+		`prize > 1`:            `unknown column "prize"`,
+		`first > 1`:            "cannot compare string with int",
+		`at > "yesterday"`:     "is not a time",
+		`trim(qty)`:            "trim needs a string",
+		`frob(qty)`:            `unknown function "frob"`,
+		`country in ("US", 1)`: "cannot compare string with int",
+		`qty && true`:          "&& needs bools",
+	}
+	for src, want := range cases {
+		e, err := Parse(src)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		if _, err := e.Type(s); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: error %v, want %q", src, err, want)
+		}
+	}
+	e, _ := Parse(`price * qty`)
+	if err := e.Check(s); err == nil {
+		t.Error("a float expression should not check as a predicate")
+	}
+	for _, src := range []string{`price *`, `(qty`, `"open`, `qty ? 1`, `qty in 1`} {
+		if _, err := Parse(src); err == nil {
+			t.Errorf("%s: expected a syntax error", src)
+		}
+	}
+}
+
+func TestMask(t *testing.T) {
+	f := testFrame(t)
+	e, err := Parse(`qty > 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mask, err := e.Mask(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(mask, []bool{true, false, true}) {
+		t.Fatalf("mask = %v", mask)
+	}
+	if got := e.Columns(); !reflect.DeepEqual(got, []string{"qty"}) {
+		t.Fatalf("columns = %v", got)
+	}
+	for _, src := range []string{`qty + 1`, `null`, `nope > 1`} {
+		e, err := Parse(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e.Mask(f); err == nil {
+			t.Errorf("%s: expected an error masking with it", src)
+		}
+	}
+}
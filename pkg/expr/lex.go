@@ -0,0 +1,128 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tEOF tokenKind = iota
+	tIdent
+	tName // `quoted` column name, never a keyword or function
+	tInt
+	tFloat
+	tString
+	tOp
+	tLParen
+	tRParen
+	tComma
+)
+
+type token struct {
+	kind tokenKind
+	text string // identifier name, unquoted string, number or operator
+	pos  int    // byte offset in the source
+}
+
+// lex splits src into tokens. Identifiers may contain dots (flattened JSON
+// keys); any other column name can be written in backticks.
+func lex(src string) ([]token, error) {
+	var out []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			out = append(out, token{tLParen, "(", i})
+			i++
+		case c == ')':
+			out = append(out, token{tRParen, ")", i})
+			i++
+		case c == ',':
+			out = append(out, token{tComma, ",", i})
+			i++
+		case c == '"' || c == '\'':
+			s, n, err := lexString(src[i:], c)
+			if err != nil {
+				return nil, &Error{Pos: i, Msg: err.Error()}
+			}
+			out = append(out, token{tString, s, i})
+			i += n
+		case c == '`':
+			end := strings.IndexByte(src[i+1:], '`')
+			if end < 0 {
+				return nil, &Error{Pos: i, Msg: "unterminated `name`"}
+			}
+			out = append(out, token{tName, src[i+1 : i+1+end], i})
+			i += end + 2
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			start, kind := i, tInt
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.' || src[i] == 'e' || src[i] == 'E' ||
+				(src[i] == '-' || src[i] == '+') && (src[i-1] == 'e' || src[i-1] == 'E')) {
+				if src[i] == '.' || src[i] == 'e' || src[i] == 'E' {
+					kind = tFloat
+				}
+				i++
+			}
+			out = append(out, token{kind, src[start:i], start})
+		case identStart(c):
+			start := i
+			for i < len(src) && (identStart(src[i]) || src[i] >= '0' && src[i] <= '9' || src[i] == '.') {
+				i++
+			}
+			out = append(out, token{tIdent, src[start:i], start})
+		default:
+			op := ""
+			for _, o := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "+", "-", "*", "/", "%", "!"} {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, &Error{Pos: i, Msg: fmt.Sprintf("unexpected character %q", c)}
+			}
+			out = append(out, token{tOp, op, i})
+			i += len(op)
+		}
+	}
+	return append(out, token{tEOF, "", len(src)}), nil
+}
+
+// lexString reads a quoted string starting at s[0] and returns its value and
+// length in s. Backslash escapes \n, \t, \\ and the quote character.
+func lexString(s string, quote byte) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case quote:
+			return b.String(), i + 1, nil
+		case '\\':
+			i++
+			if i == len(s) {
+				break
+			}
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// identStart reports whether c can start an identifier; bytes of non-ASCII
+// UTF-8 sequences are accepted so names in any script work unquoted.
+func identStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
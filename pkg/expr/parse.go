@@ -0,0 +1,240 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Error is a syntax or type error at a byte offset of the expression.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("at offset %d: %s", e.Pos, e.Msg) }
+
+type node interface{ pos() int }
+
+type colRef struct {
+	p    int
+	name string
+}
+
+// lit is a literal: int64, float64, string, bool, time.Time or nil.
+type lit struct {
+	p int
+	v any
+}
+
+type unary struct {
+	p  int
+	op string
+	x  node
+}
+
+type binary struct {
+	p    int
+	op   string
+	l, r node
+}
+
+type inList struct {
+	p    int
+	x    node
+	list []node
+	not  bool
+}
+
+type call struct {
+	p    int
+	fn   string
+	args []node
+}
+
+func (n *colRef) pos() int { return n.p }
+func (n *lit) pos() int    { return n.p }
+func (n *unary) pos() int  { return n.p }
+func (n *binary) pos() int { return n.p }
+func (n *inList) pos() int { return n.p }
+func (n *call) pos() int   { return n.p }
+
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, p.unexpected(t, what)
+	}
+	return t, nil
+}
+
+func (p *parser) unexpected(t token, want string) error {
+	if t.kind == tEOF {
+		return &Error{Pos: t.pos, Msg: "unexpected end of expression, want " + want}
+	}
+	return &Error{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q, want %s", t.text, want)}
+}
+
+// binary operators by precedence, loosest first
+var levels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) parseLevel(l int) (node, error) {
+	if l == len(levels) {
+		return p.parseUnary()
+	}
+	left, err := p.parseLevel(l + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if l == 2 && t.kind == tIdent && (t.text == "in" || t.text == "not") {
+			if left, err = p.parseIn(left); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if t.kind != tOp || !contains(levels[l], t.text) {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseLevel(l + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{p: t.pos, op: t.text, l: left, r: right}
+	}
+}
+
+// parseIn parses `in (a, b, ...)` or `not in (...)` after x.
+func (p *parser) parseIn(x node) (node, error) {
+	t := p.next()
+	n := &inList{p: t.pos, x: x, not: t.text == "not"}
+	if n.not {
+		if in := p.next(); in.kind != tIdent || in.text != "in" {
+			return nil, p.unexpected(in, `"in" after "not"`)
+		}
+	}
+	if _, err := p.expect(tLParen, `"("`); err != nil {
+		return nil, err
+	}
+	for {
+		item, err := p.parseLevel(0)
+		if err != nil {
+			return nil, err
+		}
+		n.list = append(n.list, item)
+		t := p.next()
+		if t.kind == tRParen {
+			return n, nil
+		}
+		if t.kind != tComma {
+			return nil, p.unexpected(t, `"," or ")"`)
+		}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	t := p.peek()
+	if t.kind == tOp && (t.text == "!" || t.text == "-") {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unary{p: t.pos, op: t.text, x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tInt:
+		v, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("bad number %q", t.text)}
+		}
+		return &lit{p: t.pos, v: v}, nil
+	case tFloat:
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("bad number %q", t.text)}
+		}
+		return &lit{p: t.pos, v: v}, nil
+	case tString:
+		return &lit{p: t.pos, v: t.text}, nil
+	case tName:
+		return &colRef{p: t.pos, name: t.text}, nil
+	case tLParen:
+		x, err := p.parseLevel(0)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tRParen, `")"`); err != nil {
+			return nil, err
+		}
+		return x, nil
+	case tIdent:
+		switch t.text {
+		case "true":
+			return &lit{p: t.pos, v: true}, nil
+		case "false":
+			return &lit{p: t.pos, v: false}, nil
+		case "null":
+			return &lit{p: t.pos, v: nil}, nil
+		}
+		if p.peek().kind != tLParen {
+			return &colRef{p: t.pos, name: t.text}, nil
+		}
+		p.next()
+		c := &call{p: t.pos, fn: t.text}
+		if p.peek().kind == tRParen {
+			p.next()
+			return c, nil
+		}
+		for {
+			arg, err := p.parseLevel(0)
+			if err != nil {
+				return nil, err
+			}
+			c.args = append(c.args, arg)
+			t := p.next()
+			if t.kind == tRParen {
+				return c, nil
+			}
+			if t.kind != tComma {
+				return nil, p.unexpected(t, `"," or ")"`)
+			}
+		}
+	}
+	return nil, p.unexpected(t, "a value, column or function")
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
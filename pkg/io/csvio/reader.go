@@ -19,12 +19,34 @@ type ReaderOptions struct {
     Delimiter  rune // 0 = sniff, default ','
     SampleRows int  // for inference; default 100
     Strict     bool // if true, error on short/long records
+    // TimeFormats are Go layouts (or "unix"/"unix_ms") for time columns;
+    // empty uses iox.DefaultTimeFormats.
+    TimeFormats []string
+    // TimeZone applies to times without an offset; default UTC.
+    TimeZone string
+    // TrueValues and FalseValues are the case-insensitive boolean literals;
+    // empty uses iox.DefaultTrueValues/DefaultFalseValues ("1"/"0" are opt-in).
+    TrueValues  []string
+    FalseValues []string
+    // Schema pins the type, nullability, time format or name of listed
+    // columns; the rest are inferred.
+    Schema iox.SchemaOverride
+    // OnCastError is what happens to a value that does not parse as its
+    // column's type (default iox.CastNull). Rows rejected by iox.CastReject
+    // go to Rejects, or are dropped when it is nil.
+    OnCastError iox.CastPolicy
+    Rejects     j.ChunkSink
 }
 
 type Reader struct {
     r   *csv.Reader
     opt ReaderOptions
     buf [][]string
+    times *iox.TimeParser
+    bools *iox.BoolParser
+    colTimes []*iox.TimeParser // per-column time parsers from opt.Schema
+    casts *iox.CastErrors
+    ncols int // input columns; the schema may add <col>__raw columns after them
     // repair/warning counters
     shortRecords int
     longRecords  int
@@ -34,16 +56,23 @@ type Reader struct {
 func Open(path string, opt ReaderOptions) (*Reader, *os.File, error) {
     var f *os.File
     var err error
-    if path != "-" {
+    if path != "-" && path != "" {
         f, err = os.Open(path)
         if err != nil { return nil, nil, err }
     }
     rc, err := iox.OpenMaybeCompressed(path)
     if err != nil { _ = f.Close(); return nil, nil, err }
-    rr := csv.NewReader(rc)
-    // sniff delimiter if 0
+    times, err := iox.NewTimeParser(opt.TimeFormats, opt.TimeZone)
+    if err != nil { _ = rc.Close(); _ = f.Close(); return nil, nil, err }
+    bools, err := iox.NewBoolParser(opt.TrueValues, opt.FalseValues)
+    if err != nil { _ = rc.Close(); _ = f.Close(); return nil, nil, err }
+    policy, err := iox.ParseCastPolicy(string(opt.OnCastError))
+    if err != nil { _ = rc.Close(); _ = f.Close(); return nil, nil, err }
+    br := bufio.NewReader(rc)
+    rr := csv.NewReader(br)
+    // sniff delimiter if 0, from the buffered head so that stdin is read once
     if opt.Delimiter == 0 {
-        if d, lazy, err := sniffDelimiterAndQuotes(path); err == nil && d != 0 {
+        if d, lazy := sniffDelimiterAndQuotes(br); d != 0 {
             rr.Comma = d
             rr.LazyQuotes = lazy
         }
@@ -51,15 +80,23 @@ func Open(path string, opt ReaderOptions) (*Reader, *os.File, error) {
         rr.Comma = opt.Delimiter
     }
     rr.ReuseRecord = true
-    return &Reader{r: rr, opt: opt}, f, nil
+    return &Reader{r: rr, opt: opt, times: times, bools: bools, casts: iox.NewCastErrors(policy, opt.Rejects)}, f, nil
 }
 
 // NewReaderFrom constructs a Reader from an arbitrary io.Reader (stdin, pipe).
+// Invalid time or boolean options fall back to the defaults; use Open to have
+// them reported.
 func NewReaderFrom(r io.Reader, opt ReaderOptions) *Reader {
     rr := csv.NewReader(r)
     if opt.Delimiter != 0 { rr.Comma = opt.Delimiter }
     rr.ReuseRecord = true
-    return &Reader{r: rr, opt: opt}
+    times, err := iox.NewTimeParser(opt.TimeFormats, opt.TimeZone)
+    if err != nil { times, _ = iox.NewTimeParser(opt.TimeFormats, "") }
+    bools, err := iox.NewBoolParser(opt.TrueValues, opt.FalseValues)
+    if err != nil { bools, _ = iox.NewBoolParser(nil, nil) }
+    policy, err := iox.ParseCastPolicy(string(opt.OnCastError))
+    if err != nil { policy = iox.CastNull }
+    return &Reader{r: rr, opt: opt, times: times, bools: bools, casts: iox.NewCastErrors(policy, opt.Rejects)}
 }
 
 // InferSchema reads header (if present) and samples rows to determine column kinds.
@@ -90,7 +127,8 @@ func (r *Reader) InferSchema() (j.Schema, []string, error) {
 		}
 	}
 
-	sample := [][]string{rec}
+	// ReuseRecord is on, so buffered sample rows must be copied
+	sample := [][]string{append([]string(nil), rec...)}
 	max := r.opt.SampleRows
 	if max <= 0 {
 		max = 100
@@ -103,14 +141,20 @@ func (r *Reader) InferSchema() (j.Schema, []string, error) {
 		if err != nil {
 			return j.Schema{}, nil, err
 		}
-		sample = append(sample, rr)
+		sample = append(sample, append([]string(nil), rr...))
 	}
 
-	kinds := inferKinds(sample)
+	kinds := inferKinds(sample, r.times, r.bools)
 	schema := j.Schema{Columns: make([]j.ColumnSchema, len(names))}
 	for i := range names {
 		schema.Columns[i] = j.ColumnSchema{Name: names[i], Type: kinds[i], Nullable: true}
 	}
+	schema, r.colTimes, err = r.opt.Schema.Apply(schema, r.times)
+	if err != nil {
+		return j.Schema{}, nil, err
+	}
+	r.ncols = len(schema.Columns)
+	schema = r.casts.Schema(schema)
 	// retain sampled rows for subsequent ReadAll
 	r.buf = append(r.buf, sample...)
 	return schema, names, nil
@@ -119,38 +163,24 @@ func (r *Reader) InferSchema() (j.Schema, []string, error) {
 // ReadAll loads the rest of the CSV into a Frame.
 func (r *Reader) ReadAll(schema j.Schema) (*j.Frame, error) {
     f := j.NewFrame(schema)
+    cols := r.inputColumns(schema)
     // drain buffered records from inference (if any)
     for len(r.buf) > 0 {
         rec := r.buf[0]
         r.buf = r.buf[1:]
         f.AppendNullRow()
         row := f.Rows() - 1
-        for i, cs := range schema.Columns {
+        for i, cs := range cols {
             if i >= len(rec) {
                 r.shortRecords++
-                if r.opt.Strict { return nil, fmt.Errorf("csv short record at buffered read: need %d fields, got %d", len(schema.Columns), len(rec)) }
+                if r.opt.Strict { return nil, fmt.Errorf("csv short record at buffered read: need %d fields, got %d", len(cols), len(rec)) }
                 continue
             }
             val := strings.ToValidUTF8(strings.TrimSpace(rec[i]), "?")
             if val == "" {
                 continue
             }
-            switch cs.Type {
-            case j.KindFloat:
-				if x, err := strconv.ParseFloat(val, 64); err == nil {
-					_ = f.SetCell(row, cs.Name, x)
-				}
-			case j.KindInt:
-				if x, err := strconv.ParseInt(val, 10, 64); err == nil {
-					_ = f.SetCell(row, cs.Name, x)
-				}
-			case j.KindBool:
-				if x, err := strconv.ParseBool(strings.ToLower(val)); err == nil {
-					_ = f.SetCell(row, cs.Name, x)
-				}
-			default:
-				_ = f.SetCell(row, cs.Name, val)
-			}
+            if err := r.setCell(f, row, i, cs, val); err != nil { return nil, err }
 		}
     }
     for {
@@ -164,42 +194,76 @@ func (r *Reader) ReadAll(schema j.Schema) (*j.Frame, error) {
         // append a null row then set non-empty values
         f.AppendNullRow()
         row := f.Rows() - 1
-        if len(rec) > len(schema.Columns) {
+        if len(rec) > len(cols) {
             r.longRecords++
-            if r.opt.Strict { return nil, fmt.Errorf("csv long record at row: need %d fields, got %d", len(schema.Columns), len(rec)) }
+            if r.opt.Strict { return nil, fmt.Errorf("csv long record at row: need %d fields, got %d", len(cols), len(rec)) }
         }
-        for i, cs := range schema.Columns {
+        for i, cs := range cols {
             if i >= len(rec) {
                 r.shortRecords++
-                if r.opt.Strict { return nil, fmt.Errorf("csv short record at row: need %d fields, got %d", len(schema.Columns), len(rec)) }
+                if r.opt.Strict { return nil, fmt.Errorf("csv short record at row: need %d fields, got %d", len(cols), len(rec)) }
                 continue
             }
             val := strings.ToValidUTF8(strings.TrimSpace(rec[i]), "?")
             if val == "" {
                 continue
             }
-            switch cs.Type {
-            case j.KindFloat:
-				if x, err := strconv.ParseFloat(val, 64); err == nil {
-					_ = f.SetCell(row, cs.Name, x)
-				}
-			case j.KindInt:
-				if x, err := strconv.ParseInt(val, 10, 64); err == nil {
-					_ = f.SetCell(row, cs.Name, x)
-				}
-			case j.KindBool:
-				if x, err := strconv.ParseBool(strings.ToLower(val)); err == nil {
-					_ = f.SetCell(row, cs.Name, x)
-				}
-			default:
-				_ = f.SetCell(row, cs.Name, val)
-			}
+            if err := r.setCell(f, row, i, cs, val); err != nil { return nil, err }
 		}
 	}
+    f, err := r.casts.Finish(f)
+    if err != nil { return nil, err }
+    if err := iox.CheckNullable(f); err != nil { return nil, err }
     return f, nil
 }
 
-func inferKinds(rows [][]string) []j.Kind {
+// setCell parses val as column i and stores it, handing values that do not
+// parse to the cast policy.
+func (r *Reader) setCell(f *j.Frame, row, i int, cs j.ColumnSchema, val string) error {
+    x, ok := r.parse(val, cs.Type, r.timeParser(i))
+    if !ok { return r.casts.Fail(f, row, cs.Name, cs.Type, val) }
+    _ = f.SetCell(row, cs.Name, x)
+    return nil
+}
+
+// parse reads val as kind, reporting false when it does not parse.
+func (r *Reader) parse(val string, kind j.Kind, times *iox.TimeParser) (any, bool) {
+    switch kind {
+    case j.KindFloat:
+        v, err := strconv.ParseFloat(val, 64)
+        return v, err == nil
+    case j.KindInt:
+        v, err := strconv.ParseInt(val, 10, 64)
+        return v, err == nil
+    case j.KindBool:
+        return r.bools.Parse(val)
+    case j.KindTime:
+        return times.Parse(val)
+    }
+    return val, true
+}
+
+// inputColumns returns the columns of schema read from CSV fields, leaving
+// out the <col>__raw columns added by iox.CastKeepRaw.
+func (r *Reader) inputColumns(schema j.Schema) []j.ColumnSchema {
+    if r.ncols > 0 && r.ncols < len(schema.Columns) { return schema.Columns[:r.ncols] }
+    return schema.Columns
+}
+
+// CastFailures returns the number of values per column that did not parse as
+// the column's type.
+func (r *Reader) CastFailures() map[string]int { return r.casts.Counts() }
+
+// timeParser returns the time parser for column i.
+func (r *Reader) timeParser(i int) *iox.TimeParser {
+    if i < len(r.colTimes) { return r.colTimes[i] }
+    return r.times
+}
+
+// inferKinds picks a kind per column. A column is a time (or a bool) when
+// every non-empty sampled value parses as one; those checks come before the
+// numeric one so epoch integers and opted-in 1/0 flags win over Int.
+func inferKinds(rows [][]string, times *iox.TimeParser, bools *iox.BoolParser) []j.Kind {
 	if len(rows) == 0 {
 		return nil
 	}
@@ -208,6 +272,14 @@ func inferKinds(rows [][]string) []j.Kind {
 	// numeric regex similar to old code
 	numre := regexp.MustCompile(`^[-+]?[0-9]*\.?[0-9]+([eE][-+]?[0-9]+)?$`)
 	for c := 0; c < ncol; c++ {
+		if allValues(rows, c, times.Looks) {
+			kinds[c] = j.KindTime
+			continue
+		}
+		if allValues(rows, c, func(v string) bool { _, ok := bools.Parse(v); return ok }) {
+			kinds[c] = j.KindBool
+			continue
+		}
 		num, integer, str := 0, 0, 0
 		for _, row := range rows {
 			if c >= len(row) {
@@ -223,11 +295,6 @@ func inferKinds(rows [][]string) []j.Kind {
 					integer++
 				}
 			} else {
-				// try bool
-				lv := strings.ToLower(v)
-				if lv == "true" || lv == "false" {
-					continue
-				}
 				str++
 			}
 		}
@@ -245,13 +312,29 @@ func inferKinds(rows [][]string) []j.Kind {
 	return kinds
 }
 
-func sniffDelimiterAndQuotes(path string) (rune, bool, error) {
-    rc, err := iox.OpenMaybeCompressed(path)
-    if err != nil { return 0, false, err }
-    defer func() { _ = rc.Close() }()
-    br := bufio.NewReader(rc)
+// allValues reports whether column c has a non-empty value and every
+// non-empty value satisfies ok.
+func allValues(rows [][]string, c int, ok func(string) bool) bool {
+	seen := false
+	for _, row := range rows {
+		if c >= len(row) {
+			continue
+		}
+		v := strings.TrimSpace(row[c])
+		if v == "" {
+			continue
+		}
+		if !ok(v) {
+			return false
+		}
+		seen = true
+	}
+	return seen
+}
+
+func sniffDelimiterAndQuotes(br *bufio.Reader) (rune, bool) {
     sample, _ := br.Peek(4096)
-    if len(sample) == 0 { return ',', false, nil }
+    if len(sample) == 0 { return ',', false }
     candidates := []byte{',', '\t', ';', '|'}
     best := byte(',')
     bestCount := -1
@@ -266,7 +349,7 @@ func sniffDelimiterAndQuotes(path string) (rune, bool, error) {
     quoteCount := 0
     for _, b := range sample { if b == '"' { quoteCount++ } }
     lazy := quoteCount%2 != 0 || quoteCount > 0
-    return rune(best), lazy, nil
+    return rune(best), lazy
 }
 
 // Warnings returns a summary string of any repairs/mismatches encountered.
@@ -1,9 +1,13 @@
 package csvio
 
 import (
+	"encoding/json"
+	iox "github.com/wdm0006/janitor/pkg/io/ioutils"
 	j "github.com/wdm0006/janitor/pkg/janitor"
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestInferAndRead(t *testing.T) {
@@ -32,3 +36,262 @@ func TestInferAndRead(t *testing.T) {
 		t.Fatalf("expected some rows, got %d", fr.Rows())
 	}
 }
+
+func TestSampledRowsKeepValues(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "small.csv")
+	if err := os.WriteFile(p, []byte("name,age\na,1\nb,2\nc,3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, f, err := Open(p, ReaderOptions{HasHeader: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	schema, _, err := r.InferSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fr, err := r.ReadAll(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	col, _ := fr.ColumnByName("name")
+	for i, want := range []string{"a", "b", "c"} {
+		if v, _ := col.(*j.StringColumn).Get(i); v != want {
+			t.Fatalf("row %d: name = %q, want %q", i, v, want)
+		}
+	}
+}
+
+func TestTimeColumns(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "times.csv")
+	data := "at,day,local,epoch,n\n" +
+		"2024-03-05T10:30:00Z,2024-03-05,2024-03-05 10:30:00,1709634600,1\n" +
+		"2024-03-06T11:00:00+02:00,2024-03-06,,1709719200,2\n"
+	if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	read := func(opt ReaderOptions) *j.Frame {
+		t.Helper()
+		opt.HasHeader = true
+		r, f, err := Open(p, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = f.Close() }()
+		schema, _, err := r.InferSchema()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fr, err := r.ReadAll(schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fr
+	}
+	fr := read(ReaderOptions{})
+	kinds := []j.Kind{j.KindTime, j.KindTime, j.KindTime, j.KindInt, j.KindInt}
+	for i, cs := range fr.Schema().Columns {
+		if cs.Type != kinds[i] {
+			t.Fatalf("default formats: %s kind %d, want %d", cs.Name, cs.Type, kinds[i])
+		}
+	}
+	col, _ := fr.ColumnByName("at")
+	if v, _ := col.(*j.TimeColumn).Get(1); !v.Equal(time.Date(2024, 3, 6, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("at[1] = %v", v)
+	}
+	col, _ = fr.ColumnByName("local")
+	if !col.IsNull(1) {
+		t.Fatal("local[1] should be null")
+	}
+
+	fr = read(ReaderOptions{TimeFormats: []string{"unix"}, TimeZone: "America/New_York"})
+	col, _ = fr.ColumnByName("epoch")
+	if _, ok := col.(*j.TimeColumn); !ok {
+		t.Fatal("epoch should be a time column when unix is enabled")
+	}
+	col, _ = fr.ColumnByName("n")
+	if _, ok := col.(*j.IntColumn); !ok {
+		t.Fatal("small integers should stay Int")
+	}
+	col, _ = fr.ColumnByName("local")
+	v, _ := col.(*j.TimeColumn).Get(0)
+	if want := time.Date(2024, 3, 5, 15, 30, 0, 0, time.UTC); !v.Equal(want) {
+		t.Fatalf("local[0] = %v, want %v", v, want)
+	}
+
+	if _, _, err := Open(p, ReaderOptions{TimeZone: "Mars/Olympus"}); err == nil {
+		t.Fatal("expected an error for an unknown time zone")
+	}
+}
+
+func TestBoolColumns(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "flags.csv")
+	// mixed holds bool literals among numbers, so it is neither bool nor int
+	data := "active,member,bit,word,mixed\nyes,T,1,y,5\nNo,f,0,maybe,true\n,t,1,n,false\n"
+	if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	read := func(opt ReaderOptions) *j.Frame {
+		t.Helper()
+		opt.HasHeader = true
+		sr, f, err := NewStreamReader(p, opt, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = f.Close() }()
+		fr, err := sr.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fr
+	}
+	fr := read(ReaderOptions{})
+	kinds := []j.Kind{j.KindBool, j.KindBool, j.KindInt, j.KindString, j.KindString}
+	for i, cs := range fr.Schema().Columns {
+		if cs.Type != kinds[i] {
+			t.Fatalf("defaults: %s kind %d, want %d", cs.Name, cs.Type, kinds[i])
+		}
+	}
+	col, _ := fr.ColumnByName("active")
+	b := col.(*j.BoolColumn)
+	if v, _ := b.Get(0); !v {
+		t.Fatal("active[0] should be true")
+	}
+	if v, _ := b.Get(1); v {
+		t.Fatal("active[1] should be false")
+	}
+	if !b.IsNull(2) {
+		t.Fatal("active[2] should be null")
+	}
+
+	fr = read(ReaderOptions{TrueValues: []string{"1", "true"}, FalseValues: []string{"0", "false"}})
+	if got := fr.Schema().Columns[2].Type; got != j.KindBool {
+		t.Fatalf("bit kind %d, want bool when 1/0 are opted in", got)
+	}
+	if got := fr.Schema().Columns[1].Type; got != j.KindString {
+		t.Fatalf("member kind %d, want string when t/f are not literals", got)
+	}
+
+	if _, _, err := Open(p, ReaderOptions{TrueValues: []string{"x"}, FalseValues: []string{"X"}}); err == nil {
+		t.Fatal("expected an error for a literal that is both true and false")
+	}
+}
+
+func TestSchemaOverride(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "zips.csv")
+	data := "id,zip,when\n1,02134,05/03/2024\n2,10001,\n"
+	if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var o iox.SchemaOverride
+	spec := `{"columns":[{"name":"zip","type":"string"},{"name":"when","type":"time","format":"02/01/2006","rename":"day"}]}`
+	if err := json.Unmarshal([]byte(spec), &o); err != nil {
+		t.Fatal(err)
+	}
+	r, f, err := Open(p, ReaderOptions{HasHeader: true, Schema: o})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	schema, _, err := r.InferSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []j.ColumnSchema{{Name: "id", Type: j.KindInt, Nullable: true}, {Name: "zip", Type: j.KindString, Nullable: true}, {Name: "day", Type: j.KindTime, Nullable: true}}
+	for i, cs := range schema.Columns {
+		if cs != want[i] {
+			t.Fatalf("column %d = %+v, want %+v", i, cs, want[i])
+		}
+	}
+	fr, err := r.ReadAll(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	col, _ := fr.ColumnByName("zip")
+	if v, _ := col.(*j.StringColumn).Get(0); v != "02134" {
+		t.Fatalf("zip[0] = %q", v)
+	}
+	col, _ = fr.ColumnByName("day")
+	if v, _ := col.(*j.TimeColumn).Get(0); !v.Equal(time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("day[0] = %v", v)
+	}
+
+	no := false
+	r, f2, err := Open(p, ReaderOptions{HasHeader: true, Schema: iox.SchemaOverride{Columns: []iox.ColumnOverride{{Name: "when", Nullable: &no}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f2.Close() }()
+	if schema, _, err = r.InferSchema(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReadAll(schema); err == nil {
+		t.Fatal("expected an error for a null in a non-nullable column")
+	}
+}
+
+type frameSink struct{ frames []*j.Frame }
+
+func (s *frameSink) Write(f *j.Frame) error { s.frames = append(s.frames, f); return nil }
+func (s *frameSink) Close() error           { return nil }
+
+func TestCastErrorPolicies(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "prices.csv")
+	if err := os.WriteFile(p, []byte("id,price\n1,12.5\n2,\"12,5\"\n3,N/A\n4,7\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pin := iox.SchemaOverride{Columns: []iox.ColumnOverride{{Name: "price", Type: j.KindFloat}}}
+	read := func(opt ReaderOptions) (*j.Frame, *Reader, error) {
+		t.Helper()
+		opt.HasHeader, opt.Schema = true, pin
+		r, f, err := Open(p, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = f.Close() }()
+		schema, _, err := r.InferSchema()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fr, err := r.ReadAll(schema)
+		return fr, r, err
+	}
+
+	fr, r, err := read(ReaderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fr.Rows() != 4 || r.CastFailures()["price"] != 2 {
+		t.Fatalf("null: rows=%d failures=%v", fr.Rows(), r.CastFailures())
+	}
+
+	fr, _, err = read(ReaderOptions{OnCastError: iox.CastKeepRaw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	col, ok := fr.ColumnByName("price__raw")
+	if !ok {
+		t.Fatal("keep_raw should add price__raw")
+	}
+	if v, _ := col.(*j.StringColumn).Get(1); v != "12,5" || !col.IsNull(0) {
+		t.Fatalf("price__raw[1] = %q", v)
+	}
+
+	sink := &frameSink{}
+	fr, _, err = read(ReaderOptions{OnCastError: iox.CastReject, Rejects: sink})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fr.Rows() != 2 || len(sink.frames) != 1 || sink.frames[0].Rows() != 2 {
+		t.Fatalf("reject: kept %d rows, sink got %d frames", fr.Rows(), len(sink.frames))
+	}
+	col, _ = sink.frames[0].ColumnByName(j.RejectRuleColumn)
+	if v, _ := col.(*j.StringColumn).Get(0); v != iox.CastRule {
+		t.Fatalf("reject_rule = %q", v)
+	}
+
+	if _, _, err = read(ReaderOptions{OnCastError: iox.CastFail}); err == nil {
+		t.Fatal("fail: expected an error")
+	}
+}
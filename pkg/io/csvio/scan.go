@@ -0,0 +1,81 @@
+package csvio
+
+import (
+    "io"
+    "strings"
+
+    j "github.com/wdm0006/janitor/pkg/janitor"
+    iox "github.com/wdm0006/janitor/pkg/io/ioutils"
+)
+
+// ScanSchema reads the whole file and returns its schema: each block of
+// opt.SampleRows records (default 100) is inferred on its own and merged into
+// the schema of the blocks before it by drift, whose events carry the first
+// data row of the block. Columns without a value in the file are
+// j.KindInvalid unless opt.Schema types them; see iox.FillUnknownKinds.
+func ScanSchema(path string, opt ReaderOptions, drift *iox.Drift) (j.Schema, error) {
+    opt.OnCastError, opt.Rejects = iox.CastNull, nil
+    r, f, err := Open(path, opt)
+    if err != nil { return j.Schema{}, err }
+    if f != nil { defer func() { _ = f.Close() }() }
+    _, names, err := r.InferSchema()
+    if err != nil { return j.Schema{}, err }
+    max := opt.SampleRows
+    if max <= 0 { max = 100 }
+    var schema j.Schema
+    block, row := r.buf, 1
+    for len(block) > 0 {
+        // pad or cut to the header width; inferKinds sizes columns from the first record
+        for i, rec := range block {
+            fields := make([]string, len(names))
+            copy(fields, rec)
+            block[i] = fields
+        }
+        kinds := inferKinds(block, r.times, r.bools)
+        next := j.Schema{Columns: make([]j.ColumnSchema, len(names))}
+        for i := range names {
+            if hasValue(block, i) {
+                kinds[i] = r.fitKind(block, i, kinds[i])
+            } else {
+                kinds[i] = j.KindInvalid
+            }
+            next.Columns[i] = j.ColumnSchema{Name: names[i], Type: kinds[i], Nullable: true}
+        }
+        next, _, err = r.opt.Schema.Apply(next, r.times)
+        if err != nil { return j.Schema{}, err }
+        schema, err = drift.Merge(schema, next, path, row)
+        if err != nil { return j.Schema{}, err }
+        row += len(block)
+        block = block[:0:0]
+        for len(block) < max {
+            rec, err := r.r.Read()
+            if err == io.EOF { break }
+            if err != nil { return j.Schema{}, err }
+            block = append(block, append([]string(nil), rec...))
+        }
+    }
+    return schema, nil
+}
+
+// fitKind widens kind (int -> float -> string) until every value of column c
+// in rows parses as it, so that a few odd values in a block show up as drift
+// rather than as cast failures.
+func (r *Reader) fitKind(rows [][]string, c int, kind j.Kind) j.Kind {
+    for _, row := range rows {
+        v := strings.TrimSpace(row[c])
+        if v == "" { continue }
+        for kind != j.KindString {
+            if _, ok := r.parse(v, kind, r.times); ok { break }
+            if kind == j.KindInt { kind = j.KindFloat } else { kind = j.KindString }
+        }
+    }
+    return kind
+}
+
+// hasValue reports whether column c has a non-blank value in rows.
+func hasValue(rows [][]string, c int) bool {
+    for _, row := range rows {
+        if c < len(row) && strings.TrimSpace(row[c]) != "" { return true }
+    }
+    return false
+}
@@ -0,0 +1,55 @@
+package csvio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
+	iox "github.com/wdm0006/janitor/pkg/io/ioutils"
+)
+
+func TestScanSchemaDrift(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("id,qty,note\n")
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&b, "%d,%d,\n", i, i)
+	}
+	b.WriteString("10,2.5,late\n")
+	p := filepath.Join(t.TempDir(), "drift.csv")
+	if err := os.WriteFile(p, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opt := ReaderOptions{HasHeader: true, Delimiter: ',', SampleRows: 5}
+
+	d := iox.NewDrift(iox.DriftWiden)
+	s, err := ScanSchema(p, opt, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []j.Kind{j.KindInt, j.KindFloat, j.KindString}
+	for i, k := range want {
+		if s.Columns[i].Type != k {
+			t.Fatalf("%s: got %s, want %s", s.Columns[i].Name, s.Columns[i].Type, k)
+		}
+	}
+	// note is empty until row 11: no values is not drift
+	if len(d.Events) != 1 || d.Events[0].Column != "qty" || d.Events[0].Row != 11 {
+		t.Fatalf("events: %v", d.Events)
+	}
+
+	_, err = ScanSchema(p, opt, iox.NewDrift(iox.DriftFail))
+	if err == nil || !strings.Contains(err.Error(), `column "qty" widened from int to float`) {
+		t.Fatalf("fail policy: %v", err)
+	}
+
+	d = iox.NewDrift(iox.DriftUnion)
+	if s, err = ScanSchema(p, opt, d); err != nil {
+		t.Fatal(err)
+	}
+	if s.Columns[1].Type != j.KindInt || len(d.Events) != 1 || d.Events[0].Change != iox.DriftConflict {
+		t.Fatalf("union keeps int and logs a conflict: %v %v", s, d.Events)
+	}
+}
@@ -8,6 +8,7 @@ import (
     "strings"
 
     j "github.com/wdm0006/janitor/pkg/janitor"
+    iox "github.com/wdm0006/janitor/pkg/io/ioutils"
     "fmt"
 )
 
@@ -40,12 +41,13 @@ func (s *StreamReader) Next() (*j.Frame, error) {
 		s.chunkSize = 1024
 	}
 	f := j.NewFrame(s.schema)
+	cols := s.r.inputColumns(s.schema)
 	// drain buffered lines first
     for len(s.r.buf) > 0 && f.Rows() < s.chunkSize {
         rec := s.r.buf[0]
         s.r.buf = s.r.buf[1:]
-        if len(rec) < len(s.schema.Columns) { s.shortRecords++ } else if len(rec) > len(s.schema.Columns) { s.longRecords++ }
-        appendCSVRecord(f, s.schema, rec)
+        if len(rec) < len(cols) { s.shortRecords++ } else if len(rec) > len(cols) { s.longRecords++ }
+        if err := appendCSVRecord(f, cols, rec, s.r); err != nil { return nil, err }
     }
 	for f.Rows() < s.chunkSize {
 		rec, err := s.r.r.Read()
@@ -53,47 +55,40 @@ func (s *StreamReader) Next() (*j.Frame, error) {
 			if f.Rows() == 0 {
 				return nil, io.EOF
 			}
-			return f, nil
+			break
 		}
 		if err != nil {
 			return nil, err
 		}
-        if len(rec) < len(s.schema.Columns) { s.shortRecords++ } else if len(rec) > len(s.schema.Columns) { s.longRecords++ }
-        appendCSVRecord(f, s.schema, rec)
+        if len(rec) < len(cols) { s.shortRecords++ } else if len(rec) > len(cols) { s.longRecords++ }
+        if err := appendCSVRecord(f, cols, rec, s.r); err != nil { return nil, err }
     }
+    f, err := s.r.casts.Finish(f)
+    if err != nil { return nil, err }
+    if err := iox.CheckNullable(f); err != nil { return nil, err }
     return f, nil
 }
 
 func (s *StreamReader) Schema() j.Schema { return s.schema }
 
-func appendCSVRecord(f *j.Frame, schema j.Schema, rec []string) {
+func appendCSVRecord(f *j.Frame, cols []j.ColumnSchema, rec []string, r *Reader) error {
     f.AppendNullRow()
     row := f.Rows() - 1
-    for i, cs := range schema.Columns {
+    for i, cs := range cols {
         if i >= len(rec) { continue }
         val := strings.TrimSpace(rec[i])
         if val == "" {
             continue
         }
-		switch cs.Type {
-		case j.KindFloat:
-			if x, err := strconv.ParseFloat(val, 64); err == nil {
-				_ = f.SetCell(row, cs.Name, x)
-			}
-		case j.KindInt:
-			if x, err := strconv.ParseInt(val, 10, 64); err == nil {
-				_ = f.SetCell(row, cs.Name, x)
-			}
-		case j.KindBool:
-			if x, err := strconv.ParseBool(strings.ToLower(val)); err == nil {
-				_ = f.SetCell(row, cs.Name, x)
-			}
-		default:
-			_ = f.SetCell(row, cs.Name, val)
-		}
+        if err := r.setCell(f, row, i, cs, val); err != nil { return err }
 	}
+	return nil
 }
 
+// CastFailures returns the number of values per column that did not parse as
+// the column's type.
+func (s *StreamReader) CastFailures() map[string]int { return s.r.casts.Counts() }
+
 func (s *StreamReader) Warnings() string {
     parts := []string{}
     if s.shortRecords > 0 { parts = append(parts, fmt.Sprintf("short_records=%d", s.shortRecords)) }
@@ -105,13 +100,14 @@ func (s *StreamReader) Warnings() string {
 // StreamWriter appends frames to a CSV file with a header (written once).
 type StreamWriter struct {
 	w           *csv.Writer
-	file        *os.File
+	file        io.WriteCloser
 	wroteHeader bool
 	schema      j.Schema
 }
 
+// NewStreamWriter writes CSV to path: stdout for "-", gzip for a .gz path.
 func NewStreamWriter(path string, schema j.Schema, opt WriterOptions) (*StreamWriter, error) {
-	f, err := os.Create(path)
+	f, err := iox.CreateMaybeCompressed(path)
 	if err != nil {
 		return nil, err
 	}
@@ -122,7 +118,26 @@ func NewStreamWriter(path string, schema j.Schema, opt WriterOptions) (*StreamWr
 	return &StreamWriter{w: w, file: f, schema: schema}, nil
 }
 
+// Write appends the rows of fr. Columns of the header that fr lacks are
+// written as nulls; a column the header lacks, or of another type, is an
+// error, since the header is already written.
 func (s *StreamWriter) Write(fr *j.Frame) error {
+	cols := make([]j.Column, len(s.schema.Columns))
+	for c, cs := range s.schema.Columns {
+		col, ok := fr.ColumnByName(cs.Name)
+		if !ok {
+			continue
+		}
+		if col.Kind() != cs.Type {
+			return fmt.Errorf("csv: column %q is %s, but was written as %s", cs.Name, col.Kind(), cs.Type)
+		}
+		cols[c] = col
+	}
+	for _, cs := range fr.Schema().Columns {
+		if s.schema.Index(cs.Name) < 0 {
+			return fmt.Errorf("csv: column %q is not in the header written", cs.Name)
+		}
+	}
 	if !s.wroteHeader {
 		hdr := make([]string, len(s.schema.Columns))
 		for i, cs := range s.schema.Columns {
@@ -137,7 +152,10 @@ func (s *StreamWriter) Write(fr *j.Frame) error {
 	for r := 0; r < fr.Rows(); r++ {
 		row := make([]string, len(s.schema.Columns))
 		for c, cs := range s.schema.Columns {
-			col, _ := fr.ColumnByName(cs.Name)
+			col := cols[c]
+			if col == nil {
+				continue
+			}
 			switch cs.Type {
 			case j.KindFloat:
 				if v, ok := col.(*j.FloatColumn).Get(r); ok {
@@ -161,7 +179,7 @@ func (s *StreamWriter) Write(fr *j.Frame) error {
 				}
 			case j.KindTime:
 				if v, ok := col.(*j.TimeColumn).Get(r); ok {
-					row[c] = v.Format("2006-01-02T15:04:05Z07:00")
+					row[c] = iox.FormatTime(v)
 				}
 			}
 		}
@@ -2,8 +2,11 @@ package csvio
 
 import (
 	"io"
+	"os"
 	"path/filepath"
 	"testing"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
 )
 
 func TestStreamReadCSV(t *testing.T) {
@@ -28,3 +31,49 @@ func TestStreamReadCSV(t *testing.T) {
 		t.Fatal("expected rows from stream reader")
 	}
 }
+
+func TestStreamWriterColumnChanges(t *testing.T) {
+	frame := func(cols ...j.ColumnSchema) *j.Frame {
+		f := j.NewFrame(j.Schema{Columns: cols})
+		f.AppendNullRow()
+		for _, cs := range cols {
+			if cs.Type == j.KindInt {
+				_ = f.SetCell(0, cs.Name, int64(1))
+			} else {
+				_ = f.SetCell(0, cs.Name, "x")
+			}
+		}
+		return f
+	}
+	a := j.ColumnSchema{Name: "a", Type: j.KindInt}
+	b := j.ColumnSchema{Name: "b", Type: j.KindString}
+	p := filepath.Join(t.TempDir(), "out.csv")
+	first := frame(a, b)
+	w, err := NewStreamWriter(p, first.Schema(), WriterOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(first); err != nil {
+		t.Fatal(err)
+	}
+	// a later frame without a gets nulls for it
+	if err := w.Write(frame(b)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(frame(a, b, j.ColumnSchema{Name: "c", Type: j.KindInt})); err == nil {
+		t.Fatal("expected an error for a column not in the header")
+	}
+	if err := w.Write(frame(j.ColumnSchema{Name: "a", Type: j.KindString})); err == nil {
+		t.Fatal("expected an error for a retyped column")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a,b\n1,x\n,x\n"; string(got) != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
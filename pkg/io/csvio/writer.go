@@ -66,7 +66,7 @@ func WriteAll(path string, f *j.Frame, opt WriterOptions) error {
 			case j.KindTime:
 				v, ok := col.(*j.TimeColumn).Get(r)
 				if ok {
-					row[c] = v.Format("2006-01-02T15:04:05Z07:00")
+					row[c] = iox.FormatTime(v)
 				}
 			}
 		}
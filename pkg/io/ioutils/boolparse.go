@@ -0,0 +1,46 @@
+package ioutils
+
+import (
+    "fmt"
+    "strings"
+)
+
+// Default boolean literals, matched case-insensitively. "1"/"0" are left out
+// so that integer columns are not read as flags; list them to opt in.
+var (
+    DefaultTrueValues  = []string{"true", "yes", "y", "t"}
+    DefaultFalseValues = []string{"false", "no", "n", "f"}
+)
+
+// BoolParser parses boolean literals for the CSV and JSONL readers.
+type BoolParser struct {
+    values map[string]bool
+}
+
+// NewBoolParser returns a parser for the given literals; either list falls
+// back to its default when empty. A literal may not be both true and false.
+func NewBoolParser(trueValues, falseValues []string) (*BoolParser, error) {
+    if len(trueValues) == 0 { trueValues = DefaultTrueValues }
+    if len(falseValues) == 0 { falseValues = DefaultFalseValues }
+    p := &BoolParser{values: make(map[string]bool, len(trueValues)+len(falseValues))}
+    for _, s := range trueValues { p.values[strings.ToLower(strings.TrimSpace(s))] = true }
+    for _, s := range falseValues {
+        k := strings.ToLower(strings.TrimSpace(s))
+        if p.values[k] { return nil, fmt.Errorf("boolean literal %q is both true and false", s) }
+        p.values[k] = false
+    }
+    return p, nil
+}
+
+// Parse reports the value of s when it is one of the configured literals.
+func (p *BoolParser) Parse(s string) (bool, bool) {
+    v, ok := p.values[strings.ToLower(strings.TrimSpace(s))]
+    return v, ok
+}
+
+// ParseNumber maps a JSON number to a bool when its integer text ("1", "0")
+// is a configured literal.
+func (p *BoolParser) ParseNumber(x float64) (bool, bool) {
+    if x != 0 && x != 1 { return false, false }
+    return p.Parse(fmt.Sprint(int(x)))
+}
@@ -0,0 +1,112 @@
+package ioutils
+
+import (
+    "fmt"
+
+    j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+// CastPolicy decides what a reader does with a value that does not parse as
+// its column's type.
+type CastPolicy string
+
+const (
+    CastNull    CastPolicy = "null"     // leave the cell null (default)
+    CastKeepRaw CastPolicy = "keep_raw" // leave it null and keep the text in <col>__raw
+    CastReject  CastPolicy = "reject"   // drop the row and send it to the reject sink
+    CastFail    CastPolicy = "fail"     // stop reading with an error
+)
+
+// RawSuffix names the shadow column that holds unparsed text under CastKeepRaw.
+const RawSuffix = "__raw"
+
+// CastRule is the reject_rule of rows rejected by CastReject.
+const CastRule = "cast"
+
+// ParseCastPolicy validates a policy name from config; "" means CastNull.
+func ParseCastPolicy(s string) (CastPolicy, error) {
+    switch CastPolicy(s) {
+    case "", CastNull:
+        return CastNull, nil
+    case CastKeepRaw, CastReject, CastFail:
+        return CastPolicy(s), nil
+    }
+    return "", fmt.Errorf("unknown cast error policy %q (want null, keep_raw, reject or fail)", s)
+}
+
+// CastErrors counts cast failures per column for a reader and applies its
+// CastPolicy.
+type CastErrors struct {
+    policy  CastPolicy
+    rejects j.ChunkSink
+    counts  map[string]int
+    rows    int           // input rows in frames already finished
+    pending []j.Violation // failures in the frame being filled, for CastReject
+}
+
+// NewCastErrors returns a tracker for policy. Rows rejected under CastReject
+// are written to rejects, or dropped when it is nil.
+func NewCastErrors(policy CastPolicy, rejects j.ChunkSink) *CastErrors {
+    if policy == "" { policy = CastNull }
+    return &CastErrors{policy: policy, rejects: rejects, counts: map[string]int{}}
+}
+
+// Schema returns s with a nullable <col>__raw string column appended for each
+// non-string column when the policy is CastKeepRaw, and s otherwise.
+func (c *CastErrors) Schema(s j.Schema) j.Schema {
+    if c.policy != CastKeepRaw { return s }
+    out := j.Schema{Columns: append([]j.ColumnSchema(nil), s.Columns...)}
+    for _, cs := range s.Columns {
+        if cs.Type != j.KindString { out.Columns = append(out.Columns, j.ColumnSchema{Name: cs.Name + RawSuffix, Type: j.KindString, Nullable: true}) }
+    }
+    return out
+}
+
+// Fail records that raw in row of f could not be read as column col of kind
+// kind. The cell stays null; what else happens depends on the policy.
+func (c *CastErrors) Fail(f *j.Frame, row int, col string, kind j.Kind, raw string) error {
+    c.counts[col]++
+    reason := fmt.Sprintf("cannot parse %q as %s", raw, kind)
+    switch c.policy {
+    case CastKeepRaw:
+        _ = f.SetCell(row, col+RawSuffix, raw)
+    case CastReject:
+        c.pending = append(c.pending, j.Violation{Row: row, Column: col, Value: raw, Reason: reason})
+    case CastFail:
+        return fmt.Errorf("row %d column %q: %s", c.rows+row+1, col, reason)
+    }
+    return nil
+}
+
+// Finish completes a filled frame: under CastReject the rows that failed are
+// removed and written to the reject sink.
+func (c *CastErrors) Finish(f *j.Frame) (*j.Frame, error) {
+    c.rows += f.Rows()
+    if len(c.pending) == 0 { return f, nil }
+    bad := make([]bool, f.Rows())
+    var rows []int
+    var first []j.Violation
+    for _, v := range c.pending {
+        if !bad[v.Row] {
+            bad[v.Row] = true
+            rows = append(rows, v.Row)
+            first = append(first, v)
+        }
+    }
+    c.pending = c.pending[:0]
+    if c.rejects != nil {
+        if err := c.rejects.Write(j.RejectFrame(f, CastRule, rows, first)); err != nil { return nil, err }
+    }
+    keep := make([]int, 0, f.Rows()-len(rows))
+    for r := range bad {
+        if !bad[r] { keep = append(keep, r) }
+    }
+    return f.Take(keep), nil
+}
+
+// Counts returns the number of cast failures per column.
+func (c *CastErrors) Counts() map[string]int {
+    out := make(map[string]int, len(c.counts))
+    for k, v := range c.counts { out[k] = v }
+    return out
+}
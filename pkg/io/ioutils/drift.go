@@ -0,0 +1,190 @@
+package ioutils
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+    "strings"
+
+    j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+// DriftPolicy decides what happens when data read after the first sample
+// (later rows or later files) does not fit the schema inferred so far.
+type DriftPolicy string
+
+const (
+    DriftIgnore DriftPolicy = "ignore" // keep the first schema; new columns are dropped (default)
+    DriftUnion  DriftPolicy = "union"  // add new columns, null for rows that lack them
+    DriftWiden  DriftPolicy = "widen"  // union, and widen int -> float -> string as values need it
+    DriftFail   DriftPolicy = "fail"   // stop with an error on any drift
+)
+
+// ParseDriftPolicy validates a policy name from config; "" means DriftIgnore.
+func ParseDriftPolicy(s string) (DriftPolicy, error) {
+    switch DriftPolicy(s) {
+    case "", DriftIgnore:
+        return DriftIgnore, nil
+    case DriftUnion, DriftWiden, DriftFail:
+        return DriftPolicy(s), nil
+    }
+    return "", fmt.Errorf("unknown schema drift policy %q (want ignore, union, widen or fail)", s)
+}
+
+// Drift changes recorded by Drift.Merge.
+const (
+    DriftAdded    = "added"    // a column first seen here
+    DriftWidened  = "widened"  // the column's type was widened to fit
+    DriftConflict = "conflict" // the values here need a wider type; the column kept its type
+)
+
+// DriftEvent is one schema change seen while merging.
+type DriftEvent struct {
+    Source string `json:"source"`        // file the change was seen in
+    Row    int    `json:"row,omitempty"` // first row of the sample block, 0 for a whole file
+    Column string `json:"column"`
+    Change string `json:"change"`
+    From   j.Kind `json:"from,omitempty"`
+    To     j.Kind `json:"to,omitempty"`
+}
+
+func (e DriftEvent) String() string {
+    at := e.Source
+    if e.Row > 0 { at = fmt.Sprintf("%s row %d", e.Source, e.Row) }
+    switch e.Change {
+    case DriftAdded:
+        if e.To == j.KindInvalid { return fmt.Sprintf("%s: column %q added", at, e.Column) }
+        return fmt.Sprintf("%s: column %q added (%s)", at, e.Column, e.To)
+    case DriftWidened:
+        return fmt.Sprintf("%s: column %q widened from %s to %s", at, e.Column, e.From, e.To)
+    default:
+        return fmt.Sprintf("%s: column %q has %s values, kept %s", at, e.Column, e.To, e.From)
+    }
+}
+
+// Drift merges the schemas of successive sample blocks and files under a
+// policy and keeps a log of what changed.
+type Drift struct {
+    Policy DriftPolicy
+    Events []DriftEvent
+}
+
+// NewDrift returns a Drift for policy ("" means DriftIgnore).
+func NewDrift(policy DriftPolicy) *Drift {
+    if policy == "" { policy = DriftIgnore }
+    return &Drift{Policy: policy}
+}
+
+// WidenKind returns the narrowest kind that holds values of both a and b:
+// int and float widen to float, any other mix to string.
+func WidenKind(a, b j.Kind) j.Kind {
+    switch {
+    case a == b:
+        return a
+    case a == j.KindInvalid:
+        return b
+    case b == j.KindInvalid:
+        return a
+    case (a == j.KindInt && b == j.KindFloat) || (a == j.KindFloat && b == j.KindInt):
+        return j.KindFloat
+    }
+    return j.KindString
+}
+
+// Merge folds next, the schema seen in source from row on, into base. An
+// empty base is replaced by next. Columns are matched by name; columns of
+// base that next lacks are kept. KindInvalid marks a column without values
+// so far: it takes the type of the first values seen, without an event.
+func (d *Drift) Merge(base, next j.Schema, source string, row int) (j.Schema, error) {
+    if len(base.Columns) == 0 { return next, nil }
+    out := j.Schema{Columns: append([]j.ColumnSchema(nil), base.Columns...)}
+    for _, cs := range next.Columns {
+        i := out.Index(cs.Name)
+        if i < 0 {
+            ev := DriftEvent{Source: source, Row: row, Column: cs.Name, Change: DriftAdded, To: cs.Type}
+            switch d.Policy {
+            case DriftIgnore:
+                continue
+            case DriftFail:
+                return j.Schema{}, fmt.Errorf("schema drift: %s", ev)
+            }
+            d.Events = append(d.Events, ev)
+            out.Columns = append(out.Columns, j.ColumnSchema{Name: cs.Name, Type: cs.Type, Nullable: true})
+            continue
+        }
+        have := out.Columns[i].Type
+        if have == j.KindInvalid {
+            out.Columns[i].Type = cs.Type
+            continue
+        }
+        wide := WidenKind(have, cs.Type)
+        if wide == have { continue }
+        ev := DriftEvent{Source: source, Row: row, Column: cs.Name, Change: DriftWidened, From: have, To: wide}
+        switch d.Policy {
+        case DriftIgnore:
+            continue
+        case DriftFail:
+            return j.Schema{}, fmt.Errorf("schema drift: %s", ev)
+        case DriftWiden:
+            out.Columns[i].Type = wide
+        default:
+            ev.Change, ev.To = DriftConflict, cs.Type
+        }
+        d.Events = append(d.Events, ev)
+    }
+    return out, nil
+}
+
+// FillUnknownKinds returns s with the columns that never held a value
+// (KindInvalid) typed as string.
+func FillUnknownKinds(s j.Schema) j.Schema {
+    out := j.Schema{Columns: append([]j.ColumnSchema(nil), s.Columns...)}
+    for i := range out.Columns {
+        if out.Columns[i].Type == j.KindInvalid { out.Columns[i].Type = j.KindString }
+    }
+    return out
+}
+
+// String returns the log, one event per line.
+func (d *Drift) String() string {
+    lines := make([]string, len(d.Events))
+    for i, e := range d.Events { lines[i] = e.String() }
+    return strings.Join(lines, "\n")
+}
+
+// WriteLog writes the events to path as a JSON array.
+func (d *Drift) WriteLog(path string) error {
+    events := d.Events
+    if events == nil { events = []DriftEvent{} }
+    b, err := json.MarshalIndent(events, "", "  ")
+    if err != nil { return err }
+    return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// Align returns f with exactly the columns of target, in order. Columns f
+// lacks are null; a column of another kind is an error. Nullability is
+// checked as by CheckNullable.
+func Align(f *j.Frame, target j.Schema) (*j.Frame, error) {
+    cols := make([]j.Column, len(target.Columns))
+    var missing j.Schema
+    for i, cs := range target.Columns {
+        c, ok := f.ColumnByName(cs.Name)
+        if !ok {
+            missing.Columns = append(missing.Columns, cs)
+            continue
+        }
+        if c.Kind() != cs.Type { return nil, fmt.Errorf("column %q is %s, the merged schema has %s", cs.Name, c.Kind(), cs.Type) }
+        cols[i] = c
+    }
+    if len(missing.Columns) > 0 {
+        nulls := j.NewFrame(missing)
+        for r := 0; r < f.Rows(); r++ { nulls.AppendNullRow() }
+        for i, cs := range target.Columns {
+            if cols[i] == nil { cols[i], _ = nulls.ColumnByName(cs.Name) }
+        }
+    }
+    out, err := j.NewFrameFromColumns(target, cols)
+    if err != nil { return nil, err }
+    if err := CheckNullable(out); err != nil { return nil, err }
+    return out, nil
+}
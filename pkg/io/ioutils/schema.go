@@ -0,0 +1,154 @@
+package ioutils
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+
+    j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+// ColumnOverride pins how a reader types one input column.
+type ColumnOverride struct {
+    Name     string `json:"name"` // column name in the input
+    Type     j.Kind `json:"type"`
+    Nullable *bool  `json:"nullable,omitempty"` // default true
+    Format   string `json:"format,omitempty"`   // time layout, unix or unix_ms for this column
+    Rename   string `json:"rename,omitempty"`   // column name in the Frame
+}
+
+// SchemaOverride lists the columns whose type is given rather than inferred;
+// inference still decides every other column. A serialized j.Schema is a
+// valid SchemaOverride.
+type SchemaOverride struct {
+    Columns []ColumnOverride `json:"columns"`
+}
+
+// ReadSchemaOverride loads a SchemaOverride from a JSON file.
+func ReadSchemaOverride(path string) (SchemaOverride, error) {
+    b, err := os.ReadFile(path)
+    if err != nil { return SchemaOverride{}, err }
+    var o SchemaOverride
+    if err := json.Unmarshal(b, &o); err != nil { return SchemaOverride{}, fmt.Errorf("schema %s: %w", path, err) }
+    return o, nil
+}
+
+// Names returns the input names of the listed columns.
+func (o SchemaOverride) Names() []string {
+    out := make([]string, len(o.Columns))
+    for i, c := range o.Columns { out[i] = c.Name }
+    return out
+}
+
+// Apply returns s with the listed columns' type, nullability and name
+// replaced, plus the time parser to use for each column: base, or one with
+// the column's own Format. Listing a column s does not have is an error.
+func (o SchemaOverride) Apply(s j.Schema, base *TimeParser) (j.Schema, []*TimeParser, error) {
+    out := j.Schema{Columns: append([]j.ColumnSchema(nil), s.Columns...)}
+    parsers := make([]*TimeParser, len(out.Columns))
+    for i := range parsers { parsers[i] = base }
+    seen := map[string]bool{}
+    for _, c := range o.Columns {
+        if c.Name == "" { return j.Schema{}, nil, fmt.Errorf("schema: column without a name") }
+        if seen[c.Name] { return j.Schema{}, nil, fmt.Errorf("schema: column %q listed twice", c.Name) }
+        seen[c.Name] = true
+        i := s.Index(c.Name)
+        if i < 0 { return j.Schema{}, nil, fmt.Errorf("schema: column %q not found in input", c.Name) }
+        if c.Type != j.KindInvalid { out.Columns[i].Type = c.Type }
+        if c.Nullable != nil { out.Columns[i].Nullable = *c.Nullable }
+        if c.Rename != "" { out.Columns[i].Name = c.Rename }
+        if c.Format != "" {
+            if out.Columns[i].Type != j.KindTime { return j.Schema{}, nil, fmt.Errorf("schema: column %q has a format but is not a time column", c.Name) }
+            parsers[i] = base.WithFormats([]string{c.Format})
+        }
+    }
+    names := map[string]bool{}
+    for _, cs := range out.Columns {
+        if names[cs.Name] { return j.Schema{}, nil, fmt.Errorf("schema: duplicate column name %q after rename", cs.Name) }
+        names[cs.Name] = true
+    }
+    return out, parsers, nil
+}
+
+// CheckNullable returns an error when a column the schema marks as not
+// nullable holds a null.
+func CheckNullable(f *j.Frame) error {
+    for _, cs := range f.Schema().Columns {
+        if cs.Nullable { continue }
+        col, ok := f.ColumnByName(cs.Name)
+        if !ok { continue }
+        n := 0
+        for i := 0; i < col.Len(); i++ {
+            if col.IsNull(i) { n++ }
+        }
+        if n > 0 { return fmt.Errorf("column %q is not nullable but has %d null value(s)", cs.Name, n) }
+    }
+    return nil
+}
+
+// Convert converts a decoded value (bool, int64, float64, string or
+// time.Time) to kind to, reporting false when it cannot be represented.
+func Convert(v any, to j.Kind, times *TimeParser, bools *BoolParser) (any, bool) {
+    switch to {
+    case j.KindString:
+        switch t := v.(type) {
+        case string:
+            return t, true
+        case int64:
+            return strconv.FormatInt(t, 10), true
+        case float64:
+            return strconv.FormatFloat(t, 'g', -1, 64), true
+        case bool:
+            return strconv.FormatBool(t), true
+        case time.Time:
+            return FormatTime(t), true
+        }
+    case j.KindInt:
+        switch t := v.(type) {
+        case int64:
+            return t, true
+        case float64:
+            if t == float64(int64(t)) { return int64(t), true }
+        case string:
+            if x, err := strconv.ParseInt(strings.TrimSpace(t), 10, 64); err == nil { return x, true }
+        case bool:
+            if t { return int64(1), true }
+            return int64(0), true
+        }
+    case j.KindFloat:
+        switch t := v.(type) {
+        case float64:
+            return t, true
+        case int64:
+            return float64(t), true
+        case string:
+            if x, err := strconv.ParseFloat(strings.TrimSpace(t), 64); err == nil { return x, true }
+        }
+    case j.KindBool:
+        switch t := v.(type) {
+        case bool:
+            return t, true
+        case int64:
+            return bools.ParseNumber(float64(t))
+        case float64:
+            return bools.ParseNumber(t)
+        case string:
+            return bools.Parse(t)
+        }
+    case j.KindTime:
+        switch t := v.(type) {
+        case time.Time:
+            return t, true
+        case int64:
+            return times.ParseNumber(float64(t))
+        case float64:
+            return times.ParseNumber(t)
+        case string:
+            return times.Parse(strings.TrimSpace(t))
+        }
+    }
+    return nil, false
+}
@@ -0,0 +1,127 @@
+package ioutils
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "time"
+)
+
+// Pseudo-layouts for integer epoch timestamps, usable in time format lists.
+const (
+    EpochSeconds = "unix"
+    EpochMillis  = "unix_ms"
+)
+
+// DefaultTimeFormats are the layouts tried when no formats are configured:
+// RFC3339 and the common ISO date and "YYYY-MM-DD HH:MM:SS" variants. Epoch
+// timestamps are opt-in because integer columns would otherwise be ambiguous.
+var DefaultTimeFormats = []string{
+    time.RFC3339Nano,
+    "2006-01-02T15:04:05.999999999",
+    "2006-01-02 15:04:05.999999999Z07:00",
+    "2006-01-02 15:04:05.999999999",
+    "2006-01-02T15:04",
+    "2006-01-02 15:04",
+    "2006-01-02",
+}
+
+// TimeParser parses time values for the CSV and JSONL readers.
+type TimeParser struct {
+    layouts []string
+    loc     *time.Location
+    epochS  bool
+    epochMs bool
+    custom  bool // formats were configured, so any string may be a time
+    last    int  // index of the last layout that matched, tried first
+}
+
+// NewTimeParser returns a parser trying formats in order. Formats are Go
+// layouts or EpochSeconds/EpochMillis; DefaultTimeFormats are used when no
+// layout is given. zone is an IANA name or "Local" used for values without an
+// offset; "" means UTC.
+func NewTimeParser(formats []string, zone string) (*TimeParser, error) {
+    loc := time.UTC
+    if zone != "" {
+        l, err := time.LoadLocation(zone)
+        if err != nil { return nil, fmt.Errorf("time zone %q: %w", zone, err) }
+        loc = l
+    }
+    p := &TimeParser{loc: loc}
+    for _, f := range formats {
+        switch f {
+        case EpochSeconds:
+            p.epochS = true
+        case EpochMillis:
+            p.epochMs = true
+        default:
+            p.layouts = append(p.layouts, f)
+        }
+    }
+    p.custom = len(p.layouts) > 0
+    if !p.custom { p.layouts = DefaultTimeFormats }
+    return p, nil
+}
+
+// WithFormats returns a parser for formats in p's time zone.
+func (p *TimeParser) WithFormats(formats []string) *TimeParser {
+    q, _ := NewTimeParser(formats, "")
+    if p != nil { q.loc = p.loc }
+    return q
+}
+
+// Parse parses s with the configured layouts, or as an epoch integer when an
+// epoch format is enabled.
+func (p *TimeParser) Parse(s string) (time.Time, bool) {
+    if len(p.layouts) > 0 {
+        if t, err := time.ParseInLocation(p.layouts[p.last], s, p.loc); err == nil { return t, true }
+        for i, l := range p.layouts {
+            if i == p.last { continue }
+            if t, err := time.ParseInLocation(l, s, p.loc); err == nil {
+                p.last = i
+                return t, true
+            }
+        }
+    }
+    if p.epochS || p.epochMs {
+        if n, err := strconv.ParseInt(s, 10, 64); err == nil { return p.fromEpoch(n), true }
+    }
+    return time.Time{}, false
+}
+
+// ParseNumber converts a JSON number to a time when an epoch format is enabled.
+func (p *TimeParser) ParseNumber(x float64) (time.Time, bool) {
+    if !(p.epochS || p.epochMs) || x != math.Trunc(x) || math.Abs(x) > 1<<62 { return time.Time{}, false }
+    return p.fromEpoch(int64(x)), true
+}
+
+// fromEpoch reads n as milliseconds when only EpochMillis is enabled, or when
+// both are and n is too large to be seconds in this millennium.
+func (p *TimeParser) fromEpoch(n int64) time.Time {
+    if p.epochMs && (!p.epochS || n >= 1e11 || n <= -1e11) { return time.UnixMilli(n).In(p.loc) }
+    return time.Unix(n, 0).In(p.loc)
+}
+
+// Looks reports whether s should count as a time during inference. Epoch
+// integers only count in a plausible range (years 2001-2286), so that small
+// integer columns stay integers.
+func (p *TimeParser) Looks(s string) bool {
+    if n, err := strconv.ParseInt(s, 10, 64); err == nil { return p.plausibleEpoch(float64(n)) }
+    // the default layouts all start with a dashed date; skip other strings cheaply
+    if !p.custom && (len(s) < 10 || s[4] != '-') { return false }
+    _, ok := p.Parse(s)
+    return ok
+}
+
+// LooksNumber is Looks for a JSON number.
+func (p *TimeParser) LooksNumber(x float64) bool {
+    return x == math.Trunc(x) && p.plausibleEpoch(x)
+}
+
+func (p *TimeParser) plausibleEpoch(x float64) bool {
+    x = math.Abs(x)
+    return (p.epochS && x >= 1e9 && x < 1e10) || (p.epochMs && x >= 1e12 && x < 1e13)
+}
+
+// FormatTime is the one format the CSV and JSONL writers use for time values.
+func FormatTime(t time.Time) string { return t.Format(time.RFC3339Nano) }
@@ -0,0 +1,291 @@
+package jsonlio
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ArrayMode says how the reader handles JSON array values.
+type ArrayMode string
+
+const (
+	ArrayJSON    ArrayMode = "json"    // keep the array as JSON text (default)
+	ArrayJoin    ArrayMode = "join"    // join the elements' text with ArraySeparator
+	ArrayExplode ArrayMode = "explode" // emit one row per element
+)
+
+// FlattenSeparator joins the keys of nested objects in flattened column
+// names, e.g. "user.address.city".
+const FlattenSeparator = "."
+
+// ParseArrayMode validates an array mode name from config; "" means ArrayJSON.
+func ParseArrayMode(s string) (ArrayMode, error) {
+	switch ArrayMode(s) {
+	case "", ArrayJSON:
+		return ArrayJSON, nil
+	case ArrayJoin, ArrayExplode:
+		return ArrayMode(s), nil
+	}
+	return "", fmt.Errorf("unknown array mode %q (want json, join or explode)", s)
+}
+
+// keyTree records the order in which object keys first appear, per nesting
+// path. The elements of an array share the tree of the array's key.
+type keyTree struct {
+	keys []string
+	kids map[string]*keyTree
+}
+
+func (t *keyTree) child(k string) *keyTree {
+	if t.kids == nil {
+		t.kids = map[string]*keyTree{}
+	}
+	c, ok := t.kids[k]
+	if !ok {
+		c = &keyTree{}
+		t.kids[k] = c
+		t.keys = append(t.keys, k)
+	}
+	return c
+}
+
+// kid is child without recording anything; nil when t is nil.
+func (t *keyTree) kid(k string) *keyTree {
+	if t == nil {
+		return nil
+	}
+	return t.kids[k]
+}
+
+// order returns the keys of m, in document order when t is known.
+func (t *keyTree) order(m map[string]any) []string {
+	out := make([]string, 0, len(m))
+	if t != nil {
+		for _, k := range t.keys {
+			if _, ok := m[k]; ok {
+				out = append(out, k)
+			}
+		}
+		if len(out) == len(m) {
+			return out
+		}
+		out = out[:0]
+	}
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
+
+// readTree consumes one JSON value from dec and adds the keys of the objects
+// in it to t.
+func readTree(dec *json.Decoder, t *keyTree) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	d, ok := tok.(json.Delim)
+	if !ok {
+		return nil
+	}
+	switch d {
+	case '{':
+		for dec.More() {
+			k, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if err := readTree(dec, t.child(k.(string))); err != nil {
+				return err
+			}
+		}
+	case '[':
+		for dec.More() {
+			if err := readTree(dec, t); err != nil {
+				return err
+			}
+		}
+	}
+	_, err = dec.Token() // closing delimiter
+	return err
+}
+
+// flattener turns a decoded record into the rows the reader sees, following
+// ReaderOptions.FlattenDepth and Arrays.
+type flattener struct {
+	depth   int       // object levels to expand; negative expands all
+	arrays  ArrayMode
+	joinSep string
+}
+
+func newFlattener(opt ReaderOptions) (flattener, error) {
+	mode, err := ParseArrayMode(string(opt.Arrays))
+	if err != nil {
+		return flattener{}, err
+	}
+	sep := opt.ArraySeparator
+	if sep == "" {
+		sep = ","
+	}
+	return flattener{depth: opt.FlattenDepth, arrays: mode, joinSep: sep}, nil
+}
+
+// explodedArray is an array value waiting to be spread over rows.
+type explodedArray struct {
+	name  string
+	elems []any
+	tree  *keyTree
+	depth int
+}
+
+// records returns the rows for record m. When t is not nil it is m's key
+// tree and keys lists the row keys in document order.
+func (fl flattener) records(m map[string]any, t *keyTree) (rows []map[string]any, keys []string) {
+	if fl.depth == 0 && fl.arrays == ArrayJSON {
+		if t != nil {
+			keys = t.keys
+		}
+		return []map[string]any{m}, keys
+	}
+	var order *[]string
+	if t != nil {
+		order = &keys
+	}
+	out := make(map[string]any, len(m))
+	var arrays []explodedArray
+	for _, k := range t.order(m) {
+		fl.put(out, order, &arrays, k, m[k], t.kid(k), fl.depth)
+	}
+	if len(arrays) == 0 {
+		return []map[string]any{out}, keys
+	}
+	return fl.explode(out, arrays), keys
+}
+
+// put stores v under name in out, expanding objects while depth allows.
+// Arrays to explode are queued in arrays; nil arrays keeps them as JSON.
+func (fl flattener) put(out map[string]any, order *[]string, arrays *[]explodedArray, name string, v any, t *keyTree, depth int) {
+	switch x := v.(type) {
+	case map[string]any:
+		if depth != 0 {
+			for _, k := range t.order(x) {
+				fl.put(out, order, arrays, name+FlattenSeparator+k, x[k], t.kid(k), depth-1)
+			}
+			return
+		}
+	case []any:
+		switch {
+		case fl.arrays == ArrayJoin:
+			v = fl.join(x)
+		case fl.arrays == ArrayExplode && arrays != nil:
+			*arrays = append(*arrays, explodedArray{name: name, elems: x, tree: t, depth: depth})
+			if order != nil {
+				for _, e := range x {
+					fl.put(map[string]any{}, order, nil, name, e, t, depth)
+				}
+			}
+			return
+		}
+	}
+	out[name] = v
+	if order != nil {
+		*order = append(*order, name)
+	}
+}
+
+// explode spreads the queued arrays over rows: row i holds element i of
+// every array (null past an array's end) and the other values of base.
+func (fl flattener) explode(base map[string]any, arrays []explodedArray) []map[string]any {
+	n := 1
+	for _, a := range arrays {
+		if len(a.elems) > n {
+			n = len(a.elems)
+		}
+	}
+	rows := make([]map[string]any, n)
+	for i := range rows {
+		row := make(map[string]any, len(base)+len(arrays))
+		for k, v := range base {
+			row[k] = v
+		}
+		for _, a := range arrays {
+			if i < len(a.elems) {
+				fl.put(row, nil, nil, a.name, a.elems[i], a.tree, a.depth)
+			}
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+// join renders an array as its non-null elements' text separated by
+// joinSep; an empty array is null.
+func (fl flattener) join(x []any) any {
+	if len(x) == 0 {
+		return nil
+	}
+	parts := make([]string, 0, len(x))
+	for _, e := range x {
+		if e != nil {
+			parts = append(parts, rawText(e))
+		}
+	}
+	return strings.Join(parts, fl.joinSep)
+}
+
+// unflatten nests the dotted keys of a flat row back into objects, keeping
+// the order of the flat keys. Shorter names are placed first; a key whose
+// prefix already holds a non-object value is kept flat.
+func unflatten(flat *object) *object {
+	names := append([]string(nil), flat.keys...)
+	sort.SliceStable(names, func(a, b int) bool {
+		return strings.Count(names[a], FlattenSeparator) < strings.Count(names[b], FlattenSeparator)
+	})
+	// decide, shortest names first, which keys cannot be nested
+	keepFlat := map[string]bool{}
+	tree := map[string]any{}
+	for _, name := range names {
+		parts := strings.Split(name, FlattenSeparator)
+		m := tree
+		for _, p := range parts[:len(parts)-1] {
+			next, ok := m[p].(map[string]any)
+			if !ok {
+				if _, taken := m[p]; taken {
+					m = nil
+					break
+				}
+				next = map[string]any{}
+				m[p] = next
+			}
+			m = next
+		}
+		last := parts[len(parts)-1]
+		if _, taken := m[last]; m == nil || taken {
+			keepFlat[name] = true
+			continue
+		}
+		m[last] = true
+	}
+	out := newObject()
+	for _, name := range flat.keys {
+		v := flat.vals[name]
+		if keepFlat[name] {
+			out.set(name, v)
+			continue
+		}
+		parts := strings.Split(name, FlattenSeparator)
+		o := out
+		for _, p := range parts[:len(parts)-1] {
+			next, ok := o.vals[p].(*object)
+			if !ok {
+				next = newObject()
+				o.set(p, next)
+			}
+			o = next
+		}
+		o.set(parts[len(parts)-1], v)
+	}
+	return out
+}
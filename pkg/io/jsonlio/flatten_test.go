@@ -0,0 +1,142 @@
+package jsonlio
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+const nestedRecords = `{"id":1,"user":{"name":"ann","address":{"city":"Oslo","zip":"N1 9GU"}},"tags":["a","b"],"items":[{"sku":"x","qty":2},{"sku":"y","qty":1}]}
+{"id":2,"user":{"name":"bob"},"tags":[],"items":[]}
+`
+
+func schemaNames(s j.Schema) string {
+	var out []string
+	for _, cs := range s.Columns {
+		out = append(out, cs.Name)
+	}
+	return strings.Join(out, ",")
+}
+
+func readNested(t *testing.T, opt ReaderOptions) *j.Frame {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "nested.jsonl")
+	if err := os.WriteFile(p, []byte(nestedRecords), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, f, err := Open(p, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	schema, err := r.InferSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fr, err := r.ReadAll(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fr
+}
+
+func stringAt(t *testing.T, f *j.Frame, name string, row int) (string, bool) {
+	t.Helper()
+	col, ok := f.ColumnByName(name)
+	if !ok {
+		t.Fatalf("no column %q in %s", name, schemaNames(f.Schema()))
+	}
+	return col.(*j.StringColumn).Get(row)
+}
+
+func TestFlattenDepth(t *testing.T) {
+	fr := readNested(t, ReaderOptions{FlattenDepth: 1})
+	if got := schemaNames(fr.Schema()); got != "id,user.name,user.address,tags,items" {
+		t.Fatalf("depth 1 columns: %s", got)
+	}
+	if v, _ := stringAt(t, fr, "user.address", 0); v != `{"city":"Oslo","zip":"N1 9GU"}` {
+		t.Fatalf("user.address[0] = %s", v)
+	}
+
+	fr = readNested(t, ReaderOptions{FlattenDepth: -1, Arrays: ArrayJoin, ArraySeparator: "|"})
+	if got := schemaNames(fr.Schema()); got != "id,user.name,user.address.city,user.address.zip,tags,items" {
+		t.Fatalf("full depth columns: %s", got)
+	}
+	if v, _ := stringAt(t, fr, "user.address.city", 0); v != "Oslo" {
+		t.Fatalf("user.address.city[0] = %s", v)
+	}
+	if v, _ := stringAt(t, fr, "tags", 0); v != "a|b" {
+		t.Fatalf("tags[0] = %s", v)
+	}
+	if _, ok := stringAt(t, fr, "tags", 1); ok {
+		t.Fatal("an empty array should join to null")
+	}
+}
+
+func TestExplodeArrays(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "nested.jsonl")
+	if err := os.WriteFile(p, []byte(nestedRecords), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, f, err := NewStreamReader(p, ReaderOptions{FlattenDepth: -1, Arrays: ArrayExplode}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	if got := schemaNames(s.Schema()); got != "id,user.name,user.address.city,user.address.zip,tags,items.sku,items.qty" {
+		t.Fatalf("columns: %s", got)
+	}
+	var ids []int64
+	var skus []string
+	for {
+		fr, err := s.Next()
+		if err != nil {
+			break
+		}
+		if fr.Rows() != 1 {
+			t.Fatalf("chunk of %d rows, want 1", fr.Rows())
+		}
+		col, _ := fr.ColumnByName("id")
+		id, _ := col.(*j.IntColumn).Get(0)
+		ids = append(ids, id)
+		sku, ok := stringAt(t, fr, "items.sku", 0)
+		if !ok {
+			sku = "-"
+		}
+		skus = append(skus, sku)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[1] != 1 || ids[2] != 2 {
+		t.Fatalf("ids = %v", ids)
+	}
+	if strings.Join(skus, ",") != "x,y,-" {
+		t.Fatalf("skus = %v", skus)
+	}
+}
+
+func TestUnflattenWriter(t *testing.T) {
+	fr := readNested(t, ReaderOptions{FlattenDepth: -1})
+	out := filepath.Join(t.TempDir(), "out.jsonl")
+	w, err := NewStreamWriter(out, WriterOptions{Unflatten: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(fr); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := strings.SplitN(string(b), "\n", 2)[0]
+	// keys keep the order of the input record
+	want := `{"id":1,"user":{"name":"ann","address":{"city":"Oslo","zip":"N1 9GU"}},"tags":"[\"a\",\"b\"]","items":"[{\"qty\":2,\"sku\":\"x\"},{\"qty\":1,\"sku\":\"y\"}]"}`
+	if first != want {
+		t.Fatalf("unflattened row:\n got %s\nwant %s", first, want)
+	}
+}
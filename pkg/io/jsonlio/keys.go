@@ -0,0 +1,71 @@
+package jsonlio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// decodeSample decodes up to max records from dec and returns the rows fl
+// makes of them, with their keys in first-seen order: the keys of the first
+// record as written, then each key that first appears in a later record.
+func decodeSample(dec *json.Decoder, max int, fl flattener) ([]map[string]any, []string, error) {
+	var sample []map[string]any
+	var order []string
+	seen := map[string]bool{}
+	for n := 0; n < max; n++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, nil, err
+		}
+		var m map[string]any
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, nil, err
+		}
+		t := &keyTree{}
+		if err := readTree(json.NewDecoder(bytes.NewReader(raw)), t); err != nil {
+			return nil, nil, err
+		}
+		rows, keys := fl.records(m, t)
+		for _, k := range keys {
+			if !seen[k] {
+				seen[k] = true
+				order = append(order, k)
+			}
+		}
+		sample = append(sample, rows...)
+	}
+	return sample, order, nil
+}
+
+// columnKeys returns the input keys to read, in schema order: opt.Columns
+// when set, otherwise the sampled keys in first-seen order followed by
+// override keys missing from the sample.
+func columnKeys(order []string, opt ReaderOptions) ([]string, error) {
+	if len(opt.Columns) > 0 {
+		seen := map[string]bool{}
+		for _, k := range opt.Columns {
+			if seen[k] {
+				return nil, fmt.Errorf("columns: key %q listed twice", k)
+			}
+			seen[k] = true
+		}
+		return append([]string(nil), opt.Columns...), nil
+	}
+	keys := append([]string(nil), order...)
+	seen := map[string]bool{}
+	for _, k := range keys {
+		seen[k] = true
+	}
+	for _, k := range opt.Schema.Names() {
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
@@ -4,6 +4,7 @@ import (
     "bufio"
     "encoding/json"
     "io"
+    "math"
     "os"
     "regexp"
     "strconv"
@@ -15,26 +16,75 @@ import (
 
 type ReaderOptions struct {
 	SampleRows int
+	// TimeFormats are Go layouts (or "unix"/"unix_ms") for time columns;
+	// empty uses iox.DefaultTimeFormats.
+	TimeFormats []string
+	// TimeZone applies to times without an offset; default UTC.
+	TimeZone string
+	// TrueValues and FalseValues are the case-insensitive boolean literals
+	// accepted in strings (and as 1/0 numbers when listed); empty uses
+	// iox.DefaultTrueValues/DefaultFalseValues.
+	TrueValues  []string
+	FalseValues []string
+	// Schema pins the type, nullability, time format or name of listed
+	// keys; the rest are inferred. Listed keys are read even when they are
+	// missing from the sampled records.
+	Schema iox.SchemaOverride
+	// OnCastError is what happens to a value that does not parse as its
+	// column's type (default iox.CastNull). Rows rejected by iox.CastReject
+	// go to Rejects, or are dropped when it is nil.
+	OnCastError iox.CastPolicy
+	Rejects     j.ChunkSink
+	// Columns lists the keys to read, in output order; other keys are
+	// skipped and listed keys missing from the data read as null. Empty reads
+	// every key in the order it is first seen.
+	Columns []string
+	// FlattenDepth expands nested objects into dotted columns
+	// ("user.address.city") up to that many levels; deeper objects stay JSON
+	// text. 0 keeps every object as JSON text and a negative depth expands
+	// all levels. Columns and Schema name the flattened keys.
+	FlattenDepth int
+	// Arrays is how array values are read: ArrayJSON (default) keeps them
+	// as JSON text, ArrayJoin joins the elements with ArraySeparator
+	// (default ",") and ArrayExplode emits one row per element, with
+	// object elements flattened under the array's key. Several arrays in a
+	// record are exploded together, element by element.
+	Arrays         ArrayMode
+	ArraySeparator string
 }
 
 type Reader struct {
 	r    *bufio.Reader
+	dec  *json.Decoder
 	opt  ReaderOptions
 	buf  []map[string]any
-	keys []string
+	keys []string // input key of each schema column
+	times *iox.TimeParser
+	bools *iox.BoolParser
+	colTimes []*iox.TimeParser // per-column time parsers from opt.Schema
+	casts    *iox.CastErrors
+	fl       flattener
 }
 
 func Open(path string, opt ReaderOptions) (*Reader, *os.File, error) {
     var f *os.File
     var err error
-    if path != "-" {
+    if path != "-" && path != "" {
         f, err = os.Open(path)
         if err != nil { return nil, nil, err }
     }
+    times, err := iox.NewTimeParser(opt.TimeFormats, opt.TimeZone)
+    if err != nil { _ = f.Close(); return nil, nil, err }
+    bools, err := iox.NewBoolParser(opt.TrueValues, opt.FalseValues)
+    if err != nil { _ = f.Close(); return nil, nil, err }
+    policy, err := iox.ParseCastPolicy(string(opt.OnCastError))
+    if err != nil { _ = f.Close(); return nil, nil, err }
+    fl, err := newFlattener(opt)
+    if err != nil { _ = f.Close(); return nil, nil, err }
     rc, err := iox.OpenMaybeCompressed(path)
     if err != nil { _ = f.Close(); return nil, nil, err }
     rd := bufio.NewReader(rc)
-    return &Reader{r: rd, opt: opt}, f, nil
+    return &Reader{r: rd, dec: json.NewDecoder(rd), opt: opt, times: times, bools: bools, casts: iox.NewCastErrors(policy, opt.Rejects), fl: fl}, f, nil
 }
 
 func (r *Reader) InferSchema() (j.Schema, error) {
@@ -42,33 +92,34 @@ func (r *Reader) InferSchema() (j.Schema, error) {
 	if max <= 0 {
 		max = 100
 	}
-	dec := json.NewDecoder(r.r)
-	var sample []map[string]any
-	keysSet := map[string]struct{}{}
-	for len(sample) < max {
-		var m map[string]any
-		if err := dec.Decode(&m); err != nil {
-			if err == io.EOF {
-				break
-			}
-			return j.Schema{}, err
-		}
-		sample = append(sample, m)
-		for k := range m {
-			keysSet[k] = struct{}{}
-		}
+	sample, order, err := decodeSample(r.dec, max, r.fl)
+	if err != nil {
+		return j.Schema{}, err
 	}
 	r.buf = append(r.buf, sample...)
-	r.keys = make([]string, 0, len(keysSet))
-	for k := range keysSet {
-		r.keys = append(r.keys, k)
+	return r.buildSchema(sample, order)
+}
+
+// buildSchema infers a schema from sampled records whose keys were first
+// seen in order, applies opt.Columns and opt.Schema and records the input key
+// and time parser of each column.
+func (r *Reader) buildSchema(sample []map[string]any, order []string) (j.Schema, error) {
+	keys, err := columnKeys(order, r.opt)
+	if err != nil {
+		return j.Schema{}, err
 	}
-	kinds := inferKinds(sample, r.keys)
+	r.keys = keys
+	kinds := inferKinds(sample, r.keys, r.times, r.bools)
 	schema := j.Schema{Columns: make([]j.ColumnSchema, len(r.keys))}
 	for i, k := range r.keys {
 		schema.Columns[i] = j.ColumnSchema{Name: k, Type: kinds[i], Nullable: true}
 	}
-	return schema, nil
+	schema, colTimes, err := r.opt.Schema.Apply(schema, r.times)
+	if err != nil {
+		return j.Schema{}, err
+	}
+	r.colTimes = colTimes
+	return r.casts.Schema(schema), nil
 }
 
 func (r *Reader) ReadAll(schema j.Schema) (*j.Frame, error) {
@@ -79,79 +130,145 @@ func (r *Reader) ReadAll(schema j.Schema) (*j.Frame, error) {
 		r.buf = r.buf[1:]
 		f.AppendNullRow()
 		row := f.Rows() - 1
-		r.setRowFromMap(f, row, m)
+		if err := r.setRowFromMap(f, row, m); err != nil {
+			return nil, err
+		}
 	}
 	// continue decoding
-	dec := json.NewDecoder(r.r)
 	for {
 		var m map[string]any
-		if err := dec.Decode(&m); err != nil {
+		if err := r.dec.Decode(&m); err != nil {
 			if err == io.EOF {
 				break
 			}
 			return nil, err
 		}
-		f.AppendNullRow()
-		row := f.Rows() - 1
-		r.setRowFromMap(f, row, m)
+		rows, _ := r.fl.records(m, nil)
+		for _, m := range rows {
+			f.AppendNullRow()
+			if err := r.setRowFromMap(f, f.Rows()-1, m); err != nil {
+				return nil, err
+			}
+		}
+	}
+	f, err := r.casts.Finish(f)
+	if err != nil {
+		return nil, err
+	}
+	if err := iox.CheckNullable(f); err != nil {
+		return nil, err
 	}
 	return f, nil
 }
 
-func (r *Reader) setRowFromMap(f *j.Frame, row int, m map[string]any) {
-	for _, cs := range f.Schema().Columns {
-		if v, ok := m[cs.Name]; ok {
-			switch cs.Type {
-			case j.KindFloat:
-				switch t := v.(type) {
-				case float64:
-					_ = f.SetCell(row, cs.Name, t)
-				case string:
-					if s := strings.TrimSpace(t); s != "" {
-						if x, err := strconv.ParseFloat(s, 64); err == nil {
-							_ = f.SetCell(row, cs.Name, x)
-						}
-					}
-				}
-			case j.KindInt:
-				switch t := v.(type) {
-				case float64:
-					_ = f.SetCell(row, cs.Name, int64(t))
-				case string:
-					if s := strings.TrimSpace(t); s != "" {
-						if x, err := strconv.ParseInt(s, 10, 64); err == nil {
-							_ = f.SetCell(row, cs.Name, x)
-						}
-					}
-				}
-			case j.KindBool:
-				switch t := v.(type) {
-				case bool:
-					_ = f.SetCell(row, cs.Name, t)
-				case string:
-					lv := strings.ToLower(strings.TrimSpace(t))
-					if x, err := strconv.ParseBool(lv); err == nil {
-						_ = f.SetCell(row, cs.Name, x)
-					}
-				}
-			default:
-				switch t := v.(type) {
-				case string:
-					_ = f.SetCell(row, cs.Name, t)
-				default:
-					// fallback to JSON encoding
-					b, _ := json.Marshal(t)
-					_ = f.SetCell(row, cs.Name, string(b))
-				}
+func (r *Reader) setRowFromMap(f *j.Frame, row int, m map[string]any) error {
+	cols := f.Schema().Columns
+	if r.keys != nil && len(r.keys) < len(cols) {
+		cols = cols[:len(r.keys)] // leave out <col>__raw columns
+	}
+	for i, cs := range cols {
+		key, times := cs.Name, r.times
+		if i < len(r.keys) {
+			key = r.keys[i]
+		}
+		if i < len(r.colTimes) {
+			times = r.colTimes[i]
+		}
+		v, ok := m[key]
+		if !ok || v == nil {
+			continue
+		}
+		if t, isStr := v.(string); isStr && cs.Type != j.KindString && strings.TrimSpace(t) == "" {
+			continue
+		}
+		x, ok := r.convert(v, cs.Type, times)
+		if !ok {
+			if err := r.casts.Fail(f, row, cs.Name, cs.Type, rawText(v)); err != nil {
+				return err
+			}
+			continue
+		}
+		_ = f.SetCell(row, cs.Name, x)
+	}
+	return nil
+}
+
+// convert reads JSON value v as kind, reporting false when it does not parse.
+func (r *Reader) convert(v any, kind j.Kind, times *iox.TimeParser) (any, bool) {
+	switch kind {
+	case j.KindFloat:
+		switch t := v.(type) {
+		case float64:
+			return t, true
+		case string:
+			if p, err := strconv.ParseFloat(strings.TrimSpace(t), 64); err == nil {
+				return p, true
 			}
 		}
+	case j.KindInt:
+		switch t := v.(type) {
+		case float64:
+			return int64(t), t == math.Trunc(t)
+		case string:
+			if p, err := strconv.ParseInt(strings.TrimSpace(t), 10, 64); err == nil {
+				return p, true
+			}
+		}
+	case j.KindBool:
+		switch t := v.(type) {
+		case bool:
+			return t, true
+		case float64:
+			return r.bools.ParseNumber(t)
+		case string:
+			return r.bools.Parse(t)
+		}
+	case j.KindTime:
+		switch t := v.(type) {
+		case float64:
+			return times.ParseNumber(t)
+		case string:
+			return times.Parse(strings.TrimSpace(t))
+		}
+	default:
+		if t, ok := v.(string); ok {
+			return t, true
+		}
+		// fallback to JSON encoding
+		b, _ := json.Marshal(v)
+		return string(b), true
+	}
+	return nil, false
+}
+
+// rawText is the text of a JSON value as kept by iox.CastKeepRaw.
+func rawText(v any) string {
+	if s, ok := v.(string); ok {
+		return s
 	}
+	b, _ := json.Marshal(v)
+	return string(b)
 }
 
-func inferKinds(sample []map[string]any, keys []string) []j.Kind {
+// CastFailures returns the number of values per key that did not parse as the
+// column's type.
+func (r *Reader) CastFailures() map[string]int { return r.casts.Counts() }
+
+// inferKinds picks a kind per key. A key is a time when every non-null
+// sampled value looks like one (strings, or epoch numbers when enabled), and
+// a bool when every value is a JSON bool or a configured boolean literal.
+func inferKinds(sample []map[string]any, keys []string, times *iox.TimeParser, bools *iox.BoolParser) []j.Kind {
 	kinds := make([]j.Kind, len(keys))
 	numre := regexp.MustCompile(`^[-+]?[0-9]*\.?[0-9]+([eE][-+]?[0-9]+)?$`)
 	for i, k := range keys {
+		if allValues(sample, k, func(v any) bool { return looksTime(times, v) }) {
+			kinds[i] = j.KindTime
+			continue
+		}
+		if allValues(sample, k, func(v any) bool { return looksBool(bools, v) }) {
+			kinds[i] = j.KindBool
+			continue
+		}
 		nNum, nInt, nBool, nStr := 0, 0, 0, 0
 		for _, m := range sample {
 			v, ok := m[k]
@@ -198,3 +315,43 @@ func inferKinds(sample []map[string]any, keys []string) []j.Kind {
 	}
 	return kinds
 }
+
+// allValues reports whether key k has a value in the sample and every value
+// other than null and blank strings satisfies ok.
+func allValues(sample []map[string]any, k string, ok func(any) bool) bool {
+	seen := false
+	for _, m := range sample {
+		v := m[k]
+		if s, isStr := v.(string); v == nil || isStr && strings.TrimSpace(s) == "" {
+			continue
+		}
+		if !ok(v) {
+			return false
+		}
+		seen = true
+	}
+	return seen
+}
+
+func looksTime(times *iox.TimeParser, v any) bool {
+	switch t := v.(type) {
+	case float64:
+		return times.LooksNumber(t)
+	case string:
+		return times.Looks(strings.TrimSpace(t))
+	}
+	return false
+}
+
+func looksBool(bools *iox.BoolParser, v any) bool {
+	ok := false
+	switch t := v.(type) {
+	case bool:
+		ok = true
+	case float64:
+		_, ok = bools.ParseNumber(t)
+	case string:
+		_, ok = bools.Parse(t)
+	}
+	return ok
+}
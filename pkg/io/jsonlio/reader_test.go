@@ -1,10 +1,16 @@
 package jsonlio
 
 import (
+	"io"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
-)
+	"time"
 
+	iox "github.com/wdm0006/janitor/pkg/io/ioutils"
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
 func TestJSONLInferAndRead(t *testing.T) {
 	p := filepath.FromSlash("../../../examples/data/sample.jsonl")
 	r, f, err := Open(p, ReaderOptions{SampleRows: 10})
@@ -27,3 +33,214 @@ func TestJSONLInferAndRead(t *testing.T) {
 		t.Fatalf("expected 3 rows, got %d", fr.Rows())
 	}
 }
+
+func TestTimeRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "in.jsonl")
+	data := `{"at":"2024-03-05 10:30:00","ms":1709634600000,"n":7}` + "\n" +
+		`{"at":null,"ms":1709634601500,"n":8}` + "\n"
+	if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sr, f, err := NewStreamReader(p, ReaderOptions{TimeFormats: []string{"2006-01-02 15:04:05", "unix_ms"}}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	fr, err := sr.Next()
+	if err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"at", "ms"} {
+		col, _ := fr.ColumnByName(name)
+		if _, ok := col.(*j.TimeColumn); !ok {
+			t.Fatalf("%s should be a time column", name)
+		}
+	}
+	col, _ := fr.ColumnByName("ms")
+	if v, _ := col.(*j.TimeColumn).Get(1); !v.Equal(time.UnixMilli(1709634601500)) {
+		t.Fatalf("ms[1] = %v", v)
+	}
+	col, _ = fr.ColumnByName("n")
+	if _, ok := col.(*j.IntColumn); !ok {
+		t.Fatal("n should stay Int")
+	}
+
+	out := filepath.Join(dir, "out.jsonl")
+	if err := WriteAll(out, fr, WriterOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"at":"2024-03-05T10:30:00Z"`) || !strings.Contains(string(b), `"ms":"2024-03-05T10:30:01.5Z"`) {
+		t.Fatalf("unexpected output: %s", b)
+	}
+}
+
+func TestBoolLiterals(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "flags.jsonl")
+	data := `{"a":"yes","b":1,"c":true}` + "\n" + `{"a":"N","b":0,"c":"no"}` + "\n"
+	if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		opt  ReaderOptions
+		want map[string]j.Kind
+	}{
+		{ReaderOptions{}, map[string]j.Kind{"a": j.KindBool, "b": j.KindInt, "c": j.KindBool}},
+		{ReaderOptions{TrueValues: []string{"yes", "1", "true"}, FalseValues: []string{"n", "0", "no"}}, map[string]j.Kind{"a": j.KindBool, "b": j.KindBool, "c": j.KindBool}},
+	} {
+		r, f, err := Open(p, tc.opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		schema, err := r.InferSchema()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fr, err := r.ReadAll(schema)
+		_ = f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, cs := range schema.Columns {
+			if cs.Type != tc.want[cs.Name] {
+				t.Fatalf("%v: %s kind %d, want %d", tc.opt.TrueValues, cs.Name, cs.Type, tc.want[cs.Name])
+			}
+			if cs.Type != j.KindBool {
+				continue
+			}
+			col, _ := fr.ColumnByName(cs.Name)
+			v0, ok0 := col.(*j.BoolColumn).Get(0)
+			v1, ok1 := col.(*j.BoolColumn).Get(1)
+			if !ok0 || !ok1 || !v0 || v1 {
+				t.Fatalf("%s = %v,%v (ok %v,%v), want true,false", cs.Name, v0, v1, ok0, ok1)
+			}
+		}
+	}
+}
+
+func TestSchemaOverride(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "events.jsonl")
+	var b strings.Builder
+	for i := 0; i < 50; i++ {
+		b.WriteString(`{"id":1,"code":"007"}` + "\n")
+	}
+	b.WriteString(`{"id":2,"code":"008","late":"x"}` + "\n")
+	if err := os.WriteFile(p, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	o := iox.SchemaOverride{Columns: []iox.ColumnOverride{
+		{Name: "code", Type: j.KindString},
+		{Name: "late", Type: j.KindString, Rename: "late_flag"},
+	}}
+	r, f, err := Open(p, ReaderOptions{SampleRows: 1, Schema: o})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	schema, err := r.InferSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fr, err := r.ReadAll(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fr.Rows() != 51 {
+		t.Fatalf("expected 51 rows, got %d", fr.Rows())
+	}
+	col, _ := fr.ColumnByName("code")
+	if v, _ := col.(*j.StringColumn).Get(0); v != "007" {
+		t.Fatalf("code[0] = %q", v)
+	}
+	col, ok := fr.ColumnByName("late_flag")
+	if !ok {
+		t.Fatal("listed key missing from the sample should still be a column")
+	}
+	if v, _ := col.(*j.StringColumn).Get(50); v != "x" {
+		t.Fatalf("late_flag[50] = %q", v)
+	}
+}
+
+func TestCastFailuresStream(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "mixed.jsonl")
+	data := `{"n":1,"ok":true}` + "\n" + `{"n":2.5,"ok":"maybe"}` + "\n" + `{"n":"x","ok":false}` + "\n"
+	if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	o := iox.SchemaOverride{Columns: []iox.ColumnOverride{{Name: "n", Type: j.KindInt}}}
+	sr, f, err := NewStreamReader(p, ReaderOptions{Schema: o, OnCastError: iox.CastKeepRaw}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	fr, err := sr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sr.CastFailures(); got["n"] != 2 || got["ok"] != 1 {
+		t.Fatalf("failures = %v", got)
+	}
+	col, _ := fr.ColumnByName("n__raw")
+	if v, _ := col.(*j.StringColumn).Get(1); v != "2.5" {
+		t.Fatalf("n__raw[1] = %q", v)
+	}
+	col, _ = fr.ColumnByName("ok__raw")
+	if v, _ := col.(*j.StringColumn).Get(1); v != "maybe" {
+		t.Fatalf("ok__raw[1] = %q", v)
+	}
+}
+
+func TestColumnOrder(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "rows.jsonl")
+	data := `{"zeta":1,"alpha":"a","mid":true}` + "\n" +
+		`{"mid":false,"extra":2.5,"alpha":"b","zeta":2}` + "\n"
+	if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	names := func(s j.Schema) string {
+		var out []string
+		for _, cs := range s.Columns {
+			out = append(out, cs.Name)
+		}
+		return strings.Join(out, ",")
+	}
+	for i := 0; i < 5; i++ {
+		s, fh, err := NewStreamReader(p, ReaderOptions{}, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = fh.Close()
+		if got := names(s.Schema()); got != "zeta,alpha,mid,extra" {
+			t.Fatalf("first-seen order: got %s", got)
+		}
+	}
+
+	r, f, err := Open(p, ReaderOptions{Columns: []string{"mid", "zeta", "missing"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	schema, err := r.InferSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(schema); got != "mid,zeta,missing" {
+		t.Fatalf("selected columns: got %s", got)
+	}
+	fr, err := r.ReadAll(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	col, _ := fr.ColumnByName("zeta")
+	if v, _ := col.(*j.IntColumn).Get(1); v != 2 {
+		t.Fatalf("zeta[1] = %d", v)
+	}
+	col, _ = fr.ColumnByName("missing")
+	if !col.IsNull(0) || !col.IsNull(1) {
+		t.Fatal("listed key absent from the data should be null")
+	}
+}
@@ -0,0 +1,105 @@
+package jsonlio
+
+import (
+	"strings"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
+	iox "github.com/wdm0006/janitor/pkg/io/ioutils"
+)
+
+// ScanSchema reads the whole file and returns its schema: each block of
+// opt.SampleRows records (default 100) is inferred on its own and merged into
+// the schema of the blocks before it by drift, so keys and types that first
+// show up late are seen. Drift events carry the first row of the block. Keys
+// without a value in the file are j.KindInvalid unless opt.Schema types them;
+// see iox.FillUnknownKinds.
+func ScanSchema(path string, opt ReaderOptions, drift *iox.Drift) (j.Schema, error) {
+	opt.OnCastError, opt.Rejects = iox.CastNull, nil
+	r, f, err := Open(path, opt)
+	if err != nil {
+		return j.Schema{}, err
+	}
+	if f != nil {
+		defer func() { _ = f.Close() }()
+	}
+	max := opt.SampleRows
+	if max <= 0 {
+		max = 100
+	}
+	var schema j.Schema
+	row := 1
+	for {
+		sample, order, err := decodeSample(r.dec, max, r.fl)
+		if err != nil {
+			return j.Schema{}, err
+		}
+		if len(sample) == 0 && row > 1 {
+			return schema, nil
+		}
+		keys, err := columnKeys(order, r.opt)
+		if err != nil {
+			return j.Schema{}, err
+		}
+		kinds := inferKinds(sample, keys, r.times, r.bools)
+		next := j.Schema{Columns: make([]j.ColumnSchema, len(keys))}
+		for i, k := range keys {
+			if hasValue(sample, k) {
+				kinds[i] = r.fitKind(sample, k, kinds[i])
+			} else {
+				kinds[i] = j.KindInvalid
+			}
+			next.Columns[i] = j.ColumnSchema{Name: k, Type: kinds[i], Nullable: true}
+		}
+		next, _, err = r.opt.Schema.Apply(next, r.times)
+		if err != nil {
+			return j.Schema{}, err
+		}
+		schema, err = drift.Merge(schema, next, path, row)
+		if err != nil {
+			return j.Schema{}, err
+		}
+		if len(sample) == 0 {
+			return schema, nil
+		}
+		row += len(sample)
+	}
+}
+
+// fitKind widens kind (int -> float -> string) until every value of k in
+// sample converts to it, so that a few odd values in a block show up as drift
+// rather than as cast failures.
+func (r *Reader) fitKind(sample []map[string]any, k string, kind j.Kind) j.Kind {
+	for _, m := range sample {
+		v := m[k]
+		if s, ok := v.(string); v == nil || ok && strings.TrimSpace(s) == "" {
+			continue
+		}
+		for kind != j.KindString {
+			if _, ok := r.convert(v, kind, r.times); ok {
+				break
+			}
+			if kind == j.KindInt {
+				kind = j.KindFloat
+			} else {
+				kind = j.KindString
+			}
+		}
+	}
+	return kind
+}
+
+// hasValue reports whether key k has a non-null, non-blank value in sample.
+func hasValue(sample []map[string]any, k string) bool {
+	for _, m := range sample {
+		switch v := m[k].(type) {
+		case nil:
+		case string:
+			if strings.TrimSpace(v) != "" {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+	return false
+}
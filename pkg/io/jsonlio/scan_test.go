@@ -0,0 +1,55 @@
+package jsonlio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
+	iox "github.com/wdm0006/janitor/pkg/io/ioutils"
+)
+
+func TestScanSchemaLateKeys(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&b, `{"id":%d,"v":%d,"sparse":null}`+"\n", i, i)
+	}
+	b.WriteString(`{"id":10,"v":"n/a","sparse":3,"late":true}` + "\n")
+	p := filepath.Join(t.TempDir(), "drift.jsonl")
+	if err := os.WriteFile(p, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d := iox.NewDrift(iox.DriftWiden)
+	s, err := ScanSchema(p, ReaderOptions{SampleRows: 4}, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := schemaNames(s); got != "id,v,sparse,late" {
+		t.Fatalf("columns: %s", got)
+	}
+	want := []j.Kind{j.KindInt, j.KindString, j.KindInt, j.KindBool}
+	for i, k := range want {
+		if s.Columns[i].Type != k {
+			t.Fatalf("%s: got %s, want %s", s.Columns[i].Name, s.Columns[i].Type, k)
+		}
+	}
+	var log []string
+	for _, e := range d.Events {
+		log = append(log, e.String())
+	}
+	wantLog := p + ` row 9: column "v" widened from int to string` + "\n" + p + ` row 9: column "late" added (bool)`
+	if strings.Join(log, "\n") != wantLog {
+		t.Fatalf("drift log:\n%s", strings.Join(log, "\n"))
+	}
+
+	// the ignore policy keeps the first block's keys
+	s, err = ScanSchema(p, ReaderOptions{SampleRows: 4}, iox.NewDrift(iox.DriftIgnore))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := schemaNames(s); got != "id,v,sparse" {
+		t.Fatalf("ignore columns: %s", got)
+	}
+}
@@ -7,15 +7,40 @@ import (
 	"os"
 
 	j "github.com/wdm0006/janitor/pkg/janitor"
+	iox "github.com/wdm0006/janitor/pkg/io/ioutils"
 )
 
 type StreamReader struct {
 	dec       *json.Decoder
 	schema    j.Schema
 	chunkSize int
+	conv      *Reader // schema-to-input mapping and value parsers
+	pending   []map[string]any // rows of an exploded record left for the next chunk
 }
 
-func NewStreamReader(path string, chunkSize int) (*StreamReader, *os.File, error) {
+// NewStreamReader opens the file, infers the schema from the first
+// opt.SampleRows records (default 100) and returns a StreamReader.
+func NewStreamReader(path string, opt ReaderOptions, chunkSize int) (*StreamReader, *os.File, error) {
+	times, err := iox.NewTimeParser(opt.TimeFormats, opt.TimeZone)
+	if err != nil {
+		return nil, nil, err
+	}
+	bools, err := iox.NewBoolParser(opt.TrueValues, opt.FalseValues)
+	if err != nil {
+		return nil, nil, err
+	}
+	policy, err := iox.ParseCastPolicy(string(opt.OnCastError))
+	if err != nil {
+		return nil, nil, err
+	}
+	fl, err := newFlattener(opt)
+	if err != nil {
+		return nil, nil, err
+	}
+	max := opt.SampleRows
+	if max <= 0 {
+		max = 100
+	}
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, nil, err
@@ -23,30 +48,16 @@ func NewStreamReader(path string, chunkSize int) (*StreamReader, *os.File, error
 	r := bufio.NewReader(f)
 	dec := json.NewDecoder(r)
 	// infer schema from first chunk
-	sample := make([]map[string]any, 0, 100)
-	keysSet := map[string]struct{}{}
-	for len(sample) < 100 {
-		var m map[string]any
-		if err := dec.Decode(&m); err != nil {
-			if err == io.EOF {
-				break
-			}
-            _ = f.Close()
-			return nil, nil, err
-		}
-		sample = append(sample, m)
-		for k := range m {
-			keysSet[k] = struct{}{}
-		}
-	}
-	keys := make([]string, 0, len(keysSet))
-	for k := range keysSet {
-		keys = append(keys, k)
+	sample, order, err := decodeSample(dec, max, fl)
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
 	}
-	kinds := inferKinds(sample, keys)
-	schema := j.Schema{Columns: make([]j.ColumnSchema, len(keys))}
-	for i, k := range keys {
-		schema.Columns[i] = j.ColumnSchema{Name: k, Type: kinds[i], Nullable: true}
+	conv := &Reader{opt: opt, times: times, bools: bools, casts: iox.NewCastErrors(policy, opt.Rejects), fl: fl}
+	schema, err := conv.buildSchema(sample, order)
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
 	}
 	// create a new decoder over the same file by seeking back to start
     if _, err := f.Seek(0, io.SeekStart); err != nil {
@@ -54,7 +65,7 @@ func NewStreamReader(path string, chunkSize int) (*StreamReader, *os.File, error
         return nil, nil, err
     }
 	dec = json.NewDecoder(bufio.NewReader(f))
-	return &StreamReader{dec: dec, schema: schema, chunkSize: chunkSize}, f, nil
+	return &StreamReader{dec: dec, schema: schema, chunkSize: chunkSize, conv: conv}, f, nil
 }
 
 func (s *StreamReader) Next() (*j.Frame, error) {
@@ -63,70 +74,64 @@ func (s *StreamReader) Next() (*j.Frame, error) {
 	}
 	f := j.NewFrame(s.schema)
 	for f.Rows() < s.chunkSize {
-		var m map[string]any
-		if err := s.dec.Decode(&m); err != nil {
-			if err == io.EOF {
-				if f.Rows() == 0 {
-					return nil, io.EOF
+		if len(s.pending) == 0 {
+			var m map[string]any
+			if err := s.dec.Decode(&m); err != nil {
+				if err == io.EOF {
+					if f.Rows() == 0 {
+						return nil, io.EOF
+					}
+					break
 				}
-				return f, nil
+				return nil, err
 			}
-			return nil, err
+			s.pending, _ = s.conv.fl.records(m, nil)
 		}
+		m := s.pending[0]
+		s.pending = s.pending[1:]
 		f.AppendNullRow()
-		row := f.Rows() - 1
 		// reuse setter from reader.go
-		(&Reader{}).setRowFromMap(f, row, m)
+		if err := s.conv.setRowFromMap(f, f.Rows()-1, m); err != nil {
+			return nil, err
+		}
+	}
+	f, err := s.conv.casts.Finish(f)
+	if err != nil {
+		return nil, err
+	}
+	if err := iox.CheckNullable(f); err != nil {
+		return nil, err
 	}
 	return f, nil
 }
 
 func (s *StreamReader) Schema() j.Schema { return s.schema }
 
+// CastFailures returns the number of values per key that did not parse as the
+// column's type.
+func (s *StreamReader) CastFailures() map[string]int { return s.conv.casts.Counts() }
+
 type StreamWriter struct {
 	enc  *json.Encoder
 	w    *bufio.Writer
-	file *os.File
+	file io.WriteCloser
+	opt  WriterOptions
 }
 
-func NewStreamWriter(path string) (*StreamWriter, error) {
-	f, err := os.Create(path)
+// NewStreamWriter writes JSON lines to path: stdout for "-", gzip for a .gz
+// path.
+func NewStreamWriter(path string, opt WriterOptions) (*StreamWriter, error) {
+	f, err := iox.CreateMaybeCompressed(path)
 	if err != nil {
 		return nil, err
 	}
 	w := bufio.NewWriter(f)
-	return &StreamWriter{enc: json.NewEncoder(w), w: w, file: f}, nil
+	return &StreamWriter{enc: json.NewEncoder(w), w: w, file: f, opt: opt}, nil
 }
 
 func (s *StreamWriter) Write(f *j.Frame) error {
 	for r := 0; r < f.Rows(); r++ {
-		m := map[string]any{}
-		for _, cs := range f.Schema().Columns {
-			col, _ := f.ColumnByName(cs.Name)
-			switch cs.Type {
-			case j.KindFloat:
-				if v, ok := col.(*j.FloatColumn).Get(r); ok {
-					m[cs.Name] = v
-				}
-			case j.KindInt:
-				if v, ok := col.(*j.IntColumn).Get(r); ok {
-					m[cs.Name] = v
-				}
-			case j.KindBool:
-				if v, ok := col.(*j.BoolColumn).Get(r); ok {
-					m[cs.Name] = v
-				}
-			case j.KindString:
-				if v, ok := col.(*j.StringColumn).Get(r); ok {
-					m[cs.Name] = v
-				}
-			case j.KindTime:
-				if v, ok := col.(*j.TimeColumn).Get(r); ok {
-					m[cs.Name] = v
-				}
-			}
-		}
-		if err := s.enc.Encode(m); err != nil {
+		if err := s.enc.Encode(rowMap(f, r, s.opt)); err != nil {
 			return err
 		}
 	}
@@ -2,13 +2,16 @@ package jsonlio
 
 import (
 	"io"
+	"os"
 	"path/filepath"
 	"testing"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
 )
 
 func TestStreamReadJSONL(t *testing.T) {
 	p := filepath.FromSlash("../../../examples/data/sample.jsonl")
-	sr, f, err := NewStreamReader(p, 2)
+	sr, f, err := NewStreamReader(p, ReaderOptions{}, 2)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -28,3 +31,31 @@ func TestStreamReadJSONL(t *testing.T) {
 		t.Fatalf("expected 3 rows, got %d", total)
 	}
 }
+
+func TestWriterKeepsColumnOrder(t *testing.T) {
+	s := j.Schema{Columns: []j.ColumnSchema{
+		{Name: "zeta", Type: j.KindInt},
+		{Name: "alpha", Type: j.KindString, Nullable: true},
+		{Name: "mid", Type: j.KindBool},
+	}}
+	f := j.NewFrame(s)
+	f.AppendNullRow()
+	_ = f.SetCell(0, "zeta", int64(1))
+	_ = f.SetCell(0, "alpha", "<a>")
+	_ = f.SetCell(0, "mid", true)
+	f.AppendNullRow()
+	_ = f.SetCell(1, "zeta", int64(2))
+	_ = f.SetCell(1, "mid", false)
+	p := filepath.Join(t.TempDir(), "out.jsonl")
+	if err := WriteAll(p, f, WriterOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "{\"zeta\":1,\"alpha\":\"\\u003ca\\u003e\",\"mid\":true}\n{\"zeta\":2,\"mid\":false}\n"
+	if string(b) != want {
+		t.Fatalf("got %q, want %q", b, want)
+	}
+}
@@ -2,13 +2,20 @@ package jsonlio
 
 import (
     "bufio"
+    "bytes"
     "encoding/json"
 
     j "github.com/wdm0006/janitor/pkg/janitor"
     iox "github.com/wdm0006/janitor/pkg/io/ioutils"
 )
 
-func WriteAll(path string, f *j.Frame) error {
+type WriterOptions struct {
+	// Unflatten nests dotted column names back into objects, so that
+	// "user.address.city" is written as {"user":{"address":{"city":...}}}.
+	Unflatten bool
+}
+
+func WriteAll(path string, f *j.Frame, opt WriterOptions) error {
     out, err := iox.CreateMaybeCompressed(path)
     if err != nil {
         return err
@@ -17,35 +24,83 @@ func WriteAll(path string, f *j.Frame) error {
 	w := bufio.NewWriter(out)
 	enc := json.NewEncoder(w)
 	for r := 0; r < f.Rows(); r++ {
-		m := map[string]any{}
-		for _, cs := range f.Schema().Columns {
-			col, _ := f.ColumnByName(cs.Name)
-			switch cs.Type {
-			case j.KindFloat:
-				if v, ok := col.(*j.FloatColumn).Get(r); ok {
-					m[cs.Name] = v
-				}
-			case j.KindInt:
-				if v, ok := col.(*j.IntColumn).Get(r); ok {
-					m[cs.Name] = v
-				}
-			case j.KindBool:
-				if v, ok := col.(*j.BoolColumn).Get(r); ok {
-					m[cs.Name] = v
-				}
-			case j.KindString:
-				if v, ok := col.(*j.StringColumn).Get(r); ok {
-					m[cs.Name] = v
-				}
-			case j.KindTime:
-				if v, ok := col.(*j.TimeColumn).Get(r); ok {
-					m[cs.Name] = v
-				}
-			}
-		}
-		if err := enc.Encode(m); err != nil {
+		if err := enc.Encode(rowMap(f, r, opt)); err != nil {
 			return err
 		}
 	}
 	return w.Flush()
 }
+
+// rowMap returns row r of f as a JSON object with its keys in column order;
+// null cells are left out.
+func rowMap(f *j.Frame, r int, opt WriterOptions) *object {
+	m := newObject()
+	for _, cs := range f.Schema().Columns {
+		col, _ := f.ColumnByName(cs.Name)
+		switch cs.Type {
+		case j.KindFloat:
+			if v, ok := col.(*j.FloatColumn).Get(r); ok {
+				m.set(cs.Name, v)
+			}
+		case j.KindInt:
+			if v, ok := col.(*j.IntColumn).Get(r); ok {
+				m.set(cs.Name, v)
+			}
+		case j.KindBool:
+			if v, ok := col.(*j.BoolColumn).Get(r); ok {
+				m.set(cs.Name, v)
+			}
+		case j.KindString:
+			if v, ok := col.(*j.StringColumn).Get(r); ok {
+				m.set(cs.Name, v)
+			}
+		case j.KindTime:
+			if v, ok := col.(*j.TimeColumn).Get(r); ok {
+				m.set(cs.Name, iox.FormatTime(v))
+			}
+		}
+	}
+	if opt.Unflatten {
+		return unflatten(m)
+	}
+	return m
+}
+
+// object is a JSON object that keeps its keys in the order they were set;
+// encoding/json writes map keys sorted.
+type object struct {
+	keys []string
+	vals map[string]any
+}
+
+func newObject() *object { return &object{vals: map[string]any{}} }
+
+func (o *object) set(k string, v any) {
+	if _, ok := o.vals[k]; !ok {
+		o.keys = append(o.keys, k)
+	}
+	o.vals[k] = v
+}
+
+func (o *object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		kb, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		vb, err := json.Marshal(o.vals[k])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(kb)
+		b.WriteByte(':')
+		b.Write(vb)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
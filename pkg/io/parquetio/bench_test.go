@@ -23,7 +23,20 @@ func BenchmarkParquetWrite(b *testing.B) {
     b.Cleanup(func() { _ = os.Remove(path) })
     b.ResetTimer()
     for i := 0; i < b.N; i++ {
-        _ = WriteAll(path, f)
+        _ = WriteAll(path, f, WriterOptions{})
     }
 }
 
+
+func BenchmarkParquetRead(b *testing.B) {
+    path := "bench_read.parquet"
+    if err := WriteAll(path, makeFrame(50000), WriterOptions{}); err != nil { b.Fatal(err) }
+    b.Cleanup(func() { _ = os.Remove(path) })
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        r, err := OpenReader(path, ReaderOptions{})
+        if err != nil { b.Fatal(err) }
+        if _, err := r.ReadAll(); err != nil { b.Fatal(err) }
+        _ = r.Close()
+    }
+}
@@ -2,154 +2,373 @@ package parquetio
 
 import (
     "fmt"
+    "io"
+    "math"
+    "math/big"
     "os"
-    "strconv"
     "strings"
+    "time"
 
-    parquet "github.com/segmentio/parquet-go"
+    parquet "github.com/parquet-go/parquet-go"
 
+    iox "github.com/wdm0006/janitor/pkg/io/ioutils"
     j "github.com/wdm0006/janitor/pkg/janitor"
 )
 
+// Reader decodes a Parquet file column by column into Frames, one row group
+// at a time.
 type Reader struct {
     file   *os.File
-    reader *parquet.GenericReader[map[string]any]
-    schema j.Schema
+    pf     *parquet.File
+    leaves []leaf // one per leaf column, in file order
+    schema j.Schema // the leaf columns, then any <col>__raw columns of casts
+    casts  *iox.CastErrors
+    fails  []castFailure // conversions that failed in the columns being filled
+
+    group   int            // index of the next row group to open
+    cursors []columnCursor // page readers of the open row group
+    left    int64          // rows not yet read from the open row group
+}
+
+// leaf maps one Parquet leaf column to a janitor column.
+type leaf struct {
+    name   string
+    kind   j.Kind
+    append func(c j.Column, v parquet.Value) // appends a non-null value
+    value  func(v parquet.Value) any         // decodes a non-null value
+}
+
+// castFailure is a value of leaf column col that could not be converted to
+// the column's type.
+type castFailure struct {
+    row, col int
+    raw      string
 }
 
-func OpenReader(path string, sampleRows int) (*Reader, error) {
+// ReaderOptions configure a Reader. The file's metadata supplies the schema,
+// so only Schema overrides need options; the time and boolean settings are
+// used when Schema converts a column to a time or bool.
+type ReaderOptions struct {
+    // Schema pins the type, nullability or name of listed columns.
+    Schema      iox.SchemaOverride
+    TimeFormats []string
+    TimeZone    string
+    TrueValues  []string
+    FalseValues []string
+    // OnCastError is what happens to values a Schema conversion cannot
+    // convert ("" means null). Rejected rows are written to Rejects.
+    OnCastError iox.CastPolicy
+    Rejects     j.ChunkSink
+}
+
+// OpenReader opens a Parquet file. The schema comes from the file's own
+// metadata, with opt.Schema applied on top.
+func OpenReader(path string, opt ReaderOptions) (*Reader, error) {
+    times, err := iox.NewTimeParser(opt.TimeFormats, opt.TimeZone)
+    if err != nil { return nil, err }
+    bools, err := iox.NewBoolParser(opt.TrueValues, opt.FalseValues)
+    if err != nil { return nil, err }
+    policy, err := iox.ParseCastPolicy(string(opt.OnCastError))
+    if err != nil { return nil, err }
     f, err := os.Open(path)
     if err != nil { return nil, err }
-    r := parquet.NewGenericReader[map[string]any](f)
-    // infer schema from first N rows
-    if sampleRows <= 0 { sampleRows = 100 }
-    rows := make([]map[string]any, sampleRows)
-    n, err := r.Read(rows)
-    if err != nil && err.Error() != "EOF" && !strings.Contains(err.Error(), "EOF") {
-        _ = r.Close(); _ = f.Close(); return nil, err
-    }
-    rows = rows[:n]
-    schema := inferSchema(rows)
-    // push the read rows back by creating a new reader (segmentio readers can't unread), so reopen
-    if err := r.Close(); err != nil { _ = f.Close(); return nil, err }
-    if _, err := f.Seek(0, 0); err != nil { _ = f.Close(); return nil, err }
-    r2 := parquet.NewGenericReader[map[string]any](f)
-    return &Reader{file: f, reader: r2, schema: schema}, nil
+    st, err := f.Stat()
+    if err != nil { _ = f.Close(); return nil, err }
+    pf, err := parquet.OpenFile(f, st.Size())
+    if err != nil { _ = f.Close(); return nil, err }
+    schema, leaves, err := schemaOf(pf.Schema())
+    if err != nil { _ = f.Close(); return nil, fmt.Errorf("%s: %w", path, err) }
+    schema, colTimes, err := opt.Schema.Apply(schema, times)
+    if err != nil { _ = f.Close(); return nil, fmt.Errorf("%s: %w", path, err) }
+    r := &Reader{file: f, pf: pf, leaves: leaves, casts: iox.NewCastErrors(policy, opt.Rejects)}
+    for i, cs := range schema.Columns {
+        if cs.Type != leaves[i].kind || colTimes[i] != times { leaves[i] = r.converted(i, leaves[i], cs.Type, colTimes[i], bools) }
+    }
+    r.schema = r.casts.Schema(schema)
+    return r, nil
+}
+
+// converted returns a leaf that decodes like src and converts each value to
+// kind. A value that cannot be converted is appended as null and recorded as
+// a cast failure of leaf column col.
+func (r *Reader) converted(col int, src leaf, kind j.Kind, times *iox.TimeParser, bools *iox.BoolParser) leaf {
+    l := leaf{name: src.name, kind: kind}
+    l.append = func(c j.Column, v parquet.Value) {
+        val := src.value(v)
+        x, ok := iox.Convert(val, kind, times, bools)
+        if !ok {
+            appendNull(c)
+            raw, _ := iox.Convert(val, j.KindString, times, bools)
+            s, _ := raw.(string)
+            r.fails = append(r.fails, castFailure{row: c.Len() - 1, col: col, raw: s})
+            return
+        }
+        switch col := c.(type) {
+        case *j.BoolColumn:
+            col.Append(x.(bool))
+        case *j.IntColumn:
+            col.Append(x.(int64))
+        case *j.FloatColumn:
+            col.Append(x.(float64))
+        case *j.StringColumn:
+            col.Append(x.(string))
+        case *j.TimeColumn:
+            col.Append(x.(time.Time))
+        }
+    }
+    return l
 }
 
 func (r *Reader) Close() error {
-    _ = r.reader.Close()
+    r.closeGroup()
     return r.file.Close()
 }
 
 func (r *Reader) Schema() j.Schema { return r.schema }
 
+// ReadAll reads every remaining row into one Frame.
 func (r *Reader) ReadAll() (*j.Frame, error) {
-    f := j.NewFrame(r.schema)
-    buf := make([]map[string]any, 1024)
+    cols := r.newColumns()
     for {
-        n, err := r.reader.Read(buf)
-        if n > 0 {
-            for i := 0; i < n; i++ {
-                f.AppendNullRow()
-                row := f.Rows() - 1
-                setRow(f, row, buf[i])
-            }
+        _, err := r.fill(cols, math.MaxInt64)
+        if err == io.EOF { break }
+        if err != nil { return nil, err }
+    }
+    return r.frame(cols)
+}
+
+// frame builds a Frame of filled cols and applies the cast error policy to
+// the values that did not convert.
+func (r *Reader) frame(cols []j.Column) (*j.Frame, error) {
+    n := 0
+    if len(r.leaves) > 0 { n = cols[0].Len() }
+    for _, c := range cols[len(r.leaves):] {
+        for c.Len() < n { appendNull(c) }
+    }
+    fr, err := j.NewFrameFromColumns(r.schema, cols)
+    if err != nil { return nil, err }
+    for _, e := range r.fails {
+        cs := r.schema.Columns[e.col]
+        if err := r.casts.Fail(fr, e.row, cs.Name, cs.Type, e.raw); err != nil { r.fails = nil; return nil, err }
+    }
+    r.fails = r.fails[:0]
+    if fr, err = r.casts.Finish(fr); err != nil { return nil, err }
+    if err := iox.CheckNullable(fr); err != nil { return nil, err }
+    return fr, nil
+}
+
+// CastFailures returns the number of values per column that a Schema
+// conversion could not convert.
+func (r *Reader) CastFailures() map[string]int { return r.casts.Counts() }
+
+func (r *Reader) newColumns() []j.Column {
+    cols := make([]j.Column, len(r.schema.Columns))
+    for i, cs := range r.schema.Columns {
+        switch cs.Type {
+        case j.KindBool:
+            cols[i] = j.NewBoolColumn(cs.Name, 0)
+        case j.KindInt:
+            cols[i] = j.NewIntColumn(cs.Name, 0)
+        case j.KindFloat:
+            cols[i] = j.NewFloatColumn(cs.Name, 0)
+        case j.KindTime:
+            cols[i] = j.NewTimeColumn(cs.Name, 0)
+        default:
+            cols[i] = j.NewStringColumn(cs.Name, 0)
         }
-        if err != nil {
-            if err.Error() == "EOF" || strings.Contains(err.Error(), "EOF") { break }
-            return nil, err
+    }
+    return cols
+}
+
+// fill appends up to max rows of the current row group to cols, opening the
+// next row group when the current one is exhausted. It never reads across a
+// row-group boundary and returns io.EOF after the last row group.
+func (r *Reader) fill(cols []j.Column, max int64) (int64, error) {
+    for r.left == 0 {
+        r.closeGroup()
+        groups := r.pf.RowGroups()
+        if r.group >= len(groups) { return 0, io.EOF }
+        rg := groups[r.group]
+        r.group++
+        r.left = rg.NumRows()
+        chunks := rg.ColumnChunks()
+        r.cursors = make([]columnCursor, len(chunks))
+        for i, cc := range chunks { r.cursors[i] = columnCursor{pages: cc.Pages()} }
+    }
+    n := r.left
+    if n > max { n = max }
+    for i := range r.leaves {
+        if err := r.cursors[i].read(cols[i], r.leaves[i], n); err != nil {
+            return 0, fmt.Errorf("parquet column %s: %w", r.leaves[i].name, err)
         }
-        if n == 0 { break }
-    }
-    return f, nil
-}
-
-func inferSchema(rows []map[string]any) j.Schema {
-    keysSet := map[string]struct{}{}
-    for _, m := range rows {
-        for k := range m { keysSet[k] = struct{}{} }
-    }
-    keys := make([]string, 0, len(keysSet))
-    for k := range keysSet { keys = append(keys, k) }
-    kinds := make([]j.Kind, len(keys))
-    for i, k := range keys {
-        nNum, nInt, nBool, nStr := 0, 0, 0, 0
-        for _, m := range rows {
-            v, ok := m[k]
-            if !ok || v == nil { continue }
-            switch t := v.(type) {
-            case float64:
-                nNum++; if float64(int64(t)) == t { nInt++ }
-            case int, int64:
-                nNum++; nInt++
-            case bool:
-                nBool++
-            case string:
-                s := strings.TrimSpace(t)
-                if s == "" { continue }
-                if x, err := strconv.ParseFloat(s, 64); err == nil { nNum++; if float64(int64(x)) == x { nInt++ } } else { nStr++ }
-            default:
-                nStr++
-            }
+    }
+    r.left -= n
+    return n, nil
+}
+
+func (r *Reader) closeGroup() {
+    for _, c := range r.cursors { _ = c.pages.Close() }
+    r.cursors, r.left = nil, 0
+}
+
+// columnCursor reads one column chunk page by page, in batches of values.
+type columnCursor struct {
+    pages  parquet.Pages
+    values parquet.ValueReader
+    buf    []parquet.Value
+    pos    int
+}
+
+// read appends n rows to c. Repeated columns keep the first value of each row.
+func (cur *columnCursor) read(c j.Column, l leaf, n int64) error {
+    for rows := int64(0); rows < n; {
+        if cur.pos == len(cur.buf) {
+            if err := cur.refill(); err != nil { return err }
+            continue
         }
-        switch {
-        case nBool > nNum && nBool >= nStr:
-            kinds[i] = j.KindBool
-        case nNum > nStr:
-            if nInt == nNum { kinds[i] = j.KindInt } else { kinds[i] = j.KindFloat }
-        default:
-            kinds[i] = j.KindString
+        v := cur.buf[cur.pos]
+        cur.pos++
+        if v.RepetitionLevel() != 0 { continue }
+        if v.IsNull() { appendNull(c) } else { l.append(c, v) }
+        rows++
+    }
+    return nil
+}
+
+func (cur *columnCursor) refill() error {
+    if cur.buf == nil { cur.buf = make([]parquet.Value, 0, 1024) }
+    for {
+        if cur.values == nil {
+            page, err := cur.pages.ReadPage()
+            if err == io.EOF { return io.ErrUnexpectedEOF }
+            if err != nil { return err }
+            cur.values = page.Values()
         }
+        n, err := cur.values.ReadValues(cur.buf[:cap(cur.buf)])
+        cur.buf, cur.pos = cur.buf[:n], 0
+        if err == io.EOF { cur.values = nil } else if err != nil { return err }
+        if n > 0 { return nil }
+    }
+}
+
+func appendNull(c j.Column) {
+    switch col := c.(type) {
+    case *j.BoolColumn:
+        col.AppendNull()
+    case *j.IntColumn:
+        col.AppendNull()
+    case *j.FloatColumn:
+        col.AppendNull()
+    case *j.StringColumn:
+        col.AppendNull()
+    case *j.TimeColumn:
+        col.AppendNull()
+    }
+}
+
+// schemaOf builds a janitor Schema from a Parquet schema, one column per leaf
+// (nested fields are named by their dotted path), using the logical type where
+// the file declares one.
+func schemaOf(s *parquet.Schema) (j.Schema, []leaf, error) {
+    var out j.Schema
+    var leaves []leaf
+    for _, path := range s.Columns() {
+        lc, ok := s.Lookup(path...)
+        if !ok { return j.Schema{}, nil, fmt.Errorf("parquet column %v not found", path) }
+        l := leafOf(lc.Node.Type())
+        l.name = strings.Join(path, ".")
+        out.Columns = append(out.Columns, j.ColumnSchema{Name: l.name, Type: l.kind, Nullable: lc.Node.Optional()})
+        leaves = append(leaves, l)
     }
-    schema := j.Schema{Columns: make([]j.ColumnSchema, len(keys))}
-    for i, k := range keys { schema.Columns[i] = j.ColumnSchema{Name: k, Type: kinds[i], Nullable: true} }
-    return schema
-}
-
-func setRow(f *j.Frame, row int, m map[string]any) {
-    for _, cs := range f.Schema().Columns {
-        if v, ok := m[cs.Name]; ok {
-            switch cs.Type {
-            case j.KindFloat:
-                switch t := v.(type) {
-                case float64:
-                    _ = f.SetCell(row, cs.Name, t)
-                case int:
-                    _ = f.SetCell(row, cs.Name, float64(t))
-                case int64:
-                    _ = f.SetCell(row, cs.Name, float64(t))
-                case string:
-                    if s := strings.TrimSpace(t); s != "" { if x, err := strconv.ParseFloat(s, 64); err == nil { _ = f.SetCell(row, cs.Name, x) } }
-                }
-            case j.KindInt:
-                switch t := v.(type) {
-                case int64:
-                    _ = f.SetCell(row, cs.Name, t)
-                case int:
-                    _ = f.SetCell(row, cs.Name, int64(t))
-                case float64:
-                    _ = f.SetCell(row, cs.Name, int64(t))
-                case string:
-                    if s := strings.TrimSpace(t); s != "" { if x, err := strconv.ParseInt(s, 10, 64); err == nil { _ = f.SetCell(row, cs.Name, x) } }
-                }
-            case j.KindBool:
-                switch t := v.(type) {
-                case bool:
-                    _ = f.SetCell(row, cs.Name, t)
-                case string:
-                    lv := strings.ToLower(strings.TrimSpace(t))
-                    if x, err := strconv.ParseBool(lv); err == nil { _ = f.SetCell(row, cs.Name, x) }
-                }
+    return out, leaves, nil
+}
+
+const julianUnixEpoch = 2440588 // Julian day number of 1970-01-01
+
+func timeLeaf(conv func(parquet.Value) time.Time) leaf {
+    return leaf{kind: j.KindTime, append: func(c j.Column, v parquet.Value) { c.(*j.TimeColumn).Append(conv(v)) },
+        value: func(v parquet.Value) any { return conv(v) }}
+}
+
+func floatLeaf(conv func(parquet.Value) float64) leaf {
+    return leaf{kind: j.KindFloat, append: func(c j.Column, v parquet.Value) { c.(*j.FloatColumn).Append(conv(v)) },
+        value: func(v parquet.Value) any { return conv(v) }}
+}
+
+var (
+    intLeaf    = leaf{kind: j.KindInt, append: func(c j.Column, v parquet.Value) { c.(*j.IntColumn).Append(intValue(v)) },
+        value: func(v parquet.Value) any { return intValue(v) }}
+    stringLeaf = leaf{kind: j.KindString, append: func(c j.Column, v parquet.Value) { c.(*j.StringColumn).Append(string(v.ByteArray())) },
+        value: func(v parquet.Value) any { return string(v.ByteArray()) }}
+    boolLeaf   = leaf{kind: j.KindBool, append: func(c j.Column, v parquet.Value) { c.(*j.BoolColumn).Append(v.Boolean()) },
+        value: func(v parquet.Value) any { return v.Boolean() }}
+)
+
+func leafOf(t parquet.Type) leaf {
+    if lt := t.LogicalType(); lt != nil {
+        switch {
+        case lt.Timestamp != nil:
+            u := lt.Timestamp.Unit
+            switch {
+            case u.Millis != nil:
+                return timeLeaf(func(v parquet.Value) time.Time { return time.UnixMilli(v.Int64()).UTC() })
+            case u.Nanos != nil:
+                return timeLeaf(func(v parquet.Value) time.Time { return time.Unix(0, v.Int64()).UTC() })
             default:
-                switch t := v.(type) {
-                case string:
-                    _ = f.SetCell(row, cs.Name, t)
-                default:
-                    _ = f.SetCell(row, cs.Name, fmt.Sprintf("%v", t))
-                }
+                return timeLeaf(func(v parquet.Value) time.Time { return time.UnixMicro(v.Int64()).UTC() })
             }
+        case lt.Date != nil:
+            return timeLeaf(func(v parquet.Value) time.Time { return time.Unix(int64(v.Int32())*86400, 0).UTC() })
+        case lt.Decimal != nil:
+            scale := math.Pow10(int(lt.Decimal.Scale))
+            return floatLeaf(func(v parquet.Value) float64 { return unscaled(v) / scale })
+        case lt.Integer != nil:
+            return intLeaf
+        case lt.UTF8 != nil, lt.Enum != nil, lt.Json != nil, lt.Bson != nil, lt.UUID != nil:
+            return stringLeaf
         }
     }
+    switch t.Kind() {
+    case parquet.Boolean:
+        return boolLeaf
+    case parquet.Int32, parquet.Int64:
+        return intLeaf
+    case parquet.Int96:
+        // legacy timestamps: nanoseconds of the day plus a Julian day number
+        return timeLeaf(func(v parquet.Value) time.Time {
+            x := v.Int96()
+            nanos := int64(x[1])<<32 | int64(x[0])
+            days := int64(x[2]) - julianUnixEpoch
+            return time.Unix(days*86400, nanos).UTC()
+        })
+    case parquet.Float:
+        return floatLeaf(func(v parquet.Value) float64 { return float64(v.Float()) })
+    case parquet.Double:
+        return floatLeaf(parquet.Value.Double)
+    default:
+        return stringLeaf
+    }
+}
+
+func intValue(v parquet.Value) int64 {
+    if v.Kind() == parquet.Int32 { return int64(v.Int32()) }
+    return v.Int64()
 }
 
+// unscaled returns the integer stored in a DECIMAL value, which may be an
+// INT32, an INT64 or a big-endian two's-complement byte array.
+func unscaled(v parquet.Value) float64 {
+    switch v.Kind() {
+    case parquet.Int32:
+        return float64(v.Int32())
+    case parquet.Int64:
+        return float64(v.Int64())
+    }
+    b := v.ByteArray()
+    n := new(big.Int).SetBytes(b)
+    if len(b) > 0 && b[0]&0x80 != 0 {
+        n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(b))*8))
+    }
+    x, _ := new(big.Float).SetInt(n).Float64()
+    return x
+}
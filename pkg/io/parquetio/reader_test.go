@@ -0,0 +1,196 @@
+package parquetio
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	iox "github.com/wdm0006/janitor/pkg/io/ioutils"
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+func TestNativeTypesRoundTrip(t *testing.T) {
+	s := j.Schema{Columns: []j.ColumnSchema{
+		{Name: "ts", Type: j.KindTime, Nullable: true},
+		{Name: "day", Type: j.KindTime, Nullable: true},
+		{Name: "price", Type: j.KindFloat, Nullable: true},
+		{Name: "qty", Type: j.KindInt, Nullable: true},
+		{Name: "sku", Type: j.KindString, Nullable: true},
+	}}
+	ts := time.Date(2024, 3, 5, 10, 30, 15, 123456000, time.UTC)
+	f := j.NewFrame(s)
+	f.AppendNullRow()
+	_ = f.SetCell(0, "ts", ts)
+	_ = f.SetCell(0, "day", ts)
+	_ = f.SetCell(0, "price", 19.99)
+	_ = f.SetCell(0, "qty", int64(-7))
+	_ = f.SetCell(0, "sku", "A-1")
+	f.AppendNullRow()
+	opt := WriterOptions{Columns: map[string]ColumnOptions{
+		"day":   {Type: "date"},
+		"price": {Type: "decimal", Precision: 9, Scale: 2},
+		"qty":   {Type: "int32"},
+	}}
+	for _, codec := range []string{"snappy", "zstd", "gzip", "none"} {
+		opt.Compression = codec
+		p := filepath.Join(t.TempDir(), codec+".parquet")
+		if err := WriteAll(p, f, opt); err != nil {
+			t.Fatalf("%s: %v", codec, err)
+		}
+		r, err := OpenReader(p, ReaderOptions{})
+		if err != nil {
+			t.Fatalf("%s: %v", codec, err)
+		}
+		got := r.Schema()
+		for i, want := range []j.Kind{j.KindTime, j.KindTime, j.KindFloat, j.KindInt, j.KindString} {
+			if got.Columns[i].Type != want {
+				t.Fatalf("%s: column %s kind %d, want %d", codec, got.Columns[i].Name, got.Columns[i].Type, want)
+			}
+		}
+		fr, err := r.ReadAll()
+		_ = r.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", codec, err)
+		}
+		col, _ := fr.ColumnByName("ts")
+		if v, _ := col.(*j.TimeColumn).Get(0); !v.Equal(ts) {
+			t.Fatalf("%s: ts = %v, want %v", codec, v, ts)
+		}
+		col, _ = fr.ColumnByName("day")
+		if v, _ := col.(*j.TimeColumn).Get(0); !v.Equal(time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)) {
+			t.Fatalf("%s: day = %v", codec, v)
+		}
+		col, _ = fr.ColumnByName("price")
+		if v, _ := col.(*j.FloatColumn).Get(0); v != 19.99 {
+			t.Fatalf("%s: price = %v", codec, v)
+		}
+		col, _ = fr.ColumnByName("qty")
+		if v, _ := col.(*j.IntColumn).Get(0); v != -7 {
+			t.Fatalf("%s: qty = %v", codec, v)
+		}
+		if !col.IsNull(1) {
+			t.Fatalf("%s: qty[1] should be null", codec)
+		}
+	}
+}
+
+func TestWriterRejectsBadOverride(t *testing.T) {
+	s := j.Schema{Columns: []j.ColumnSchema{{Name: "s", Type: j.KindString, Nullable: true}}}
+	p := filepath.Join(t.TempDir(), "bad.parquet")
+	if _, err := NewStreamWriter(p, s, WriterOptions{Columns: map[string]ColumnOptions{"s": {Type: "date"}}}); err == nil {
+		t.Fatal("expected an error for a date override on a string column")
+	}
+	if _, err := NewStreamWriter(p, s, WriterOptions{Compression: "lz5"}); err == nil {
+		t.Fatal("expected an error for an unknown codec")
+	}
+}
+
+func TestSchemaOverrideConverts(t *testing.T) {
+	s := j.Schema{Columns: []j.ColumnSchema{
+		{Name: "id", Type: j.KindInt, Nullable: true},
+		{Name: "at", Type: j.KindString, Nullable: true},
+	}}
+	f := j.NewFrame(s)
+	f.AppendNullRow()
+	_ = f.SetCell(0, "id", int64(42))
+	_ = f.SetCell(0, "at", "2024-03-05")
+	f.AppendNullRow()
+	_ = f.SetCell(1, "id", int64(7))
+	_ = f.SetCell(1, "at", "soon")
+	p := filepath.Join(t.TempDir(), "o.parquet")
+	if err := WriteAll(p, f, WriterOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	r, err := OpenReader(p, ReaderOptions{Schema: iox.SchemaOverride{Columns: []iox.ColumnOverride{
+		{Name: "id", Type: j.KindString, Rename: "key"},
+		{Name: "at", Type: j.KindTime},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = r.Close() }()
+	fr, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	col, ok := fr.ColumnByName("key")
+	if !ok {
+		t.Fatal("id should be renamed to key")
+	}
+	if v, _ := col.(*j.StringColumn).Get(0); v != "42" {
+		t.Fatalf("key[0] = %q", v)
+	}
+	col, _ = fr.ColumnByName("at")
+	if v, _ := col.(*j.TimeColumn).Get(0); !v.Equal(time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("at[0] = %v", v)
+	}
+	if !col.IsNull(1) {
+		t.Fatal("an unconvertible value should be null")
+	}
+}
+
+type frames struct{ got []*j.Frame }
+
+func (s *frames) Write(f *j.Frame) error { s.got = append(s.got, f); return nil }
+func (s *frames) Close() error           { return nil }
+
+func TestCastErrorPolicies(t *testing.T) {
+	s := j.Schema{Columns: []j.ColumnSchema{{Name: "at", Type: j.KindString, Nullable: true}}}
+	f := j.NewFrame(s)
+	for i, v := range []string{"2024-03-05", "soon", "2024-03-06"} {
+		f.AppendNullRow()
+		_ = f.SetCell(i, "at", v)
+	}
+	p := filepath.Join(t.TempDir(), "c.parquet")
+	if err := WriteAll(p, f, WriterOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	read := func(policy iox.CastPolicy, rejects j.ChunkSink) (*j.Frame, map[string]int, error) {
+		t.Helper()
+		opt := ReaderOptions{Schema: iox.SchemaOverride{Columns: []iox.ColumnOverride{{Name: "at", Type: j.KindTime}}},
+			OnCastError: policy, Rejects: rejects}
+		sr, err := NewStreamReader(p, opt, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = sr.Close() }()
+		out := j.NewFrame(sr.Schema())
+		for {
+			fr, err := sr.Next()
+			if err == io.EOF {
+				return out, sr.CastFailures(), nil
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := out.Append(fr); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	fr, counts, err := read(iox.CastNull, nil)
+	if err != nil || fr.Rows() != 3 || counts["at"] != 1 {
+		t.Fatalf("null: rows %d counts %v err %v", fr.Rows(), counts, err)
+	}
+	fr, _, err = read(iox.CastKeepRaw, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, ok := fr.ColumnByName("at" + iox.RawSuffix)
+	if v := j.Value(raw, 1); !ok || v != "soon" || j.Value(raw, 0) != nil {
+		t.Fatalf("keep_raw: at__raw = %v, %v", j.Value(raw, 0), v)
+	}
+	sink := &frames{}
+	if fr, _, err = read(iox.CastReject, sink); err != nil || fr.Rows() != 2 {
+		t.Fatalf("reject: rows %d err %v", fr.Rows(), err)
+	}
+	if len(sink.got) != 1 || sink.got[0].Rows() != 1 {
+		t.Fatalf("reject: wrote %v", sink.got)
+	}
+	if _, _, err = read(iox.CastFail, nil); err == nil || !strings.Contains(err.Error(), `"soon"`) {
+		t.Fatalf("fail: err %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package parquetio
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+func writeSample(t *testing.T) string {
+	t.Helper()
+	s := j.Schema{Columns: []j.ColumnSchema{
+		{Name: "x", Type: j.KindFloat, Nullable: true},
+		{Name: "n", Type: j.KindInt, Nullable: true},
+		{Name: "s", Type: j.KindString, Nullable: true},
+	}}
+	f := j.NewFrame(s)
+	for i := 0; i < 5; i++ {
+		f.AppendNullRow()
+		_ = f.SetCell(i, "x", float64(i)+0.5)
+		if i != 2 {
+			_ = f.SetCell(i, "n", int64(i*10))
+		}
+		_ = f.SetCell(i, "s", string(rune('a'+i)))
+	}
+	p := filepath.Join(t.TempDir(), "sample.parquet")
+	if err := WriteAll(p, f, WriterOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestReadAllRoundTrip(t *testing.T) {
+	p := writeSample(t)
+	r, err := OpenReader(p, ReaderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = r.Close() }()
+	fr, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fr.Rows() != 5 {
+		t.Fatalf("expected 5 rows, got %d", fr.Rows())
+	}
+	col, _ := fr.ColumnByName("n")
+	n := col.(*j.IntColumn)
+	if v, ok := n.Get(4); !ok || v != 40 {
+		t.Fatalf("n[4] = %v %v, want 40", v, ok)
+	}
+	if !n.IsNull(2) {
+		t.Fatal("n[2] should be null")
+	}
+	col, _ = fr.ColumnByName("s")
+	if v, _ := col.(*j.StringColumn).Get(1); v != "b" {
+		t.Fatalf("s[1] = %q, want b", v)
+	}
+}
+
+func TestStreamReadParquet(t *testing.T) {
+	p := writeSample(t)
+	sr, err := NewStreamReader(p, ReaderOptions{}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = sr.Close() }()
+	total := 0
+	for {
+		fr, err := sr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		col, _ := fr.ColumnByName("x")
+		if v, _ := col.(*j.FloatColumn).Get(0); v != float64(total)+0.5 {
+			t.Fatalf("row %d: x = %v", total, v)
+		}
+		total += fr.Rows()
+	}
+	if total != 5 {
+		t.Fatalf("expected 5 rows, got %d", total)
+	}
+}
+
+func TestStreamChunksFollowRowGroups(t *testing.T) {
+	s := j.Schema{Columns: []j.ColumnSchema{
+		{Name: "z", Type: j.KindInt, Nullable: true},
+		{Name: "a", Type: j.KindString, Nullable: true},
+	}}
+	p := filepath.Join(t.TempDir(), "groups.parquet")
+	w, err := NewStreamWriter(p, s, WriterOptions{RowGroupSize: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := 0
+	for _, n := range []int{4, 4, 2} {
+		f := j.NewFrame(s)
+		for i := 0; i < n; i++ {
+			f.AppendNullRow()
+			_ = f.SetCell(i, "z", int64(row))
+			row++
+		}
+		if err := w.Write(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		chunk int
+		sizes []int
+	}{{3, []int{3, 1, 3, 1, 2}}, {100, []int{4, 4, 2}}} {
+		sr, err := NewStreamReader(p, ReaderOptions{}, tc.chunk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sr.Schema().Columns; got[0].Name != "z" || got[1].Name != "a" {
+			t.Fatalf("column order not kept: %+v", got)
+		}
+		var sizes []int
+		next := int64(0)
+		for {
+			fr, err := sr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			col, _ := fr.ColumnByName("z")
+			for i := 0; i < fr.Rows(); i++ {
+				if v, _ := col.(*j.IntColumn).Get(i); v != next {
+					t.Fatalf("chunk %d: z = %d, want %d", tc.chunk, v, next)
+				}
+				next++
+			}
+			sizes = append(sizes, fr.Rows())
+		}
+		_ = sr.Close()
+		if fmt.Sprint(sizes) != fmt.Sprint(tc.sizes) {
+			t.Fatalf("chunk %d: sizes %v, want %v", tc.chunk, sizes, tc.sizes)
+		}
+	}
+}
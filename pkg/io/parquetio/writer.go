@@ -1,69 +1,286 @@
 package parquetio
 
 import (
-    "encoding/json"
     "fmt"
+    "io"
+    "math"
+    "os"
+    "reflect"
+    "strings"
+
+    parquet "github.com/parquet-go/parquet-go"
+    "github.com/parquet-go/parquet-go/compress"
+    "github.com/parquet-go/parquet-go/encoding"
 
     j "github.com/wdm0006/janitor/pkg/janitor"
-    pw "github.com/xitongsys/parquet-go/writer"
-    local "github.com/xitongsys/parquet-go-source/local"
 )
 
-func parquetSchemaJSON(s j.Schema) string {
-    // Build a minimal JSON schema for parquet-go JSONWriter
-    type field struct { Tag string `json:"Tag"` }
-    type schema struct {
-        Tag    string  `json:"Tag"`
-        Fields []field `json:"Fields"`
+// defaultRowGroupSize is the number of rows buffered before a row group is written.
+const defaultRowGroupSize = 128 * 1024
+
+// WriterOptions controls the physical layout of written files.
+type WriterOptions struct {
+    Compression  string                   // snappy (default), zstd, gzip or none
+    Columns      map[string]ColumnOptions // per-column type overrides
+    RowGroupSize int                      // rows buffered per row group (default 131072)
+}
+
+// ColumnOptions overrides the Parquet type a column is written as. Type is one
+// of "int32" (Int), "date" (Time, days), "timestamp_millis" (Time) or
+// "decimal" (Float, with Precision and Scale). By default Int is INT64, Float
+// DOUBLE, Time TIMESTAMP_MICROS and String a dictionary-encoded UTF8 column.
+type ColumnOptions struct {
+    Type      string `json:"type"`
+    Precision int    `json:"precision,omitempty"`
+    Scale     int    `json:"scale,omitempty"`
+}
+
+func compressionCodec(name string) (compress.Codec, error) {
+    switch strings.ToLower(name) {
+    case "", "snappy":
+        return &parquet.Snappy, nil
+    case "zstd":
+        return &parquet.Zstd, nil
+    case "gzip":
+        return &parquet.Gzip, nil
+    case "none", "uncompressed":
+        return &parquet.Uncompressed, nil
+    default:
+        return nil, fmt.Errorf("unknown parquet compression %q (want snappy, zstd, gzip or none)", name)
     }
-    sc := schema{Tag: "name=schema, repetitiontype=REQUIRED"}
-    for _, cs := range s.Columns {
-        tag := "name=" + cs.Name + ", repetitiontype=OPTIONAL, type="
-        switch cs.Type {
-        case j.KindFloat:
-            tag += "DOUBLE"
-        case j.KindInt:
-            tag += "INT64"
-        case j.KindBool:
-            tag += "BOOLEAN"
-        case j.KindString, j.KindTime:
-            tag += "BYTE_ARRAY, convertedtype=UTF8"
-        default:
-            tag += "BYTE_ARRAY, convertedtype=UTF8"
+}
+
+// encodeFunc appends the Parquet values of every row of col to vals. Values
+// carry definition levels (0 null, 1 present) and the leaf column index.
+type encodeFunc func(col j.Column, ci int, vals []parquet.Value) ([]parquet.Value, error)
+
+// columnNode returns the Parquet node for cs and the encoder for its values,
+// checking the override against the column's kind. Every column is OPTIONAL:
+// inferred schemas may miss nulls that appear in later chunks.
+func columnNode(cs j.ColumnSchema, co ColumnOptions) (parquet.Node, encodeFunc, error) {
+    bad := fmt.Errorf("parquet column %s: type %q not valid for this column's kind", cs.Name, co.Type)
+    switch cs.Type {
+    case j.KindFloat:
+        switch co.Type {
+        case "":
+            return parquet.Optional(parquet.Leaf(parquet.DoubleType)), encodeFloat(parquet.DoubleValue), nil
+        case "decimal":
+            if co.Precision <= 0 || co.Precision > 18 || co.Scale < 0 || co.Scale > co.Precision {
+                return nil, nil, fmt.Errorf("parquet column %s: decimal needs 0 < precision <= 18 and 0 <= scale <= precision", cs.Name)
+            }
+            return decimalNode(cs.Name, co)
+        }
+    case j.KindInt:
+        switch co.Type {
+        case "", "int64":
+            return parquet.Optional(parquet.Leaf(parquet.Int64Type)), encodeInt(parquet.Int64Value), nil
+        case "int32":
+            return parquet.Optional(parquet.Leaf(parquet.Int32Type)), func(col j.Column, ci int, vals []parquet.Value) ([]parquet.Value, error) {
+                c := col.(*j.IntColumn)
+                for i := 0; i < c.Len(); i++ {
+                    v, ok := c.Get(i)
+                    if !ok { vals = append(vals, parquet.NullValue().Level(0, 0, ci)); continue }
+                    if v < math.MinInt32 || v > math.MaxInt32 { return nil, fmt.Errorf("parquet column %s: %d overflows int32", cs.Name, v) }
+                    vals = append(vals, parquet.Int32Value(int32(v)).Level(0, 1, ci))
+                }
+                return vals, nil
+            }, nil
+        }
+    case j.KindBool:
+        if co.Type == "" {
+            return parquet.Optional(parquet.Leaf(parquet.BooleanType)), func(col j.Column, ci int, vals []parquet.Value) ([]parquet.Value, error) {
+                c := col.(*j.BoolColumn)
+                for i := 0; i < c.Len(); i++ {
+                    if v, ok := c.Get(i); ok { vals = append(vals, parquet.BooleanValue(v).Level(0, 1, ci)) } else { vals = append(vals, parquet.NullValue().Level(0, 0, ci)) }
+                }
+                return vals, nil
+            }, nil
+        }
+    case j.KindTime:
+        switch co.Type {
+        case "", "timestamp_micros":
+            return parquet.Optional(parquet.Timestamp(parquet.Microsecond)), encodeTime(func(c *j.TimeColumn, i int) parquet.Value { v, _ := c.Get(i); return parquet.Int64Value(v.UnixMicro()) }), nil
+        case "timestamp_millis":
+            return parquet.Optional(parquet.Timestamp(parquet.Millisecond)), encodeTime(func(c *j.TimeColumn, i int) parquet.Value { v, _ := c.Get(i); return parquet.Int64Value(v.UnixMilli()) }), nil
+        case "date":
+            return parquet.Optional(parquet.Date()), encodeTime(func(c *j.TimeColumn, i int) parquet.Value {
+                v, _ := c.Get(i)
+                return parquet.Int32Value(int32(math.Floor(float64(v.Unix()) / 86400)))
+            }), nil
+        }
+    default:
+        if co.Type == "" {
+            return parquet.Optional(parquet.Encoded(parquet.String(), &parquet.RLEDictionary)), func(col j.Column, ci int, vals []parquet.Value) ([]parquet.Value, error) {
+                c := col.(*j.StringColumn)
+                for i := 0; i < c.Len(); i++ {
+                    if v, ok := c.Get(i); ok { vals = append(vals, parquet.ByteArrayValue([]byte(v)).Level(0, 1, ci)) } else { vals = append(vals, parquet.NullValue().Level(0, 0, ci)) }
+                }
+                return vals, nil
+            }, nil
         }
-        sc.Fields = append(sc.Fields, field{Tag: tag})
     }
-    b, _ := json.Marshal(sc)
-    return string(b)
+    return nil, nil, bad
 }
 
-// WriteAll writes a Frame to a Parquet file using parquet-go JSONWriter.
-func WriteAll(path string, f *j.Frame) error {
-    fw, err := local.NewLocalFileWriter(path)
-    if err != nil { return err }
-    schema := parquetSchemaJSON(f.Schema())
-    writer, err := pw.NewJSONWriter(schema, fw, 4)
-    if err != nil { _ = fw.Close(); return fmt.Errorf("parquet writer init: %w", err) }
-    defer func() { _ = writer.WriteStop(); _ = fw.Close() }()
-    // write rows
-    for r := 0; r < f.Rows(); r++ {
-        rec := make(map[string]any, len(f.Schema().Columns))
-        for _, cs := range f.Schema().Columns {
-            col, _ := f.ColumnByName(cs.Name)
-            switch cs.Type {
-            case j.KindFloat:
-                if v, ok := col.(*j.FloatColumn).Get(r); ok { rec[cs.Name] = v }
-            case j.KindInt:
-                if v, ok := col.(*j.IntColumn).Get(r); ok { rec[cs.Name] = v }
-            case j.KindBool:
-                if v, ok := col.(*j.BoolColumn).Get(r); ok { rec[cs.Name] = v }
-            case j.KindString:
-                if v, ok := col.(*j.StringColumn).Get(r); ok { rec[cs.Name] = v }
-            case j.KindTime:
-                if v, ok := col.(*j.TimeColumn).Get(r); ok { rec[cs.Name] = v.Format("2006-01-02T15:04:05Z07:00") }
+func encodeFloat(mk func(float64) parquet.Value) encodeFunc {
+    return func(col j.Column, ci int, vals []parquet.Value) ([]parquet.Value, error) {
+        c := col.(*j.FloatColumn)
+        for i := 0; i < c.Len(); i++ {
+            if v, ok := c.Get(i); ok { vals = append(vals, mk(v).Level(0, 1, ci)) } else { vals = append(vals, parquet.NullValue().Level(0, 0, ci)) }
+        }
+        return vals, nil
+    }
+}
+
+func encodeInt(mk func(int64) parquet.Value) encodeFunc {
+    return func(col j.Column, ci int, vals []parquet.Value) ([]parquet.Value, error) {
+        c := col.(*j.IntColumn)
+        for i := 0; i < c.Len(); i++ {
+            if v, ok := c.Get(i); ok { vals = append(vals, mk(v).Level(0, 1, ci)) } else { vals = append(vals, parquet.NullValue().Level(0, 0, ci)) }
+        }
+        return vals, nil
+    }
+}
+
+func encodeTime(mk func(c *j.TimeColumn, i int) parquet.Value) encodeFunc {
+    return func(col j.Column, ci int, vals []parquet.Value) ([]parquet.Value, error) {
+        c := col.(*j.TimeColumn)
+        for i := 0; i < c.Len(); i++ {
+            if c.IsNull(i) { vals = append(vals, parquet.NullValue().Level(0, 0, ci)) } else { vals = append(vals, mk(c, i).Level(0, 1, ci)) }
+        }
+        return vals, nil
+    }
+}
+
+// decimalNode stores floats as unscaled integers, INT32 up to precision 9.
+func decimalNode(name string, co ColumnOptions) (parquet.Node, encodeFunc, error) {
+    typ := parquet.Type(parquet.Int64Type)
+    if co.Precision <= 9 { typ = parquet.Int32Type }
+    scale, limit := math.Pow10(co.Scale), math.Pow10(co.Precision)
+    enc := func(col j.Column, ci int, vals []parquet.Value) ([]parquet.Value, error) {
+        c := col.(*j.FloatColumn)
+        for i := 0; i < c.Len(); i++ {
+            v, ok := c.Get(i)
+            if !ok { vals = append(vals, parquet.NullValue().Level(0, 0, ci)); continue }
+            u := math.Round(v * scale)
+            if math.IsNaN(u) || math.Abs(u) >= limit {
+                return nil, fmt.Errorf("parquet column %s: %v does not fit decimal(%d,%d)", name, v, co.Precision, co.Scale)
             }
+            if co.Precision <= 9 { vals = append(vals, parquet.Int32Value(int32(u)).Level(0, 1, ci)) } else { vals = append(vals, parquet.Int64Value(int64(u)).Level(0, 1, ci)) }
         }
-        if err := writer.Write(rec); err != nil { return fmt.Errorf("parquet write row: %w", err) }
+        return vals, nil
     }
+    return parquet.Optional(parquet.Decimal(co.Scale, co.Precision, typ)), enc, nil
+}
+
+// columnGroup is the root node of a written file. Unlike parquet.Group, which
+// sorts its fields by name, it keeps the Frame's column order.
+type columnGroup []parquet.Field
+
+type columnField struct {
+    parquet.Node
+    name string
+}
+
+func (f columnField) Name() string { return f.name }
+
+func (f columnField) Value(base reflect.Value) reflect.Value { return base.MapIndex(reflect.ValueOf(f.name)) }
+
+func (g columnGroup) String() string {
+    var b strings.Builder
+    b.WriteString("message {")
+    for _, f := range g { fmt.Fprintf(&b, " %s;", f.Name()) }
+    b.WriteString(" }")
+    return b.String()
+}
+
+func (g columnGroup) ID() int                     { return 0 }
+func (g columnGroup) Type() parquet.Type          { return parquet.Group{}.Type() }
+func (g columnGroup) Optional() bool              { return false }
+func (g columnGroup) Repeated() bool              { return false }
+func (g columnGroup) Required() bool              { return true }
+func (g columnGroup) Leaf() bool                  { return false }
+func (g columnGroup) Fields() []parquet.Field     { return g }
+func (g columnGroup) Encoding() encoding.Encoding { return nil }
+func (g columnGroup) Compression() compress.Codec { return nil }
+func (g columnGroup) GoType() reflect.Type        { return reflect.TypeOf(map[string]any{}) }
+
+// WriteAll writes a Frame to a Parquet file.
+func WriteAll(path string, f *j.Frame, opt WriterOptions) error {
+    w, err := NewStreamWriter(path, f.Schema(), opt)
+    if err != nil { return err }
+    if err := w.Write(f); err != nil { _ = w.Close(); return err }
+    return w.Close()
+}
+
+// StreamWriter writes Frames to a Parquet file incrementally. Frames are
+// buffered column by column and written out as row groups of about
+// WriterOptions.RowGroupSize rows.
+type StreamWriter struct {
+    file    io.WriteCloser
+    writer  *parquet.Writer
+    buf     *parquet.Buffer
+    names   []string
+    encode  []encodeFunc
+    vals    []parquet.Value
+    rgSize  int
+}
+
+// NewStreamWriter creates path and writes frames with the given schema to it.
+func NewStreamWriter(path string, schema j.Schema, opt WriterOptions) (*StreamWriter, error) {
+    codec, err := compressionCodec(opt.Compression)
+    if err != nil { return nil, err }
+    s := &StreamWriter{rgSize: opt.RowGroupSize}
+    if s.rgSize <= 0 { s.rgSize = defaultRowGroupSize }
+    var root columnGroup
+    for _, cs := range schema.Columns {
+        node, enc, err := columnNode(cs, opt.Columns[cs.Name])
+        if err != nil { return nil, err }
+        root = append(root, columnField{Node: node, name: cs.Name})
+        s.names = append(s.names, cs.Name)
+        s.encode = append(s.encode, enc)
+    }
+    ps := parquet.NewSchema("schema", root)
+    if path == "-" || path == "" {
+        s.file = stdout{}
+    } else if s.file, err = os.Create(path); err != nil {
+        return nil, err
+    }
+    s.writer = parquet.NewWriter(s.file, ps, parquet.Compression(codec))
+    s.buf = parquet.NewBuffer(ps)
+    return s, nil
+}
+
+func (s *StreamWriter) Write(fr *j.Frame) error {
+    cols := s.buf.ColumnBuffers()
+    for ci, name := range s.names {
+        col, ok := fr.ColumnByName(name)
+        if !ok { return fmt.Errorf("parquet write: frame has no column %s", name) }
+        var err error
+        s.vals, err = s.encode[ci](col, ci, s.vals[:0])
+        if err != nil { return err }
+        if _, err := cols[ci].WriteValues(s.vals); err != nil { return fmt.Errorf("parquet write column %s: %w", name, err) }
+    }
+    if s.buf.NumRows() >= int64(s.rgSize) { return s.flush() }
     return nil
 }
+
+func (s *StreamWriter) flush() error {
+    if s.buf.NumRows() == 0 { return nil }
+    if _, err := s.writer.WriteRowGroup(s.buf); err != nil { return fmt.Errorf("parquet write row group: %w", err) }
+    s.buf.Reset()
+    return nil
+}
+
+// stdout is the destination of a "-" path; Close leaves stdout open.
+type stdout struct{}
+
+func (stdout) Write(b []byte) (int, error) { return os.Stdout.Write(b) }
+func (stdout) Close() error                { return nil }
+
+func (s *StreamWriter) Close() error {
+    err := s.flush()
+    if cerr := s.writer.Close(); err == nil && cerr != nil { err = fmt.Errorf("parquet write footer: %w", cerr) }
+    if cerr := s.file.Close(); err == nil { err = cerr }
+    return err
+}
@@ -0,0 +1,177 @@
+package janitor
+
+import "fmt"
+
+// NewNullColumn returns a column of the given kind holding n nulls.
+func NewNullColumn(name string, kind Kind, n int) Column {
+	c := newColumn(ColumnSchema{Name: name, Type: kind})
+	for i := 0; i < n; i++ {
+		switch col := c.(type) {
+		case *BoolColumn:
+			col.AppendNull()
+		case *IntColumn:
+			col.AppendNull()
+		case *FloatColumn:
+			col.AppendNull()
+		case *StringColumn:
+			col.AppendNull()
+		case *TimeColumn:
+			col.AppendNull()
+		}
+	}
+	return c
+}
+
+// Value returns row i of c as a bool, int64, float64, string or time.Time,
+// or nil when it is null.
+func Value(c Column, i int) any {
+	if c.IsNull(i) {
+		return nil
+	}
+	switch col := c.(type) {
+	case *BoolColumn:
+		return col.data[i]
+	case *IntColumn:
+		return col.data[i]
+	case *FloatColumn:
+		return col.data[i]
+	case *StringColumn:
+		return col.data[i]
+	case *TimeColumn:
+		return col.data[i]
+	}
+	return nil
+}
+
+// AddColumn appends c as the column described by cs. c must be of kind
+// cs.Type and, unless f has no columns yet, have f.Rows() rows; it takes the
+// name cs.Name.
+func (f *Frame) AddColumn(cs ColumnSchema, c Column) error {
+	if _, ok := f.index[cs.Name]; ok {
+		return fmt.Errorf("column %q already exists", cs.Name)
+	}
+	if c.Kind() != cs.Type {
+		return fmt.Errorf("column %q: got a %s column, schema says %s", cs.Name, c.Kind(), cs.Type)
+	}
+	if len(f.cols) == 0 {
+		f.nrows = c.Len()
+	} else if c.Len() != f.nrows {
+		return fmt.Errorf("column %q has %d rows, want %d", cs.Name, c.Len(), f.nrows)
+	}
+	f.addColumn(cs, renamed(c, cs.Name))
+	return nil
+}
+
+// DropColumn removes the named column.
+func (f *Frame) DropColumn(name string) error {
+	i, ok := f.index[name]
+	if !ok {
+		return fmt.Errorf("unknown column: %s", name)
+	}
+	cols := append([]ColumnSchema(nil), f.schema.Columns[:i]...)
+	f.schema.Columns = append(cols, f.schema.Columns[i+1:]...)
+	f.cols = append(f.cols[:i:i], f.cols[i+1:]...)
+	f.reindex()
+	return nil
+}
+
+// RenameColumn renames a column in place.
+func (f *Frame) RenameColumn(old, name string) error {
+	i, ok := f.index[old]
+	if !ok {
+		return fmt.Errorf("unknown column: %s", old)
+	}
+	if old == name {
+		return nil
+	}
+	if _, ok := f.index[name]; ok {
+		return fmt.Errorf("column %q already exists", name)
+	}
+	cols := append([]ColumnSchema(nil), f.schema.Columns...)
+	cols[i].Name = name
+	f.schema.Columns = cols
+	f.cols[i] = renamed(f.cols[i], name)
+	f.reindex()
+	return nil
+}
+
+// ReplaceColumn puts c, described by cs, in the place of the named column.
+// c must be of kind cs.Type and have f.Rows() rows; cs.Name may differ from
+// name but not collide with another column.
+func (f *Frame) ReplaceColumn(name string, cs ColumnSchema, c Column) error {
+	i, ok := f.index[name]
+	if !ok {
+		return fmt.Errorf("unknown column: %s", name)
+	}
+	if k, ok := f.index[cs.Name]; ok && k != i {
+		return fmt.Errorf("column %q already exists", cs.Name)
+	}
+	if c.Kind() != cs.Type {
+		return fmt.Errorf("column %q: got a %s column, schema says %s", cs.Name, c.Kind(), cs.Type)
+	}
+	if c.Len() != f.nrows {
+		return fmt.Errorf("column %q has %d rows, want %d", cs.Name, c.Len(), f.nrows)
+	}
+	cols := append([]ColumnSchema(nil), f.schema.Columns...)
+	cols[i] = cs
+	f.schema.Columns = cols
+	f.cols[i] = renamed(c, cs.Name)
+	f.reindex()
+	return nil
+}
+
+// Select returns a Frame with the named columns in the given order. The
+// column data is shared with f, not copied; renaming a column of either
+// frame does not affect the other.
+func (f *Frame) Select(names ...string) (*Frame, error) {
+	out := &Frame{index: make(map[string]int, len(names)), nrows: f.nrows}
+	for _, name := range names {
+		i, ok := f.index[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown column: %s", name)
+		}
+		if _, dup := out.index[name]; dup {
+			return nil, fmt.Errorf("column %q selected twice", name)
+		}
+		out.addColumn(f.schema.Columns[i], f.cols[i])
+	}
+	return out, nil
+}
+
+func (f *Frame) reindex() {
+	f.index = make(map[string]int, len(f.cols))
+	for i, cs := range f.schema.Columns {
+		f.index[cs.Name] = i
+	}
+}
+
+// renamed returns a copy of c's header under a new name. The data is shared
+// with c, so frames that alias c keep seeing the old name.
+func renamed(c Column, name string) Column {
+	if c.Name() == name {
+		return c
+	}
+	switch col := c.(type) {
+	case *BoolColumn:
+		cp := *col
+		cp.name = name
+		return &cp
+	case *IntColumn:
+		cp := *col
+		cp.name = name
+		return &cp
+	case *FloatColumn:
+		cp := *col
+		cp.name = name
+		return &cp
+	case *StringColumn:
+		cp := *col
+		cp.name = name
+		return &cp
+	case *TimeColumn:
+		cp := *col
+		cp.name = name
+		return &cp
+	}
+	return c
+}
@@ -0,0 +1,80 @@
+package janitor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFrameColumnOps(t *testing.T) {
+	f := NewFrame(Schema{Columns: []ColumnSchema{
+		{Name: "a", Type: KindInt},
+		{Name: "b", Type: KindString, Nullable: true},
+		{Name: "c", Type: KindFloat},
+	}})
+	f.AppendNullRow()
+	f.AppendNullRow()
+
+	if err := f.AddColumn(ColumnSchema{Name: "d", Type: KindBool, Nullable: true}, NewNullColumn("x", KindBool, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := f.ColumnByName("d"); !ok || c.Name() != "d" {
+		t.Fatal("added column not found under its schema name")
+	}
+	if err := f.AddColumn(ColumnSchema{Name: "e", Type: KindBool}, NewNullColumn("e", KindBool, 3)); err == nil {
+		t.Fatal("expected an error for a length mismatch")
+	}
+	if err := f.AddColumn(ColumnSchema{Name: "a", Type: KindInt}, NewNullColumn("a", KindInt, 2)); err == nil {
+		t.Fatal("expected an error for a duplicate name")
+	}
+
+	if err := f.DropColumn("c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.RenameColumn("b", "name"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.RenameColumn("a", "d"); err == nil {
+		t.Fatal("expected an error renaming onto an existing column")
+	}
+	if err := f.ReplaceColumn("a", ColumnSchema{Name: "a", Type: KindString, Nullable: true}, NewNullColumn("", KindString, 2)); err != nil {
+		t.Fatal(err)
+	}
+	want := []ColumnSchema{
+		{Name: "a", Type: KindString, Nullable: true},
+		{Name: "name", Type: KindString, Nullable: true},
+		{Name: "d", Type: KindBool, Nullable: true},
+	}
+	if got := f.Schema().Columns; !reflect.DeepEqual(got, want) {
+		t.Fatalf("schema = %+v, want %+v", got, want)
+	}
+	if c, _ := f.ColumnByName("name"); c.Name() != "name" {
+		t.Fatalf("renamed column reports name %q", c.Name())
+	}
+	if err := f.SetCell(1, "name", "x"); err != nil {
+		t.Fatal(err)
+	}
+
+	sel, err := f.Select("d", "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sel.Schema().Names(); !reflect.DeepEqual(got, []string{"d", "name"}) || sel.Rows() != 2 {
+		t.Fatalf("select = %v with %d rows", got, sel.Rows())
+	}
+	c, _ := sel.ColumnByName("name")
+	if Value(c, 0) != nil || Value(c, 1) != "x" {
+		t.Fatalf("selected values = %v, %v", Value(c, 0), Value(c, 1))
+	}
+	if err := sel.RenameColumn("name", "label"); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := f.ColumnByName("name"); c.Name() != "name" {
+		t.Fatalf("renaming a selected column renamed the source to %q", c.Name())
+	}
+	if c, _ := sel.ColumnByName("label"); c.Name() != "label" || Value(c, 1) != "x" {
+		t.Fatalf("renamed selected column = %q, %v", c.Name(), Value(c, 1))
+	}
+	if _, err := f.Select("nope"); err == nil {
+		t.Fatal("expected an error selecting a missing column")
+	}
+}
@@ -5,15 +5,16 @@ import (
 	"time"
 )
 
-// Schema describes the logical shape of a dataset.
+// Schema describes the logical shape of a dataset. Its JSON form is
+// {"columns": [{"name": ..., "type": "int", "nullable": true}, ...]}.
 type Schema struct {
-	Columns []ColumnSchema
+	Columns []ColumnSchema `json:"columns"`
 }
 
 type ColumnSchema struct {
-	Name     string
-	Type     Kind
-	Nullable bool
+	Name     string `json:"name"`
+	Type     Kind   `json:"type"`
+	Nullable bool   `json:"nullable"`
 }
 
 // Kind enumerates supported logical types.
@@ -149,25 +150,52 @@ type Frame struct {
 func NewFrame(s Schema) *Frame {
 	f := &Frame{schema: s, cols: make([]Column, len(s.Columns)), index: make(map[string]int)}
 	for i, cs := range s.Columns {
-		switch cs.Type {
-		case KindBool:
-			f.cols[i] = NewBoolColumn(cs.Name, 0)
-		case KindInt:
-			f.cols[i] = NewIntColumn(cs.Name, 0)
-		case KindFloat:
-			f.cols[i] = NewFloatColumn(cs.Name, 0)
-		case KindString:
-			f.cols[i] = NewStringColumn(cs.Name, 0)
-		case KindTime:
-			f.cols[i] = NewTimeColumn(cs.Name, 0)
-		default:
-			panic("invalid column kind")
-		}
+		f.cols[i] = newColumn(cs)
 		f.index[cs.Name] = i
 	}
 	return f
 }
 
+// NewFrameFromColumns builds a Frame around typed columns, which must match s
+// in order, name and kind and share one length. The columns are not copied.
+func NewFrameFromColumns(s Schema, cols []Column) (*Frame, error) {
+	if len(cols) != len(s.Columns) {
+		return nil, fmt.Errorf("schema has %d columns, got %d", len(s.Columns), len(cols))
+	}
+	f := &Frame{schema: s, cols: cols, index: make(map[string]int)}
+	for i, cs := range s.Columns {
+		c := cols[i]
+		if c.Name() != cs.Name || c.Kind() != cs.Type {
+			return nil, fmt.Errorf("column %d: got %s (kind %d), schema has %s (kind %d)", i, c.Name(), c.Kind(), cs.Name, cs.Type)
+		}
+		if i == 0 {
+			f.nrows = c.Len()
+		} else if c.Len() != f.nrows {
+			return nil, fmt.Errorf("column %s has %d rows, want %d", cs.Name, c.Len(), f.nrows)
+		}
+		f.index[cs.Name] = i
+	}
+	return f, nil
+}
+
+// newColumn returns an empty column for cs.
+func newColumn(cs ColumnSchema) Column {
+	switch cs.Type {
+	case KindBool:
+		return NewBoolColumn(cs.Name, 0)
+	case KindInt:
+		return NewIntColumn(cs.Name, 0)
+	case KindFloat:
+		return NewFloatColumn(cs.Name, 0)
+	case KindString:
+		return NewStringColumn(cs.Name, 0)
+	case KindTime:
+		return NewTimeColumn(cs.Name, 0)
+	default:
+		panic("invalid column kind")
+	}
+}
+
 func (f *Frame) Schema() Schema { return f.schema }
 func (f *Frame) Rows() int      { return f.nrows }
 func (f *Frame) Cols() int      { return len(f.cols) }
@@ -276,3 +304,60 @@ func (f *Frame) SetCell(row int, name string, v any) error {
 	}
 	return nil
 }
+
+// Take returns a new Frame holding the given rows of f, in order.
+func (f *Frame) Take(rows []int) *Frame {
+	out := &Frame{
+		schema: Schema{Columns: append([]ColumnSchema(nil), f.schema.Columns...)},
+		cols:   make([]Column, len(f.cols)),
+		index:  make(map[string]int, len(f.index)),
+		nrows:  len(rows),
+	}
+	for name, i := range f.index {
+		out.index[name] = i
+	}
+	for i, c := range f.cols {
+		switch col := c.(type) {
+		case *BoolColumn:
+			nc := NewBoolColumn(col.name, len(rows))
+			for k, r := range rows {
+				nc.data[k], nc.nulls[k] = col.data[r], col.nulls[r]
+			}
+			out.cols[i] = nc
+		case *IntColumn:
+			nc := NewIntColumn(col.name, len(rows))
+			for k, r := range rows {
+				nc.data[k], nc.nulls[k] = col.data[r], col.nulls[r]
+			}
+			out.cols[i] = nc
+		case *FloatColumn:
+			nc := NewFloatColumn(col.name, len(rows))
+			for k, r := range rows {
+				nc.data[k], nc.nulls[k] = col.data[r], col.nulls[r]
+			}
+			out.cols[i] = nc
+		case *StringColumn:
+			nc := NewStringColumn(col.name, len(rows))
+			for k, r := range rows {
+				nc.data[k], nc.nulls[k] = col.data[r], col.nulls[r]
+			}
+			out.cols[i] = nc
+		case *TimeColumn:
+			nc := NewTimeColumn(col.name, len(rows))
+			for k, r := range rows {
+				nc.data[k], nc.nulls[k] = col.data[r], col.nulls[r]
+			}
+			out.cols[i] = nc
+		default:
+			panic("unknown column type")
+		}
+	}
+	return out
+}
+
+// addColumn appends c to f; c must have f.Rows() rows.
+func (f *Frame) addColumn(cs ColumnSchema, c Column) {
+	f.schema.Columns = append(f.schema.Columns, cs)
+	f.cols = append(f.cols, c)
+	f.index[cs.Name] = len(f.cols) - 1
+}
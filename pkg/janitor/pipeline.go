@@ -1,6 +1,10 @@
 package janitor
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"io"
+)
 
 // Transform is a mutation or validation applied to a Frame.
 // Streaming versions will operate on chunks; this is a simple placeholder.
@@ -9,9 +13,53 @@ type Transform interface {
 	Apply(ctx context.Context, f *Frame) (*Frame, error)
 }
 
+// Fitter is a Transform whose Apply depends on statistics learned from data
+// (imputers, scalers). Fit may be called once per chunk and accumulates across
+// calls; once fitted, Apply fills using the accumulated, dataset-wide state
+// instead of statistics computed from the frame it is given.
+type Fitter interface {
+	Transform
+	Fit(ctx context.Context, f *Frame) error
+}
+
+// Reshaper is a Transform that changes the columns of the frames it returns.
+// OutputSchema returns the schema Apply produces from a frame of schema in, or
+// an error when the step cannot apply to it.
+type Reshaper interface {
+	Transform
+	OutputSchema(in Schema) (Schema, error)
+}
+
+// SchemaChecker is a Transform that can tell, before any data is read,
+// whether it does its work on frames of a schema: its columns exist and have
+// a kind it supports, and its parameters are set. Steps skip frames they do
+// not apply to, so CheckSchema is what tells those no-ops from real work.
+type SchemaChecker interface {
+	Transform
+	CheckSchema(in Schema) error
+}
+
+// Resetter is a Transform that carries state from one Apply to the next, such
+// as the keys a deduplicating step has seen. FinishFit resets the steps
+// applied during the fitting pass, so the transform pass starts fresh.
+type Resetter interface {
+	Transform
+	Reset()
+}
+
+// FitFinisher is a Fitter that retains data while fitting, such as the values
+// a median is taken from. Pipeline.FinishFit lets it reduce them to the
+// statistics Apply uses once the fitting pass is over.
+type FitFinisher interface {
+	Fitter
+	FinishFit()
+}
+
 // Pipeline composes a sequence of Transforms.
 type Pipeline struct {
-	steps []Transform
+	steps       []Transform
+	rejects     ChunkSink
+	onViolation []ViolationFunc
 }
 
 func NewPipeline() *Pipeline { return &Pipeline{} }
@@ -24,11 +72,185 @@ func (p *Pipeline) Add(t Transform) *Pipeline {
 func (p *Pipeline) Run(ctx context.Context, f *Frame) (*Frame, error) {
 	var err error
 	cur := f
+	for i, t := range p.steps {
+		cur, err = p.apply(ctx, i, t, cur, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return cur, nil
+}
+
+// apply runs one step, routing Validators through check.
+func (p *Pipeline) apply(ctx context.Context, step int, t Transform, f *Frame, emit bool) (*Frame, error) {
+	if v, ok := t.(Validator); ok {
+		return p.check(ctx, step, v, f, emit)
+	}
+	return t.Apply(ctx, f)
+}
+
+// OutputSchema returns the schema of the frames Run produces from frames of
+// schema in. Steps that are not Reshapers keep the schema they are given.
+func (p *Pipeline) OutputSchema(in Schema) (Schema, error) {
+	out := in
+	for _, t := range p.steps {
+		r, ok := t.(Reshaper)
+		if !ok {
+			continue
+		}
+		var err error
+		if out, err = r.OutputSchema(out); err != nil {
+			return Schema{}, fmt.Errorf("%s: %w", t.Name(), err)
+		}
+	}
+	return out, nil
+}
+
+// StepError is a problem Pipeline.Validate found in one step.
+type StepError struct {
+	Step int // index of the step in the pipeline
+	Name string
+	Err  error
+	// Fatal is set when the step cannot run on the schema (its OutputSchema
+	// failed); otherwise the step would run but skip its work.
+	Fatal bool
+}
+
+func (e *StepError) Error() string { return fmt.Sprintf("step %d (%s): %v", e.Step, e.Name, e.Err) }
+
+func (e *StepError) Unwrap() error { return e.Err }
+
+// Validate checks every step against the schema it will see, the input
+// schema in as changed by the steps before it, and returns every problem
+// found. A step whose OutputSchema fails passes its input schema on, so the
+// steps after it are still checked.
+func (p *Pipeline) Validate(in Schema) []*StepError {
+	var errs []*StepError
+	cur := in
+	for i, t := range p.steps {
+		if c, ok := t.(SchemaChecker); ok {
+			if err := c.CheckSchema(cur); err != nil {
+				errs = append(errs, &StepError{Step: i, Name: t.Name(), Err: err})
+			}
+		}
+		if r, ok := t.(Reshaper); ok {
+			out, err := r.OutputSchema(cur)
+			if err != nil {
+				errs = append(errs, &StepError{Step: i, Name: t.Name(), Err: err, Fatal: true})
+				continue
+			}
+			cur = out
+		}
+	}
+	return errs
+}
+
+// Close releases resources held by steps, such as spill files, by closing
+// every step that implements io.Closer.
+func (p *Pipeline) Close() error {
+	var first error
+	for _, t := range p.steps {
+		if c, ok := t.(io.Closer); ok {
+			if err := c.Close(); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+// NeedsFit reports whether any step in the pipeline is a Fitter.
+func (p *Pipeline) NeedsFit() bool {
 	for _, t := range p.steps {
-		cur, err = t.Apply(ctx, cur)
+		if _, ok := t.(Fitter); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// FitRun fits every Fitter on the output of the steps before it and then
+// applies it, so a single call over a full frame is an exact fit-and-transform.
+// Repeated calls accumulate statistics; each returned frame reflects the
+// statistics seen so far.
+func (p *Pipeline) FitRun(ctx context.Context, f *Frame) (*Frame, error) {
+	var err error
+	cur := f
+	for i, t := range p.steps {
+		if ft, ok := t.(Fitter); ok {
+			if err := ft.Fit(ctx, cur); err != nil {
+				return nil, err
+			}
+		}
+		cur, err = p.apply(ctx, i, t, cur, true)
 		if err != nil {
 			return nil, err
 		}
 	}
 	return cur, nil
 }
+
+// FinishFit ends a fitting pass, after its last FitStream call. Steps
+// applied during the pass are reset, so the transform pass starts fresh, and
+// FitFinishers reduce what they retained. Fitting again afterwards continues
+// from the reduced statistics, as after Load.
+func (p *Pipeline) FinishFit() {
+	last := p.lastFitter()
+	for i, t := range p.steps {
+		if r, ok := t.(Resetter); ok && i < last {
+			r.Reset()
+		}
+		if ff, ok := t.(FitFinisher); ok {
+			ff.FinishFit()
+		}
+	}
+}
+
+// lastFitter returns the index of the last Fitter, or -1.
+func (p *Pipeline) lastFitter() int {
+	last := -1
+	for i, t := range p.steps {
+		if _, ok := t.(Fitter); ok {
+			last = i
+		}
+	}
+	return last
+}
+
+// FitStream makes a fitting pass over src, feeding each chunk through the
+// pipeline up to its last Fitter and discarding the result. Afterwards the
+// pipeline can be run over a fresh source with RunStream, and every chunk is
+// transformed with the same dataset-wide statistics. Chunks are modified in
+// place as in Run; quarantined rows are left out of the fit but are not written
+// to the reject sink until the transform pass. Several sources are fitted as
+// one dataset by calling FitStream for each and then FinishFit once.
+func (p *Pipeline) FitStream(ctx context.Context, src ChunkSource) error {
+	last := p.lastFitter()
+	if last < 0 {
+		return nil
+	}
+	for {
+		f, err := src.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		cur := f
+		for i, t := range p.steps[:last+1] {
+			if ft, ok := t.(Fitter); ok {
+				if err := ft.Fit(ctx, cur); err != nil {
+					return err
+				}
+			}
+			if i == last {
+				break
+			}
+			cur, err = p.apply(ctx, i, t, cur, false)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
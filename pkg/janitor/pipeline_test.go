@@ -3,8 +3,14 @@ package janitor_test
 import (
 	"context"
 	j "github.com/wdm0006/janitor/pkg/janitor"
+	cols "github.com/wdm0006/janitor/pkg/transform/columns"
 	imp "github.com/wdm0006/janitor/pkg/transform/impute"
+	rows "github.com/wdm0006/janitor/pkg/transform/rows"
 	std "github.com/wdm0006/janitor/pkg/transform/standardize"
+	val "github.com/wdm0006/janitor/pkg/transform/validate"
+	"io"
+	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -35,3 +41,252 @@ func TestPipeline(t *testing.T) {
 		t.Fatalf("trim failed, got %q", s0)
 	}
 }
+
+type sliceSource struct{ frames []*j.Frame }
+
+func (s *sliceSource) Next() (*j.Frame, error) {
+	if len(s.frames) == 0 {
+		return nil, io.EOF
+	}
+	f := s.frames[0]
+	s.frames = s.frames[1:]
+	return f, nil
+}
+
+func TestFitStreamIndependentOfChunking(t *testing.T) {
+	s := j.Schema{Columns: []j.ColumnSchema{{Name: "x", Type: j.KindFloat, Nullable: true}}}
+	chunks := func() []*j.Frame {
+		var out []*j.Frame
+		for _, vals := range [][]any{{1.0, nil}, {5.0, 6.0}} {
+			f := j.NewFrame(s)
+			for i, v := range vals {
+				f.AppendNullRow()
+				_ = f.SetCell(i, "x", v)
+			}
+			out = append(out, f)
+		}
+		return out
+	}
+	p := j.NewPipeline().Add(&imp.Mean{Column: "x"})
+	if !p.NeedsFit() {
+		t.Fatal("pipeline with an imputer should need fitting")
+	}
+	if err := p.FitStream(context.Background(), &sliceSource{frames: chunks()}); err != nil {
+		t.Fatal(err)
+	}
+	first := chunks()[0]
+	out, err := p.Run(context.Background(), first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	col, _ := out.ColumnByName("x")
+	if v, _ := col.(*j.FloatColumn).Get(1); v != 4 {
+		t.Fatalf("expected dataset-wide mean 4, got %v", v)
+	}
+}
+
+func TestFitStreamSeveralSources(t *testing.T) {
+	s := j.Schema{Columns: []j.ColumnSchema{{Name: "id", Type: j.KindInt}, {Name: "x", Type: j.KindFloat, Nullable: true}}}
+	frame := func(ids []int64, xs []any) *j.Frame {
+		f := j.NewFrame(s)
+		for i := range ids {
+			f.AppendNullRow()
+			_ = f.SetCell(i, "id", ids[i])
+			_ = f.SetCell(i, "x", xs[i])
+		}
+		return f
+	}
+	// id 1 in the second source is a duplicate; the kept rows have x 0, 0 and null
+	sources := func() [][]*j.Frame {
+		return [][]*j.Frame{
+			{frame([]int64{1, 2}, []any{0.0, 0.0})},
+			{frame([]int64{1, 3}, []any{100.0, nil})},
+		}
+	}
+	p := j.NewPipeline().Add(&rows.Dedupe{Columns: []string{"id"}}).Add(&imp.Mean{Column: "x"})
+	defer func() { _ = p.Close() }()
+	for _, src := range sources() {
+		if err := p.FitStream(context.Background(), &sliceSource{frames: src}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p.FinishFit()
+	var xs []any
+	for _, src := range sources() {
+		out, err := p.Run(context.Background(), src[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		col, _ := out.ColumnByName("x")
+		for i := 0; i < out.Rows(); i++ {
+			xs = append(xs, j.Value(col, i))
+		}
+	}
+	if want := []any{0.0, 0.0, 0.0}; !reflect.DeepEqual(xs, want) {
+		t.Fatalf("got %v, want %v", xs, want)
+	}
+}
+
+func TestSaveLoadState(t *testing.T) {
+	s := j.Schema{Columns: []j.ColumnSchema{{Name: "x", Type: j.KindFloat, Nullable: true}, {Name: "s", Type: j.KindString, Nullable: true}}}
+	frame := func(x any, str any) *j.Frame {
+		f := j.NewFrame(s)
+		f.AppendNullRow()
+		_ = f.SetCell(0, "x", x)
+		_ = f.SetCell(0, "s", str)
+		return f
+	}
+	build := func() *j.Pipeline {
+		return j.NewPipeline().Add(&std.Trim{Column: "s"}).Add(&imp.Median{Column: "x"}).Add(&imp.Mode{Column: "s"})
+	}
+	train := build()
+	for _, f := range []*j.Frame{frame(1.0, " a "), frame(3.0, "a"), frame(8.0, "b")} {
+		if _, err := train.FitRun(context.Background(), f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := train.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	serve := build()
+	if err := serve.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	out, err := serve.Run(context.Background(), frame(nil, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	colX, _ := out.ColumnByName("x")
+	if v, _ := colX.(*j.FloatColumn).Get(0); v != 3 {
+		t.Fatalf("expected saved median 3, got %v", v)
+	}
+	colS, _ := out.ColumnByName("s")
+	if v, _ := colS.(*j.StringColumn).Get(0); v != "a" {
+		t.Fatalf("expected saved mode %q, got %q", "a", v)
+	}
+
+	other := j.NewPipeline().Add(&imp.Mean{Column: "x"})
+	if err := other.Load(path); err == nil {
+		t.Fatal("expected error loading state into a different pipeline")
+	}
+
+	// keep: last learns row positions of the fitted data, which cannot be reused
+	last := j.NewPipeline().Add(&rows.DedupeLast{Columns: []string{"x"}})
+	if _, err := last.FitRun(context.Background(), frame(1.0, "a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := last.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := last.Load(path); err == nil {
+		t.Fatal("expected error loading state for dedupe keep: last")
+	}
+}
+
+type captureSink struct{ frames []*j.Frame }
+
+func (c *captureSink) Write(f *j.Frame) error { c.frames = append(c.frames, f); return nil }
+func (c *captureSink) Close() error           { return nil }
+
+func TestQuarantineRoutesFailingRows(t *testing.T) {
+	s := j.Schema{Columns: []j.ColumnSchema{{Name: "age", Type: j.KindInt, Nullable: true}}}
+	f := j.NewFrame(s)
+	for i, v := range []int64{5, -1, 40, 200} {
+		f.AppendNullRow()
+		_ = f.SetCell(i, "age", v)
+	}
+	lo, hi := 0.0, 120.0
+	rejects := &captureSink{}
+	var seen int
+	p := j.NewPipeline().
+		Add(&val.Range{Column: "age", Min: &lo, Max: &hi, OnFail: j.ActionQuarantine}).
+		SetRejectSink(rejects).
+		OnViolation(func(step int, v j.Validator, f *j.Frame, vs []j.Violation) { seen += len(vs) })
+	out, err := p.Run(context.Background(), f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Rows() != 2 || seen != 2 {
+		t.Fatalf("expected 2 clean rows and 2 violations, got %d and %d", out.Rows(), seen)
+	}
+	if len(rejects.frames) != 1 || rejects.frames[0].Rows() != 2 {
+		t.Fatal("expected one reject frame with 2 rows")
+	}
+	reason, ok := rejects.frames[0].ColumnByName(j.RejectReasonColumn)
+	if !ok {
+		t.Fatal("reject frame missing reason column")
+	}
+	if v, _ := reason.(*j.StringColumn).Get(1); v != "above max 120" {
+		t.Fatalf("unexpected reason %q", v)
+	}
+
+	failing := j.NewPipeline().Add(&val.Range{Column: "age", Min: &lo})
+	if _, err := failing.Run(context.Background(), f); err == nil {
+		t.Fatal("expected default action to fail the run")
+	}
+}
+
+func TestValidationReportAcrossChunks(t *testing.T) {
+	s := j.Schema{Columns: []j.ColumnSchema{{Name: "age", Type: j.KindInt, Nullable: true}}}
+	var chunks []*j.Frame
+	for _, vals := range [][]int64{{1, -5}, {-7, 3, -9}} {
+		f := j.NewFrame(s)
+		for i, v := range vals {
+			f.AppendNullRow()
+			_ = f.SetCell(i, "age", v)
+		}
+		chunks = append(chunks, f)
+	}
+	lo := 0.0
+	report := j.NewValidationReport(2)
+	p := j.NewPipeline().Add(&val.Range{Column: "age", Min: &lo, OnFail: j.ActionWarn}).OnViolation(report.Observe)
+	if err := j.RunStream(context.Background(), p, &sliceSource{frames: chunks}, &captureSink{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Rules) != 1 {
+		t.Fatalf("expected 1 rule, got %d", len(report.Rules))
+	}
+	rr := report.Rules[0]
+	if rr.RowsChecked != 5 || rr.RowsFailed != 3 {
+		t.Fatalf("expected 5 checked and 3 failed, got %d and %d", rr.RowsChecked, rr.RowsFailed)
+	}
+	samples := rr.Columns[0].Samples
+	if len(samples) != 2 || samples[0].Row != 1 || samples[1].Row != 2 || samples[1].Value != "-7" {
+		t.Fatalf("unexpected samples %+v", samples)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s := j.Schema{Columns: []j.ColumnSchema{{Name: "x", Type: j.KindFloat, Nullable: true}, {Name: "s", Type: j.KindString, Nullable: true}}}
+	lo, hi := 5.0, 1.0
+	p := j.NewPipeline().
+		Add(&cols.Rename{Columns: map[string]string{"s": "name"}}).
+		Add(&std.Trim{Column: "name"}).
+		Add(&std.Trim{Column: "s"}).
+		Add(&imp.Mean{Column: "name"}).
+		Add(&cols.Select{Columns: []string{"nope"}}).
+		Add(&val.Range{Column: "x", Min: &lo, Max: &hi})
+	errs := p.Validate(s)
+	type problem struct {
+		step  int
+		fatal bool
+	}
+	var got []problem
+	for _, e := range errs {
+		got = append(got, problem{e.Step, e.Fatal})
+	}
+	want := []problem{{2, false}, {3, false}, {4, true}, {5, false}}
+	if len(got) != len(want) {
+		t.Fatalf("problems = %v", errs)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("problem %d = %+v (%v), want %+v", i, got[i], errs[i], want[i])
+		}
+	}
+	if msg := errs[1].Error(); msg != `step 3 (impute_mean): column "name" is string, want float or int` {
+		t.Fatalf("message = %s", msg)
+	}
+}
@@ -0,0 +1,245 @@
+package janitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// StepSpec describes a step that configs can name: its params and how to
+// build its transforms from them.
+type StepSpec struct {
+	Name   string
+	Doc    string
+	Params []ParamSpec
+
+	params reflect.Type
+	build  func(params any, b *StepBuilder) error
+}
+
+// ParamSpec describes one param of a step, read from the json, doc,
+// required and enum tags of the step's params struct.
+type ParamSpec struct {
+	Name     string
+	Type     reflect.Type
+	Required bool
+	Doc      string
+	Enum     []string // the values allowed, from a comma-separated enum tag
+}
+
+// JSONType returns the param's type in JSON terms: string, number, integer,
+// boolean, array, object or any.
+func (p ParamSpec) JSONType() string { return jsonType(p.Type) }
+
+// ColumnParams are the params of a step that works on one column at a time:
+// "column" names one, "columns" is a Selector. Embed it in a step's params
+// struct and build with StepBuilder.EachColumn.
+type ColumnParams struct {
+	Column  string   `json:"column" doc:"column to apply the step to"`
+	Columns Selector `json:"columns" doc:"columns to apply the step to, one step each: names, globs (*_id), re:<regexp> or type:<type>"`
+}
+
+// Selector returns the step's "column" or "columns" as one Selector.
+func (c ColumnParams) Selector() (Selector, error) {
+	if c.Column != "" {
+		if len(c.Columns) > 0 {
+			return nil, fmt.Errorf("use either column or columns")
+		}
+		return Selector{c.Column}, nil
+	}
+	if len(c.Columns) == 0 {
+		return nil, fmt.Errorf("column or columns is required")
+	}
+	return c.Columns, nil
+}
+
+// StepBuilder is what a step is built into.
+type StepBuilder struct {
+	// Schema returns the schema the step will see: the input's, as changed by
+	// the steps before it. It may read ahead in the input, so it is only
+	// called for column patterns.
+	Schema func() (Schema, error)
+	// Add adds a transform; label names it in listings, e.g. "trim:name".
+	Add func(t Transform, label string)
+	// Warn reports a problem that does not keep the step from being built,
+	// such as an unknown param.
+	Warn func(err error)
+}
+
+// Columns resolves sel, reading the schema only when sel has patterns.
+func (b *StepBuilder) Columns(sel Selector) ([]string, error) {
+	if err := sel.Check(); err != nil {
+		return nil, err
+	}
+	if !sel.HasPatterns() {
+		return sel, nil
+	}
+	in, err := b.Schema()
+	if err != nil {
+		return nil, err
+	}
+	return sel.Resolve(in)
+}
+
+// EachColumn adds newStep(column) for every column p selects, labelled with
+// the step's name and column. Without a column it only warns, since the step
+// would do nothing.
+func (b *StepBuilder) EachColumn(p ColumnParams, newStep func(column string) Transform) error {
+	sel, err := p.Selector()
+	if err != nil {
+		b.Warn(err)
+		return nil
+	}
+	names, err := b.Columns(sel)
+	if err != nil {
+		return err
+	}
+	for _, c := range names {
+		t := newStep(c)
+		b.Add(t, t.Name()+":"+c)
+	}
+	return nil
+}
+
+var (
+	stepsMu sync.RWMutex
+	steps   = map[string]*StepSpec{}
+)
+
+// RegisterStep makes a step available to configs under name. Its params are
+// decoded into a new P, which build turns into transforms. Configs in every
+// format reach the decoder as JSON, so P uses json tags; a doc tag describes
+// a param and required:"true" marks one that must be set. RegisterStep is
+// meant for init functions and panics when name is already registered.
+func RegisterStep[P any](name, doc string, build func(p *P, b *StepBuilder) error) {
+	t := reflect.TypeOf((*P)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("janitor: params of step %q must be a struct, got %s", name, t))
+	}
+	spec := &StepSpec{
+		Name:   name,
+		Doc:    doc,
+		Params: paramSpecs(t),
+		params: t,
+		build:  func(p any, b *StepBuilder) error { return build(p.(*P), b) },
+	}
+	stepsMu.Lock()
+	defer stepsMu.Unlock()
+	if _, dup := steps[name]; dup {
+		panic(fmt.Sprintf("janitor: step %q registered twice", name))
+	}
+	steps[name] = spec
+}
+
+// LookupStep returns the step registered under name.
+func LookupStep(name string) (*StepSpec, bool) {
+	stepsMu.RLock()
+	defer stepsMu.RUnlock()
+	s, ok := steps[name]
+	return s, ok
+}
+
+// Steps returns every registered step, sorted by name.
+func Steps() []*StepSpec {
+	stepsMu.RLock()
+	defer stepsMu.RUnlock()
+	out := make([]*StepSpec, 0, len(steps))
+	for _, s := range steps {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, k int) bool { return out[i].Name < out[k].Name })
+	return out
+}
+
+// Build decodes params and builds the step into b. Params that do not decode
+// cleanly (unknown names, wrong types) are reported to b.Warn and the rest
+// are used; an error means the step could not be built.
+func (s *StepSpec) Build(params json.RawMessage, b *StepBuilder) error {
+	p := reflect.New(s.params).Interface()
+	if err := decodeParams(params, p); err != nil {
+		b.Warn(err)
+	}
+	return s.build(p, b)
+}
+
+// decodeParams decodes params into dst. Unlike json.Unmarshal it also
+// reports params dst does not have, such as a misspelled name; dst is filled
+// either way.
+func decodeParams(params json.RawMessage, dst any) error {
+	if len(params) == 0 {
+		return nil
+	}
+	err := json.Unmarshal(params, dst)
+	if err == nil {
+		dec := json.NewDecoder(bytes.NewReader(params))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(reflect.New(reflect.TypeOf(dst).Elem()).Interface())
+	}
+	var te *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &te) && te.Field != "":
+		return fmt.Errorf("param %q: got a JSON %s, want %s", te.Field, te.Value, jsonType(te.Type))
+	case errors.As(err, &te):
+		return fmt.Errorf("params: got a JSON %s, want an object", te.Value)
+	case err != nil && strings.HasPrefix(err.Error(), "json: unknown field "):
+		return fmt.Errorf("unknown param %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+	}
+	return err
+}
+
+// paramSpecs lists the json fields of t, including those of embedded structs.
+func paramSpecs(t reflect.Type) []ParamSpec {
+	var out []ParamSpec
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			out = append(out, paramSpecs(f.Type)...)
+			continue
+		}
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		ps := ParamSpec{Name: name, Type: f.Type, Required: f.Tag.Get("required") == "true", Doc: f.Tag.Get("doc")}
+		if e := f.Tag.Get("enum"); e != "" {
+			ps.Enum = strings.Split(e, ",")
+		}
+		out = append(out, ps)
+	}
+	return out
+}
+
+var textUnmarshaler = reflect.TypeOf((*interface{ UnmarshalText([]byte) error })(nil)).Elem()
+
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshaler) {
+		return "string"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	}
+	return "any"
+}
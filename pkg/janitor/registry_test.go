@@ -0,0 +1,73 @@
+package janitor
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type nopStep struct{ column string }
+
+func (n *nopStep) Name() string                                        { return "test_nop" }
+func (n *nopStep) Apply(ctx context.Context, f *Frame) (*Frame, error) { return f, nil }
+
+type nopParams struct {
+	ColumnParams
+	Times int `json:"times" required:"true" doc:"how often"`
+}
+
+func TestRegisterStep(t *testing.T) {
+	RegisterStep("test_nop", "does nothing", func(p *nopParams, b *StepBuilder) error {
+		return b.EachColumn(p.ColumnParams, func(c string) Transform { return &nopStep{column: c} })
+	})
+	spec, ok := LookupStep("test_nop")
+	if !ok {
+		t.Fatal("registered step not found")
+	}
+	var names []string
+	for _, p := range spec.Params {
+		names = append(names, p.Name+":"+p.JSONType())
+	}
+	if want := []string{"column:string", "columns:array", "times:integer"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("params = %v, want %v", names, want)
+	}
+	if !spec.Params[2].Required || spec.Params[2].Doc != "how often" {
+		t.Fatalf("times = %+v", spec.Params[2])
+	}
+
+	schema := Schema{Columns: []ColumnSchema{{Name: "a_id", Type: KindInt}, {Name: "b_id", Type: KindInt}, {Name: "c", Type: KindString}}}
+	var labels []string
+	var warnings []error
+	b := &StepBuilder{
+		Schema: func() (Schema, error) { return schema, nil },
+		Add:    func(t Transform, label string) { labels = append(labels, label) },
+		Warn:   func(err error) { warnings = append(warnings, err) },
+	}
+	if err := spec.Build(json.RawMessage(`{"columns": ["*_id"], "times": "2", "extra": 1}`), b); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"test_nop:a_id", "test_nop:b_id"}; !reflect.DeepEqual(labels, want) {
+		t.Fatalf("labels = %v, want %v", labels, want)
+	}
+	if len(warnings) != 1 || warnings[0].Error() != `param "times": got a JSON string, want integer` {
+		t.Fatalf("warnings = %v", warnings)
+	}
+	warnings = nil
+	if err := spec.Build(json.RawMessage(`{"times": 2, "extra": 1}`), b); err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 2 || warnings[0].Error() != `unknown param "extra"` {
+		t.Fatalf("warnings = %v", warnings)
+	}
+	if err := spec.Build(json.RawMessage(`{"columns": ["re:^z"]}`), b); err == nil {
+		t.Fatal("expected an error for a pattern matching nothing")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic registering a name twice")
+		}
+	}()
+	RegisterStep("test_nop", "again", func(p *ColumnParams, b *StepBuilder) error { return nil })
+}
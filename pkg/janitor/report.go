@@ -0,0 +1,101 @@
+package janitor
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// ValidationReport accumulates validator outcomes across every frame or chunk
+// a Pipeline runs. Register it with p.OnViolation(r.Observe).
+type ValidationReport struct {
+	Rules []*RuleReport `json:"rules"`
+
+	maxSamples int
+	byStep     map[int]*RuleReport
+}
+
+// RuleReport summarises one validator step.
+type RuleReport struct {
+	Step        int               `json:"step"`
+	Rule        string            `json:"rule"`
+	Action      Action            `json:"action"`
+	RowsChecked int               `json:"rows_checked"`
+	RowsFailed  int               `json:"rows_failed"`
+	Columns     []*ColumnFailures `json:"columns,omitempty"`
+}
+
+// ColumnFailures holds the failures of one rule on one column.
+type ColumnFailures struct {
+	Column  string          `json:"column"`
+	Failed  int             `json:"failed"`
+	Samples []FailureSample `json:"samples,omitempty"`
+}
+
+// FailureSample is one failing value. Row counts the rows this rule has
+// checked, across chunks, so it is the input row number unless an earlier
+// step removed rows.
+type FailureSample struct {
+	Row    int    `json:"row"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+// NewValidationReport returns a report keeping up to maxSamples failing
+// values per rule and column.
+func NewValidationReport(maxSamples int) *ValidationReport {
+	return &ValidationReport{Rules: []*RuleReport{}, maxSamples: maxSamples, byStep: map[int]*RuleReport{}}
+}
+
+// Observe is a ViolationFunc that records one validator run.
+func (r *ValidationReport) Observe(step int, v Validator, f *Frame, vs []Violation) {
+	rr, ok := r.byStep[step]
+	if !ok {
+		act := v.FailAction()
+		if act == "" {
+			act = ActionFail
+		}
+		rr = &RuleReport{Step: step, Rule: v.Name(), Action: act}
+		r.byStep[step] = rr
+		r.Rules = append(r.Rules, rr)
+	}
+	base := rr.RowsChecked
+	rr.RowsChecked += f.Rows()
+	failed := map[int]struct{}{}
+	for _, x := range vs {
+		failed[x.Row] = struct{}{}
+		cf := rr.column(x.Column)
+		cf.Failed++
+		if len(cf.Samples) < r.maxSamples {
+			cf.Samples = append(cf.Samples, FailureSample{Row: base + x.Row, Value: x.Value, Reason: x.Reason})
+		}
+	}
+	rr.RowsFailed += len(failed)
+}
+
+func (rr *RuleReport) column(name string) *ColumnFailures {
+	for _, cf := range rr.Columns {
+		if cf.Column == name {
+			return cf
+		}
+	}
+	cf := &ColumnFailures{Column: name}
+	rr.Columns = append(rr.Columns, cf)
+	return cf
+}
+
+// Failed reports whether any rule had a failing row.
+func (r *ValidationReport) Failed() bool {
+	for _, rr := range r.Rules {
+		if rr.RowsFailed > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *ValidationReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
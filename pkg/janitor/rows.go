@@ -0,0 +1,64 @@
+package janitor
+
+import "fmt"
+
+// Predicate selects rows of a Frame: Mask returns one entry per row, true
+// for rows that match. Rows whose inputs are null do not match. Check
+// reports whether the predicate can be evaluated over frames of schema s.
+type Predicate interface {
+	Mask(f *Frame) ([]bool, error)
+	Check(s Schema) error
+}
+
+// Filter returns a Frame holding the rows of f where mask is true; f itself
+// when every row is kept.
+func (f *Frame) Filter(mask []bool) (*Frame, error) {
+	if len(mask) != f.nrows {
+		return nil, fmt.Errorf("filter mask has %d entries, frame has %d rows", len(mask), f.nrows)
+	}
+	rows := make([]int, 0, len(mask))
+	for i, keep := range mask {
+		if keep {
+			rows = append(rows, i)
+		}
+	}
+	if len(rows) == f.nrows {
+		return f, nil
+	}
+	return f.Take(rows), nil
+}
+
+// Append appends the rows of g to f. g must have the columns of f, with the
+// same names and types in the same order.
+func (f *Frame) Append(g *Frame) error {
+	if len(g.cols) != len(f.cols) {
+		return fmt.Errorf("append: frame has %d columns, want %d", len(g.cols), len(f.cols))
+	}
+	for i, c := range f.cols {
+		gc := g.cols[i]
+		if gc.Name() != c.Name() || gc.Kind() != c.Kind() {
+			return fmt.Errorf("append: column %d is %s %s, want %s %s", i, gc.Name(), gc.Kind(), c.Name(), c.Kind())
+		}
+	}
+	for i, c := range f.cols {
+		switch col := c.(type) {
+		case *BoolColumn:
+			src := g.cols[i].(*BoolColumn)
+			col.data, col.nulls = append(col.data, src.data...), append(col.nulls, src.nulls...)
+		case *IntColumn:
+			src := g.cols[i].(*IntColumn)
+			col.data, col.nulls = append(col.data, src.data...), append(col.nulls, src.nulls...)
+		case *FloatColumn:
+			src := g.cols[i].(*FloatColumn)
+			col.data, col.nulls = append(col.data, src.data...), append(col.nulls, src.nulls...)
+		case *StringColumn:
+			src := g.cols[i].(*StringColumn)
+			col.data, col.nulls = append(col.data, src.data...), append(col.nulls, src.nulls...)
+		case *TimeColumn:
+			src := g.cols[i].(*TimeColumn)
+			col.data, col.nulls = append(col.data, src.data...), append(col.nulls, src.nulls...)
+		}
+	}
+	f.nrows += g.nrows
+	return nil
+}
@@ -0,0 +1,93 @@
+package janitor
+
+import (
+	"fmt"
+	"strings"
+)
+
+var kindNames = [...]string{KindInvalid: "invalid", KindBool: "bool", KindInt: "int", KindFloat: "float", KindString: "string", KindTime: "time"}
+
+// String returns the kind's name as used in schema files: bool, int, float,
+// string or time.
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+	return kindNames[k]
+}
+
+// ParseKind parses a kind name. "integer", "double", "boolean", "datetime"
+// and "timestamp" are accepted as aliases.
+func ParseKind(s string) (Kind, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "bool", "boolean":
+		return KindBool, nil
+	case "int", "integer", "int64":
+		return KindInt, nil
+	case "float", "double", "float64", "number":
+		return KindFloat, nil
+	case "string", "str", "text":
+		return KindString, nil
+	case "time", "datetime", "timestamp", "date":
+		return KindTime, nil
+	}
+	return KindInvalid, fmt.Errorf("unknown column type %q (want bool, int, float, string or time)", s)
+}
+
+func (k Kind) MarshalText() ([]byte, error) {
+	if k <= KindInvalid || int(k) >= len(kindNames) {
+		return nil, fmt.Errorf("cannot encode column type %d", int(k))
+	}
+	return []byte(k.String()), nil
+}
+
+func (k *Kind) UnmarshalText(b []byte) error {
+	v, err := ParseKind(string(b))
+	if err != nil {
+		return err
+	}
+	*k = v
+	return nil
+}
+
+// Index returns the position of the named column, or -1.
+func (s Schema) Index(name string) int {
+	for i, cs := range s.Columns {
+		if cs.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Names returns the column names in order.
+func (s Schema) Names() []string {
+	names := make([]string, len(s.Columns))
+	for i, cs := range s.Columns {
+		names[i] = cs.Name
+	}
+	return names
+}
+
+// CheckColumn reports an error unless in has the named column and, when
+// kinds are given, the column is of one of them.
+func CheckColumn(in Schema, name string, kinds ...Kind) error {
+	if name == "" {
+		return fmt.Errorf("column is required")
+	}
+	i := in.Index(name)
+	if i < 0 {
+		return fmt.Errorf("unknown column: %s", name)
+	}
+	if len(kinds) == 0 {
+		return nil
+	}
+	want := make([]string, len(kinds))
+	for k, kind := range kinds {
+		if in.Columns[i].Type == kind {
+			return nil
+		}
+		want[k] = kind.String()
+	}
+	return fmt.Errorf("column %q is %s, want %s", name, in.Columns[i].Type, strings.Join(want, " or "))
+}
@@ -0,0 +1,34 @@
+package janitor
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSchemaJSONRoundTrip(t *testing.T) {
+	s := Schema{Columns: []ColumnSchema{
+		{Name: "id", Type: KindInt},
+		{Name: "score", Type: KindFloat, Nullable: true},
+		{Name: "at", Type: KindTime, Nullable: true},
+	}}
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"columns":[{"name":"id","type":"int","nullable":false},{"name":"score","type":"float","nullable":true},{"name":"at","type":"time","nullable":true}]}`
+	if string(b) != want {
+		t.Fatalf("got %s", b)
+	}
+	var back Schema
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	for i := range s.Columns {
+		if back.Columns[i] != s.Columns[i] {
+			t.Fatalf("column %d = %+v, want %+v", i, back.Columns[i], s.Columns[i])
+		}
+	}
+	if err := json.Unmarshal([]byte(`{"columns":[{"name":"x","type":"decimal"}]}`), &back); err == nil {
+		t.Fatal("expected an error for an unknown type")
+	}
+}
@@ -0,0 +1,120 @@
+package janitor
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Selector picks columns of a schema. Each entry is one of:
+//
+//   - a column name, used as is;
+//   - a glob such as "*_id" (path.Match syntax: *, ? and [...]; escape a
+//     literal metacharacter with a backslash);
+//   - "re:" and a regular expression, matched anywhere in the name unless
+//     anchored, e.g. "re:^tmp_";
+//   - "type:" and a column type, e.g. "type:string".
+//
+// Plain names are returned whether or not the schema has them, so each step
+// keeps its own rule for missing columns; a pattern that matches nothing is
+// an error.
+type Selector []string
+
+// HasPatterns reports whether s has entries other than plain names, which
+// need a schema to resolve.
+func (s Selector) HasPatterns() bool {
+	for _, e := range s {
+		if selectorKind(e) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Check reports malformed patterns without a schema.
+func (s Selector) Check() error {
+	_, err := s.matchers()
+	return err
+}
+
+// Resolve returns the columns s selects from schema: entries in the order
+// given, the columns one pattern matches in schema order, each name once.
+func (s Selector) Resolve(schema Schema) ([]string, error) {
+	ms, err := s.matchers()
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	seen := map[string]bool{}
+	for i, e := range s {
+		if ms[i] == nil {
+			if !seen[e] {
+				seen[e] = true
+				out = append(out, e)
+			}
+			continue
+		}
+		n := 0
+		for _, cs := range schema.Columns {
+			if !ms[i](cs) {
+				continue
+			}
+			n++
+			if !seen[cs.Name] {
+				seen[cs.Name] = true
+				out = append(out, cs.Name)
+			}
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("column pattern %q matches no columns", e)
+		}
+	}
+	return out, nil
+}
+
+// matchers returns a match function per entry, nil for plain names.
+func (s Selector) matchers() ([]func(ColumnSchema) bool, error) {
+	ms := make([]func(ColumnSchema) bool, len(s))
+	for i, e := range s {
+		switch selectorKind(e) {
+		case "type":
+			k, err := ParseKind(strings.TrimPrefix(e, "type:"))
+			if err != nil {
+				return nil, fmt.Errorf("column pattern %q: %w", e, err)
+			}
+			ms[i] = func(cs ColumnSchema) bool { return cs.Type == k }
+		case "re":
+			re, err := regexp.Compile(strings.TrimPrefix(e, "re:"))
+			if err != nil {
+				return nil, fmt.Errorf("column pattern %q: %w", e, err)
+			}
+			ms[i] = func(cs ColumnSchema) bool { return re.MatchString(cs.Name) }
+		case "glob":
+			if _, err := path.Match(e, ""); err != nil {
+				return nil, fmt.Errorf("column pattern %q: %w", e, err)
+			}
+			ms[i] = func(cs ColumnSchema) bool {
+				ok, _ := path.Match(e, cs.Name)
+				return ok
+			}
+		case "":
+			if e == "" {
+				return nil, fmt.Errorf("empty column name")
+			}
+		}
+	}
+	return ms, nil
+}
+
+func selectorKind(e string) string {
+	switch {
+	case strings.HasPrefix(e, "type:"):
+		return "type"
+	case strings.HasPrefix(e, "re:"):
+		return "re"
+	case strings.ContainsAny(e, `*?[\`):
+		return "glob"
+	}
+	return ""
+}
@@ -0,0 +1,50 @@
+package janitor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectorResolve(t *testing.T) {
+	s := Schema{Columns: []ColumnSchema{
+		{Name: "user_id", Type: KindInt},
+		{Name: "name", Type: KindString},
+		{Name: "order_id", Type: KindInt},
+		{Name: "city", Type: KindString},
+		{Name: "tmp_score", Type: KindFloat},
+	}}
+	cases := []struct {
+		sel  Selector
+		want []string
+	}{
+		{Selector{"name", "missing"}, []string{"name", "missing"}},
+		{Selector{"*_id"}, []string{"user_id", "order_id"}},
+		{Selector{"type:string"}, []string{"name", "city"}},
+		{Selector{"re:^tmp_", "city", "type:str"}, []string{"tmp_score", "city", "name"}},
+		{Selector{`\*`}, nil},
+	}
+	for _, c := range cases {
+		got, err := c.sel.Resolve(s)
+		if c.want == nil {
+			if err == nil {
+				t.Fatalf("%v: expected an error for a pattern matching nothing", c.sel)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: %v", c.sel, err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Fatalf("%v = %v, want %v", c.sel, got, c.want)
+		}
+	}
+
+	if (Selector{"name", "city"}).HasPatterns() || !(Selector{"name", "*_id"}).HasPatterns() {
+		t.Fatal("HasPatterns misreports plain names")
+	}
+	for _, bad := range []Selector{{"type:blob"}, {"re:("}, {"[a"}, {""}} {
+		if err := bad.Check(); err == nil {
+			t.Fatalf("%v: expected an error", bad)
+		}
+	}
+}
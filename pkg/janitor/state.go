@@ -0,0 +1,98 @@
+package janitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// StateVersion is the format version written by Pipeline.Save.
+const StateVersion = 1
+
+// StateCodec is implemented by Fitters whose learned parameters can be
+// persisted. MarshalState returns nil for a Fitter that has not been fitted.
+type StateCodec interface {
+	MarshalState() (json.RawMessage, error)
+	UnmarshalState(b json.RawMessage) error
+}
+
+type pipelineState struct {
+	Version int         `json:"version"`
+	Steps   []stepState `json:"steps"`
+}
+
+type stepState struct {
+	Name  string          `json:"name"`
+	State json.RawMessage `json:"state,omitempty"`
+}
+
+// Save writes the learned parameters of every fitted step to path as JSON.
+// Stateless steps are recorded by name only so Load can check the file
+// belongs to the same pipeline.
+func (p *Pipeline) Save(path string) error {
+	st := pipelineState{Version: StateVersion, Steps: make([]stepState, len(p.steps))}
+	for i, t := range p.steps {
+		st.Steps[i].Name = t.Name()
+		if sc, ok := t.(StateCodec); ok {
+			b, err := sc.MarshalState()
+			if err != nil {
+				return fmt.Errorf("save state for step %d (%s): %w", i, t.Name(), err)
+			}
+			st.Steps[i].State = b
+		}
+	}
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// Load restores parameters written by Save into a pipeline built with the same
+// steps. Restored steps are fitted, so Run applies the saved statistics. A
+// Fitter that cannot save its state, such as one that learns row positions,
+// cannot be restored, and loading into its pipeline is an error.
+func (p *Pipeline) Load(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var st pipelineState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return fmt.Errorf("pipeline state %s: %w", path, err)
+	}
+	if st.Version != StateVersion {
+		return fmt.Errorf("pipeline state %s: unsupported version %d (want %d)", path, st.Version, StateVersion)
+	}
+	if len(st.Steps) != len(p.steps) {
+		return fmt.Errorf("pipeline state %s: has %d steps, pipeline has %d", path, len(st.Steps), len(p.steps))
+	}
+	for i, t := range p.steps {
+		if st.Steps[i].Name != t.Name() {
+			return fmt.Errorf("pipeline state %s: step %d is %q, pipeline has %q", path, i, st.Steps[i].Name, t.Name())
+		}
+		if _, ok := t.(Fitter); ok && !hasState(t) {
+			return fmt.Errorf("pipeline state %s: step %d (%s) has no state to restore; it must be fitted on the data it is applied to", path, i, t.Name())
+		}
+		if len(st.Steps[i].State) == 0 || string(st.Steps[i].State) == "null" {
+			continue
+		}
+		sc, ok := t.(StateCodec)
+		if !ok {
+			return fmt.Errorf("pipeline state %s: step %d (%s) does not accept state", path, i, t.Name())
+		}
+		if err := sc.UnmarshalState(st.Steps[i].State); err != nil {
+			return fmt.Errorf("pipeline state %s: step %d (%s): %w", path, i, t.Name(), err)
+		}
+	}
+	return nil
+}
+
+// hasState reports whether t can save and restore its fitted state.
+func hasState(t Transform) bool {
+	if w, ok := t.(*whenFitter); ok {
+		t = w.step
+	}
+	_, ok := t.(StateCodec)
+	return ok
+}
@@ -17,8 +17,11 @@ type ChunkSink interface {
 }
 
 // RunStream pulls chunks from src, applies the pipeline, and writes to sink.
-func RunStream(ctx context.Context, p *Pipeline, src ChunkSource, sink ChunkSink) error {
-    defer func() { _ = sink.Close() }()
+// The sink is always closed; its Close error is returned if nothing else failed.
+func RunStream(ctx context.Context, p *Pipeline, src ChunkSource, sink ChunkSink) (err error) {
+    defer func() {
+        if cerr := sink.Close(); err == nil { err = cerr }
+    }()
     for {
         f, err := src.Next()
         if err == io.EOF {
@@ -0,0 +1,138 @@
+package janitor
+
+import (
+	"context"
+	"fmt"
+)
+
+// Action is what a Pipeline does with rows that fail a Validator.
+type Action string
+
+const (
+	ActionFail       Action = "fail"       // abort the run (default)
+	ActionWarn       Action = "warn"       // report the rows and keep them
+	ActionQuarantine Action = "quarantine" // remove the rows and send them to the reject sink
+)
+
+// ParseAction validates an action name from config; "" means ActionFail.
+func ParseAction(s string) (Action, error) {
+	switch Action(s) {
+	case "", ActionFail:
+		return ActionFail, nil
+	case ActionWarn, ActionQuarantine:
+		return Action(s), nil
+	default:
+		return "", fmt.Errorf("unknown action %q (want fail, warn or quarantine)", s)
+	}
+}
+
+// Violation is a single row that failed a validation rule.
+type Violation struct {
+	Row    int // index into the frame passed to Check
+	Column string
+	Value  string
+	Reason string
+}
+
+// Validator is a Transform that checks rows without modifying them. Check
+// reports every failing row; Apply keeps the fail-fast behaviour for direct
+// use. A Pipeline calls Check and handles failures according to FailAction.
+type Validator interface {
+	Transform
+	Check(ctx context.Context, f *Frame) ([]Violation, error)
+	FailAction() Action
+}
+
+// ViolationFunc observes each validator run in a Pipeline: step is the
+// validator's index in the pipeline, f the frame that was checked and vs the
+// rows that failed, before any are removed.
+type ViolationFunc func(step int, v Validator, f *Frame, vs []Violation)
+
+// Columns added to rows sent to a reject sink.
+const (
+	RejectRuleColumn   = "reject_rule"
+	RejectColumnColumn = "reject_column"
+	RejectReasonColumn = "reject_reason"
+)
+
+// SetRejectSink sets where quarantined rows are written. Each frame written
+// has the schema of the frame being validated plus the reject columns. Without
+// a sink, quarantined rows are dropped. The pipeline does not close the sink.
+func (p *Pipeline) SetRejectSink(s ChunkSink) *Pipeline {
+	p.rejects = s
+	return p
+}
+
+// OnViolation registers fn to observe every validator run. Observers are
+// called in registration order.
+func (p *Pipeline) OnViolation(fn ViolationFunc) *Pipeline {
+	p.onViolation = append(p.onViolation, fn)
+	return p
+}
+
+// check runs v against f and applies its action. When emit is false (the
+// fitting pass) failures are neither reported nor written to the reject sink.
+func (p *Pipeline) check(ctx context.Context, step int, v Validator, f *Frame, emit bool) (*Frame, error) {
+	vs, err := v.Check(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	if emit {
+		for _, fn := range p.onViolation {
+			fn(step, v, f, vs)
+		}
+	}
+	if len(vs) == 0 {
+		return f, nil
+	}
+	switch v.FailAction() {
+	case ActionWarn:
+		return f, nil
+	case ActionQuarantine:
+		bad := make([]bool, f.Rows())
+		var badRows []int
+		var first []Violation
+		for _, x := range vs {
+			if !bad[x.Row] {
+				bad[x.Row] = true
+				badRows = append(badRows, x.Row)
+				first = append(first, x)
+			}
+		}
+		if emit && p.rejects != nil {
+			if err := p.rejects.Write(RejectFrame(f, v.Name(), badRows, first)); err != nil {
+				return nil, err
+			}
+		}
+		keep := make([]int, 0, f.Rows()-len(badRows))
+		for r := 0; r < f.Rows(); r++ {
+			if !bad[r] {
+				keep = append(keep, r)
+			}
+		}
+		return f.Take(keep), nil
+	default:
+		if _, err := v.Apply(ctx, f); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: %d rows failed", v.Name(), len(vs))
+	}
+}
+
+// RejectFrame returns rows of f with the reject columns added; vs[i] explains
+// rows[i]. It is the frame a reject sink receives.
+func RejectFrame(f *Frame, rule string, rows []int, vs []Violation) *Frame {
+	out := f.Take(rows)
+	ruleCol := NewStringColumn(RejectRuleColumn, len(rows))
+	colCol := NewStringColumn(RejectColumnColumn, len(rows))
+	reasonCol := NewStringColumn(RejectReasonColumn, len(rows))
+	for i, x := range vs {
+		ruleCol.Set(i, rule)
+		colCol.Set(i, x.Column)
+		reasonCol.Set(i, x.Reason)
+	}
+	out.addColumn(ColumnSchema{Name: RejectRuleColumn, Type: KindString}, ruleCol)
+	out.addColumn(ColumnSchema{Name: RejectColumnColumn, Type: KindString}, colCol)
+	out.addColumn(ColumnSchema{Name: RejectReasonColumn, Type: KindString}, reasonCol)
+	return out
+}
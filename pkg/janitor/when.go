@@ -0,0 +1,222 @@
+package janitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// When limits t to the rows of each frame matching where: t runs on those
+// rows alone and its result is written back in place, the other rows are left
+// as they were. t may fill or change cells and add columns (null in rows that
+// do not match) but not remove rows or columns or change a column's type.
+// A Fitter is fitted on matching rows only, and a Validator checks only
+// matching rows. The wrapper keeps t's name.
+func When(where Predicate, t Transform) Transform {
+	w := &when{where: where, step: t}
+	switch t.(type) {
+	case Validator:
+		return &whenValidator{w}
+	case Fitter:
+		return &whenFitter{w}
+	}
+	return w
+}
+
+type when struct {
+	where Predicate
+	step  Transform
+}
+
+func (w *when) Name() string { return w.step.Name() }
+
+// Unwrap returns the wrapped step.
+func (w *when) Unwrap() Transform { return w.step }
+
+// Where returns the row predicate.
+func (w *when) Where() Predicate { return w.where }
+
+func (w *when) OutputSchema(in Schema) (Schema, error) {
+	if err := w.where.Check(in); err != nil {
+		return Schema{}, err
+	}
+	inner := in
+	if r, ok := w.step.(Reshaper); ok {
+		var err error
+		if inner, err = r.OutputSchema(in); err != nil {
+			return Schema{}, err
+		}
+	}
+	return whenSchema(w.step.Name(), in, inner)
+}
+
+// CheckSchema checks the wrapped step, when it is a SchemaChecker.
+func (w *when) CheckSchema(in Schema) error {
+	if c, ok := w.step.(SchemaChecker); ok {
+		return c.CheckSchema(in)
+	}
+	return nil
+}
+
+// whenSchema merges the schema t produces from in back into in.
+func whenSchema(step string, in, inner Schema) (Schema, error) {
+	out := Schema{Columns: append([]ColumnSchema(nil), in.Columns...)}
+	for i, cs := range out.Columns {
+		k := inner.Index(cs.Name)
+		if k < 0 {
+			return Schema{}, fmt.Errorf("when: %s removes column %q", step, cs.Name)
+		}
+		if inner.Columns[k].Type != cs.Type {
+			return Schema{}, fmt.Errorf("when: %s changes column %q from %s to %s", step, cs.Name, cs.Type, inner.Columns[k].Type)
+		}
+		out.Columns[i].Nullable = cs.Nullable || inner.Columns[k].Nullable
+	}
+	for _, cs := range inner.Columns {
+		if out.Index(cs.Name) < 0 {
+			out.Columns = append(out.Columns, ColumnSchema{Name: cs.Name, Type: cs.Type, Nullable: true})
+		}
+	}
+	return out, nil
+}
+
+// rows returns the indices of the rows of f matching the predicate.
+func (w *when) rows(f *Frame) ([]int, error) {
+	mask, err := w.where.Mask(f)
+	if err != nil {
+		return nil, err
+	}
+	var rows []int
+	for i, ok := range mask {
+		if ok {
+			rows = append(rows, i)
+		}
+	}
+	return rows, nil
+}
+
+func (w *when) Apply(ctx context.Context, f *Frame) (*Frame, error) {
+	rows, err := w.rows(f)
+	if err != nil {
+		return nil, err
+	}
+	sub, err := w.step.Apply(ctx, f.Take(rows))
+	if err != nil {
+		return nil, err
+	}
+	if sub.Rows() != len(rows) {
+		return nil, fmt.Errorf("when: %s changed the row count from %d to %d", w.step.Name(), len(rows), sub.Rows())
+	}
+	schema, err := whenSchema(w.step.Name(), f.schema, sub.schema)
+	if err != nil {
+		return nil, err
+	}
+	n := len(f.cols)
+	for i, cs := range schema.Columns[:n] {
+		copyRows(f.cols[i], sub.cols[sub.index[cs.Name]], rows)
+	}
+	f.schema.Columns = append([]ColumnSchema(nil), schema.Columns[:n]...)
+	for _, cs := range schema.Columns[n:] {
+		src := sub.cols[sub.index[cs.Name]]
+		c := NewNullColumn(cs.Name, cs.Type, f.nrows)
+		copyRows(c, src, rows)
+		f.addColumn(cs, c)
+	}
+	return f, nil
+}
+
+// copyRows sets row rows[k] of dst to row k of src; both have the same kind.
+func copyRows(dst, src Column, rows []int) {
+	switch d := dst.(type) {
+	case *BoolColumn:
+		s := src.(*BoolColumn)
+		for k, r := range rows {
+			d.data[r], d.nulls[r] = s.data[k], s.nulls[k]
+		}
+	case *IntColumn:
+		s := src.(*IntColumn)
+		for k, r := range rows {
+			d.data[r], d.nulls[r] = s.data[k], s.nulls[k]
+		}
+	case *FloatColumn:
+		s := src.(*FloatColumn)
+		for k, r := range rows {
+			d.data[r], d.nulls[r] = s.data[k], s.nulls[k]
+		}
+	case *StringColumn:
+		s := src.(*StringColumn)
+		for k, r := range rows {
+			d.data[r], d.nulls[r] = s.data[k], s.nulls[k]
+		}
+	case *TimeColumn:
+		s := src.(*TimeColumn)
+		for k, r := range rows {
+			d.data[r], d.nulls[r] = s.data[k], s.nulls[k]
+		}
+	}
+}
+
+// Reset and Close pass through to the wrapped step.
+func (w *when) Reset() {
+	if r, ok := w.step.(Resetter); ok {
+		r.Reset()
+	}
+}
+
+func (w *when) Close() error {
+	if c, ok := w.step.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+type whenFitter struct{ *when }
+
+// Fit fits the wrapped step on the matching rows of f.
+func (w *whenFitter) Fit(ctx context.Context, f *Frame) error {
+	rows, err := w.rows(f)
+	if err != nil {
+		return err
+	}
+	return w.step.(Fitter).Fit(ctx, f.Take(rows))
+}
+
+func (w *whenFitter) FinishFit() {
+	if ff, ok := w.step.(FitFinisher); ok {
+		ff.FinishFit()
+	}
+}
+
+func (w *whenFitter) MarshalState() (json.RawMessage, error) {
+	if sc, ok := w.step.(StateCodec); ok {
+		return sc.MarshalState()
+	}
+	return nil, nil
+}
+
+func (w *whenFitter) UnmarshalState(b json.RawMessage) error {
+	if sc, ok := w.step.(StateCodec); ok {
+		return sc.UnmarshalState(b)
+	}
+	return fmt.Errorf("%s does not accept state", w.step.Name())
+}
+
+type whenValidator struct{ *when }
+
+// Check checks the matching rows of f; violations refer to rows of f.
+func (w *whenValidator) Check(ctx context.Context, f *Frame) ([]Violation, error) {
+	rows, err := w.rows(f)
+	if err != nil {
+		return nil, err
+	}
+	vs, err := w.step.(Validator).Check(ctx, f.Take(rows))
+	if err != nil {
+		return nil, err
+	}
+	for i := range vs {
+		vs[i].Row = rows[vs[i].Row]
+	}
+	return vs, nil
+}
+
+func (w *whenValidator) FailAction() Action { return w.step.(Validator).FailAction() }
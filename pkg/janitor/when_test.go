@@ -0,0 +1,123 @@
+package janitor_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/wdm0006/janitor/pkg/expr"
+	j "github.com/wdm0006/janitor/pkg/janitor"
+	"github.com/wdm0006/janitor/pkg/transform/columns"
+	imp "github.com/wdm0006/janitor/pkg/transform/impute"
+	std "github.com/wdm0006/janitor/pkg/transform/standardize"
+	val "github.com/wdm0006/janitor/pkg/transform/validate"
+)
+
+func whenFrame(t *testing.T) *j.Frame {
+	t.Helper()
+	f := j.NewFrame(j.Schema{Columns: []j.ColumnSchema{
+		{Name: "status", Type: j.KindString},
+		{Name: "price", Type: j.KindFloat, Nullable: true},
+		{Name: "email", Type: j.KindString},
+	}})
+	for i, row := range [][]any{
+		{"free", nil, "A@X"},
+		{"paid", nil, "B@X"},
+		{"free", 9.0, "C@X"},
+		{"paid", 4.0, "D@X"},
+	} {
+		f.AppendNullRow()
+		_ = f.SetCell(i, "status", row[0])
+		_ = f.SetCell(i, "price", row[1])
+		_ = f.SetCell(i, "email", row[2])
+	}
+	return f
+}
+
+func values(f *j.Frame, name string) []any {
+	c, _ := f.ColumnByName(name)
+	out := make([]any, f.Rows())
+	for i := range out {
+		out[i] = j.Value(c, i)
+	}
+	return out
+}
+
+func where(t *testing.T, src string) *expr.Expr {
+	t.Helper()
+	e, err := expr.Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestWhen(t *testing.T) {
+	ctx := context.Background()
+	f := whenFrame(t)
+	p := j.NewPipeline().
+		Add(j.When(where(t, `status == "free"`), &imp.Constant{Column: "price", Value: 0.0})).
+		Add(j.When(where(t, `status == "paid"`), &std.Lower{Column: "email"})).
+		Add(j.When(where(t, `price > 5`), &columns.Derive{Column: "tier", Expr: where(t, `"high"`)}))
+	want, err := p.OutputSchema(f.Schema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := p.Run(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out.Schema(), want) {
+		t.Fatalf("schema = %+v, OutputSchema said %+v", out.Schema(), want)
+	}
+	if got := values(out, "price"); !reflect.DeepEqual(got, []any{0.0, nil, 9.0, 4.0}) {
+		t.Fatalf("price = %v", got)
+	}
+	if got := values(out, "email"); !reflect.DeepEqual(got, []any{"A@X", "b@x", "C@X", "d@x"}) {
+		t.Fatalf("email = %v", got)
+	}
+	if got := values(out, "tier"); !reflect.DeepEqual(got, []any{nil, nil, "high", nil}) {
+		t.Fatalf("tier = %v", got)
+	}
+
+	cast := j.When(where(t, `status == "free"`), &columns.Cast{Column: "price", To: j.KindString})
+	if _, err := j.NewPipeline().Add(cast).OutputSchema(f.Schema()); err == nil {
+		t.Fatal("expected an error for a type change under when")
+	}
+}
+
+func TestWhenFitterAndValidator(t *testing.T) {
+	ctx := context.Background()
+	f := whenFrame(t)
+	_ = f.SetCell(1, "price", 100.0)
+	// the mean of paid prices only: (100 + 4) / 2
+	mean := j.When(where(t, `status == "paid"`), &imp.Mean{Column: "price"})
+	if _, ok := mean.(j.Fitter); !ok {
+		t.Fatal("when over a Fitter should be a Fitter")
+	}
+	g := whenFrame(t)
+	_ = g.SetCell(1, "price", 100.0)
+	_ = g.SetCell(3, "price", nil)
+	p := j.NewPipeline().Add(mean)
+	if err := mean.(j.Fitter).Fit(ctx, f); err != nil {
+		t.Fatal(err)
+	}
+	out, err := p.Run(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := values(out, "price"); !reflect.DeepEqual(got, []any{nil, 100.0, 9.0, 52.0}) {
+		t.Fatalf("price = %v", got)
+	}
+
+	hi := 5.0
+	rng := j.When(where(t, `status == "paid"`), &val.Range{Column: "price", Max: &hi, OnFail: j.ActionQuarantine})
+	out, err = j.NewPipeline().Add(rng).Run(ctx, whenFrame(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// row 2 (free, 9) is out of range but not checked
+	if got := values(out, "price"); !reflect.DeepEqual(got, []any{nil, nil, 9.0, 4.0}) {
+		t.Fatalf("price = %v", got)
+	}
+}
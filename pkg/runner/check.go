@@ -0,0 +1,594 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	toml "github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// ConfigError is a problem CheckConfig found in a config file.
+type ConfigError struct {
+	File      string
+	Line, Col int    // position in File; 0 when unknown
+	Path      string // the setting, e.g. steps[2].trim.column
+	Msg       string
+}
+
+func (e *ConfigError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.File)
+	if e.Line > 0 {
+		fmt.Fprintf(&b, ":%d:%d", e.Line, e.Col)
+	}
+	if e.Path != "" {
+		b.WriteString(": " + e.Path)
+	}
+	return b.String() + ": " + e.Msg
+}
+
+// CheckConfig checks the config file at path, whose contents are b, against
+// ConfigSchema, along with the step files it includes. Each problem is
+// reported at its line and column. Values that are ${name} references are
+// only checked once resolved, by ParseConfig.
+func CheckConfig(path string, b []byte) []*ConfigError {
+	c := &checker{file: path, seen: map[string]bool{}}
+	root, err := readNodes(path, b)
+	if err != nil {
+		return []*ConfigError{nodeError(path, err)}
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		c.seen[abs] = true
+	}
+	c.check(ConfigSchema(), root, "")
+	if steps := root.field("steps"); steps != nil {
+		c.steps(steps, "steps")
+	}
+	return c.result()
+}
+
+// node is a value of a config document with its position in the file.
+type node struct {
+	kind      string // object, array, string, number, boolean or null
+	value     any    // strings for string nodes
+	integer   bool   // a number without a fraction
+	line, col int
+	fields    []field // of an object, in file order
+	items     []*node // of an array
+}
+
+type field struct {
+	name      string
+	line, col int
+	value     *node
+}
+
+func (n *node) field(name string) *node {
+	for _, f := range n.fields {
+		if f.name == name {
+			return f.value
+		}
+	}
+	return nil
+}
+
+// child returns the object or array under name, adding it when missing; an
+// array of tables yields its last table.
+func (n *node) child(name, kind string, line, col int) *node {
+	if c := n.field(name); c != nil {
+		if c.kind == "array" && kind == "object" && len(c.items) > 0 {
+			return c.items[len(c.items)-1]
+		}
+		return c
+	}
+	c := &node{kind: kind, line: line, col: col}
+	n.fields = append(n.fields, field{name: name, line: line, col: col, value: c})
+	return c
+}
+
+func (n *node) describe() string {
+	if n.kind == "string" {
+		return fmt.Sprintf("string %q", n.value)
+	}
+	return n.kind
+}
+
+type checker struct {
+	file     string
+	seen     map[string]bool // included files, to check each once
+	errs     []*ConfigError
+	included []*ConfigError // problems of included files
+}
+
+// result returns the problems in file order, then those of included files.
+func (c *checker) result() []*ConfigError {
+	sort.SliceStable(c.errs, func(a, b int) bool {
+		ea, eb := c.errs[a], c.errs[b]
+		return ea.Line < eb.Line || (ea.Line == eb.Line && ea.Col < eb.Col)
+	})
+	return append(c.errs, c.included...)
+}
+
+func (c *checker) add(line, col int, at, format string, args ...any) {
+	c.errs = append(c.errs, &ConfigError{File: c.file, Line: line, Col: col, Path: at, Msg: fmt.Sprintf(format, args...)})
+}
+
+// check checks n against the subset of JSON Schema that ConfigSchema uses.
+func (c *checker) check(s map[string]any, n *node, at string) {
+	if alts, ok := s["anyOf"].([]any); ok {
+		for _, a := range alts {
+			probe := &checker{file: c.file}
+			if probe.check(a.(map[string]any), n, at); len(probe.errs) == 0 {
+				return
+			}
+		}
+		// report against the first alternative, the plain value
+		c.check(alts[0].(map[string]any), n, at)
+		return
+	}
+	if want, ok := schemaTypes(s); ok && !hasKind(want, n) {
+		c.add(n.line, n.col, at, "want %s, got %s", strings.Join(want, " or "), n.describe())
+		return
+	}
+	if enum, ok := s["enum"].([]string); ok {
+		v, _ := n.value.(string)
+		if !contains(enum, v) {
+			c.add(n.line, n.col, at, "want one of %s, got %q", strings.Join(enum, ", "), v)
+		}
+	}
+	if p, ok := s["pattern"].(string); ok {
+		if v, _ := n.value.(string); !regexp.MustCompile(p).MatchString(v) {
+			c.add(n.line, n.col, at, "%q does not match %s", v, p)
+		}
+	}
+	switch n.kind {
+	case "object":
+		props, _ := s["properties"].(map[string]any)
+		for _, f := range n.fields {
+			fat := joinPath(at, f.name)
+			if ps, ok := props[f.name]; ok {
+				c.check(ps.(map[string]any), f.value, fat)
+				continue
+			}
+			switch ap := s["additionalProperties"].(type) {
+			case bool:
+				if !ap {
+					c.add(f.line, f.col, at, "unknown key %q%s", f.name, suggest(f.name, props))
+				}
+			case map[string]any:
+				c.check(ap, f.value, fat)
+			}
+		}
+		if req, ok := s["required"].([]string); ok {
+			for _, r := range req {
+				if n.field(r) == nil {
+					c.add(n.line, n.col, at, "missing %s", r)
+				}
+			}
+		}
+		if min, ok := s["minProperties"].(int); ok && len(n.fields) < min {
+			c.add(n.line, n.col, at, "want at least %d key(s)", min)
+		}
+	case "array":
+		if items, ok := s["items"].(map[string]any); ok {
+			for i, it := range n.items {
+				c.check(items, it, fmt.Sprintf("%s[%d]", at, i))
+			}
+		}
+	}
+}
+
+// steps checks what the schema cannot say about a steps list: that each
+// entry names one step, and that included files hold valid steps.
+func (c *checker) steps(list *node, at string) {
+	for i, st := range list.items {
+		sat := fmt.Sprintf("%s[%d]", at, i)
+		if st.kind != "object" {
+			continue
+		}
+		var names []string
+		for _, f := range st.fields {
+			if f.name != "when" && f.name != "include" {
+				names = append(names, f.name)
+			}
+		}
+		inc := st.field("include")
+		switch {
+		case inc != nil && len(st.fields) > 1:
+			c.add(st.line, st.col, sat, "include takes no other keys")
+		case inc != nil:
+			c.include(inc, sat)
+		case len(names) == 0:
+			c.add(st.line, st.col, sat, "no step name")
+		case len(names) > 1:
+			c.add(st.line, st.col, sat, "one step per entry, found %s", strings.Join(names, " and "))
+		}
+	}
+}
+
+// include checks the files an include names, skipping names that are
+// references.
+func (c *checker) include(inc *node, at string) {
+	files := []*node{inc}
+	if inc.kind == "array" {
+		files = inc.items
+	}
+	for _, f := range files {
+		name, _ := f.value.(string)
+		if name == "" || strings.Contains(name, "${") {
+			continue
+		}
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(filepath.Dir(c.file), name)
+		}
+		abs, _ := filepath.Abs(name)
+		if c.seen[abs] {
+			continue
+		}
+		c.seen[abs] = true
+		b, err := os.ReadFile(name)
+		if err != nil {
+			c.add(f.line, f.col, at+".include", "%v", err)
+			continue
+		}
+		sub := &checker{file: name, seen: c.seen}
+		root, err := readNodes(name, b)
+		if err != nil {
+			c.included = append(c.included, nodeError(name, err))
+			continue
+		}
+		steps := root
+		if root.kind == "object" {
+			stepsProp := ConfigSchema()["properties"].(map[string]any)["steps"]
+			sub.check(map[string]any{
+				"type":                 "object",
+				"properties":           map[string]any{"steps": stepsProp},
+				"additionalProperties": false,
+			}, root, "")
+			steps = root.field("steps")
+		} else {
+			sub.check(map[string]any{"type": "array", "items": stepSchema()}, root, "steps")
+		}
+		if steps != nil && steps.kind == "array" {
+			sub.steps(steps, "steps")
+		}
+		c.included = append(c.included, sub.result()...)
+	}
+}
+
+func schemaTypes(s map[string]any) ([]string, bool) {
+	switch t := s["type"].(type) {
+	case string:
+		return []string{t}, true
+	case []any:
+		out := make([]string, len(t))
+		for i, v := range t {
+			out[i] = v.(string)
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+func hasKind(want []string, n *node) bool {
+	for _, w := range want {
+		if w == n.kind || (w == "integer" && n.kind == "number" && n.integer) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func joinPath(at, name string) string {
+	if at == "" {
+		return name
+	}
+	return at + "." + name
+}
+
+// suggest names the known key closest to a misspelled one.
+func suggest(name string, props map[string]any) string {
+	best, bestDist := "", 3
+	for k := range props {
+		if d := editDistance(name, k); d < bestDist || (d == bestDist && best != "" && k < best) {
+			best, bestDist = k, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf("; did you mean %q?", best)
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// readNodes parses a JSON, YAML or TOML document, picked by the extension
+// of path, keeping the position of every value.
+func readNodes(path string, b []byte) (*node, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var doc yaml.Node
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			return nil, err
+		}
+		if len(doc.Content) == 0 {
+			return &node{kind: "null", line: 1, col: 1}, nil
+		}
+		return yamlNode(doc.Content[0]), nil
+	case ".toml":
+		var probe any
+		if err := toml.Unmarshal(b, &probe); err != nil {
+			return nil, err
+		}
+		return tomlNodes(b)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	n, err := jsonNode(dec, b)
+	if err == nil && dec.More() {
+		err = fmt.Errorf("invalid JSON after the top-level value")
+	}
+	var syn *json.SyntaxError
+	if errors.As(err, &syn) {
+		line, col := lineCol(b, int(syn.Offset))
+		return nil, &ConfigError{Line: line, Col: col, Msg: syn.Error()}
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		line, col := lineCol(b, len(b))
+		return nil, &ConfigError{Line: line, Col: col, Msg: "unexpected end of JSON"}
+	}
+	return n, err
+}
+
+// nodeError turns a parse error into a ConfigError with the position the
+// parser gives.
+func nodeError(path string, err error) *ConfigError {
+	var ce *ConfigError
+	var de *toml.DecodeError
+	switch {
+	case errors.As(err, &ce):
+		ce.File = path
+		return ce
+	case errors.As(err, &de):
+		line, col := de.Position()
+		return &ConfigError{File: path, Line: line, Col: col, Msg: de.Error()}
+	}
+	// yaml: line 3: mapping values are not allowed in this context
+	msg := strings.TrimPrefix(err.Error(), "yaml: ")
+	if rest, ok := strings.CutPrefix(msg, "line "); ok {
+		num, text, _ := strings.Cut(rest, ": ")
+		if line, err := strconv.Atoi(num); err == nil {
+			return &ConfigError{File: path, Line: line, Col: 1, Msg: text}
+		}
+	}
+	return &ConfigError{File: path, Msg: msg}
+}
+
+func lineCol(b []byte, off int) (int, int) {
+	if off > len(b) {
+		off = len(b)
+	}
+	lead := b[:off]
+	return bytes.Count(lead, []byte{'\n'}) + 1, len(lead) - bytes.LastIndexByte(lead, '\n')
+}
+
+func jsonNode(dec *json.Decoder, b []byte) (*node, error) {
+	// the value starts after the separators before it
+	off := int(dec.InputOffset())
+	for off < len(b) && strings.IndexByte(" \t\r\n,:", b[off]) >= 0 {
+		off++
+	}
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	n := &node{}
+	n.line, n.col = lineCol(b, off)
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '{' {
+			n.kind = "object"
+			for dec.More() {
+				koff := int(dec.InputOffset())
+				for koff < len(b) && strings.IndexByte(" \t\r\n,", b[koff]) >= 0 {
+					koff++
+				}
+				k, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				f := field{name: k.(string)}
+				f.line, f.col = lineCol(b, koff)
+				if f.value, err = jsonNode(dec, b); err != nil {
+					return nil, err
+				}
+				n.fields = append(n.fields, f)
+			}
+		} else {
+			n.kind = "array"
+			for dec.More() {
+				it, err := jsonNode(dec, b)
+				if err != nil {
+					return nil, err
+				}
+				n.items = append(n.items, it)
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	case string:
+		n.kind, n.value = "string", t
+	case json.Number:
+		n.kind, n.integer = "number", !strings.ContainsAny(t.String(), ".eE")
+	case bool:
+		n.kind = "boolean"
+	case nil:
+		n.kind = "null"
+	}
+	return n, nil
+}
+
+func yamlNode(y *yaml.Node) *node {
+	n := &node{line: y.Line, col: y.Column}
+	if y.Kind == yaml.AliasNode {
+		y = y.Alias
+	}
+	switch y.Kind {
+	case yaml.MappingNode:
+		n.kind = "object"
+		for i := 0; i+1 < len(y.Content); i += 2 {
+			k := y.Content[i]
+			n.fields = append(n.fields, field{name: k.Value, line: k.Line, col: k.Column, value: yamlNode(y.Content[i+1])})
+		}
+	case yaml.SequenceNode:
+		n.kind = "array"
+		for _, it := range y.Content {
+			n.items = append(n.items, yamlNode(it))
+		}
+	default:
+		var v any
+		_ = y.Decode(&v)
+		switch v.(type) {
+		case nil:
+			n.kind = "null"
+		case bool:
+			n.kind = "boolean"
+		case int, int64, uint64:
+			n.kind, n.integer = "number", true
+		case float64:
+			n.kind = "number"
+		default:
+			// strings, and timestamps, which configs read as strings
+			n.kind, n.value = "string", y.Value
+		}
+	}
+	return n
+}
+
+// tomlNodes builds the document from the parser's expressions: tables and
+// arrays of tables open objects, and key/values fill the current one.
+func tomlNodes(b []byte) (*node, error) {
+	p := &unstable.Parser{}
+	p.Reset(b)
+	root := &node{kind: "object", line: 1, col: 1}
+	cur := root
+	for p.NextExpression() {
+		e := p.Expression()
+		switch e.Kind {
+		case unstable.Table, unstable.ArrayTable:
+			cur = root
+			it := e.Key()
+			for it.Next() {
+				k := it.Node()
+				line, col := tomlPos(p, k, root)
+				if !it.IsLast() || e.Kind == unstable.Table {
+					cur = cur.child(string(k.Data), "object", line, col)
+					continue
+				}
+				arr := cur.child(string(k.Data), "array", line, col)
+				t := &node{kind: "object", line: line, col: col}
+				arr.items = append(arr.items, t)
+				cur = t
+			}
+		case unstable.KeyValue:
+			tomlKeyValue(p, cur, e)
+		}
+	}
+	return root, p.Error()
+}
+
+// tomlKeyValue adds a key/value expression, whose key may be dotted, to obj.
+func tomlKeyValue(p *unstable.Parser, obj *node, e *unstable.Node) {
+	it := e.Key()
+	for it.Next() {
+		k := it.Node()
+		line, col := tomlPos(p, k, obj)
+		if !it.IsLast() {
+			obj = obj.child(string(k.Data), "object", line, col)
+			continue
+		}
+		v := tomlValue(p, e.Value(), line, col)
+		obj.fields = append(obj.fields, field{name: string(k.Data), line: line, col: col, value: v})
+	}
+}
+
+// tomlValue converts a value; values without a position of their own take
+// line and col, their key's.
+func tomlValue(p *unstable.Parser, v *unstable.Node, line, col int) *node {
+	n := &node{line: line, col: col}
+	if v.Raw.Length > 0 {
+		n.line, n.col = tomlPos(p, v, n)
+	}
+	switch v.Kind {
+	case unstable.Bool:
+		n.kind = "boolean"
+	case unstable.Integer:
+		n.kind, n.integer = "number", true
+	case unstable.Float:
+		n.kind = "number"
+	case unstable.Array:
+		n.kind = "array"
+		it := v.Children()
+		for it.Next() {
+			n.items = append(n.items, tomlValue(p, it.Node(), n.line, n.col))
+		}
+	case unstable.InlineTable:
+		n.kind = "object"
+		it := v.Children()
+		for it.Next() {
+			tomlKeyValue(p, n, it.Node())
+		}
+	default:
+		// strings, and dates and times, which configs read as strings
+		n.kind, n.value = "string", string(v.Data)
+	}
+	return n
+}
+
+// tomlPos returns the position of n, or that of def when n has none.
+func tomlPos(p *unstable.Parser, n *unstable.Node, def *node) (int, int) {
+	if n.Raw.Length == 0 {
+		return def.line, def.col
+	}
+	s := p.Shape(n.Raw)
+	return s.Start.Line, s.Start.Column
+}
@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheckConfig(t *testing.T) {
+	docs := map[string]string{
+		"c.json": `{
+  "input": {"path": "in.csv", "has_header": "true"},
+  "output": {"partiton_by": ["region"]},
+  "steps": [
+    {"test_runner_step": {"column": "a", "bounds": [1, "x"], "value": "${V:-1}"}},
+    {"test_runner_step": {"column": "a"}, "test_runner_nocols": {}},
+    {"include": "inc.yaml"}
+  ]
+}`,
+		"c.yaml": `input:
+  path: in.csv
+  has_header: "true"
+output:
+  partiton_by: [region]
+steps:
+  - test_runner_step: {column: a, bounds: [1, x], value: "${V:-1}"}
+  - test_runner_step: {column: a}
+    test_runner_nocols:
+  - include: inc.yaml
+`,
+		"c.toml": `[input]
+path = "in.csv"
+has_header = "true"
+
+[output]
+partiton_by = ["region"]
+
+[[steps]]
+test_runner_step = {column = "a", bounds = [1, "x"], value = "${V:-1}"}
+
+[[steps]]
+test_runner_step = {column = "a"}
+test_runner_nocols = {}
+
+[[steps]]
+include = "inc.yaml"
+`,
+	}
+	want := map[string][]string{
+		"c.json": {
+			`c.json:2:45: input.has_header: want boolean, got string "true"`,
+			`c.json:3:14: output: unknown key "partiton_by"; did you mean "partition_by"?`,
+			`c.json:5:56: steps[0].test_runner_step.bounds[1]: want number, got string "x"`,
+			`c.json:6:5: steps[1]: one step per entry, found test_runner_step and test_runner_nocols`,
+		},
+		"c.yaml": {
+			`c.yaml:3:15: input.has_header: want boolean, got string "true"`,
+			`c.yaml:5:3: output: unknown key "partiton_by"; did you mean "partition_by"?`,
+			`c.yaml:7:47: steps[0].test_runner_step.bounds[1]: want number, got string "x"`,
+			`c.yaml:8:5: steps[1]: one step per entry, found test_runner_step and test_runner_nocols`,
+		},
+		"c.toml": {
+			`c.toml:3:14: input.has_header: want boolean, got string "true"`,
+			`c.toml:6:1: output: unknown key "partiton_by"; did you mean "partition_by"?`,
+			`c.toml:9:48: steps[0].test_runner_step.bounds[1]: want number, got string "x"`,
+			`c.toml:11:3: steps[1]: one step per entry, found test_runner_step and test_runner_nocols`,
+		},
+	}
+	dir := t.TempDir()
+	inc := "- test_runner_step: {column: a, colour: 1}\n"
+	if err := os.WriteFile(filepath.Join(dir, "inc.yaml"), []byte(inc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for name, doc := range docs {
+		path := filepath.Join(dir, name)
+		var got []string
+		for _, e := range CheckConfig(path, []byte(doc)) {
+			rel, _ := filepath.Rel(dir, e.File)
+			e.File = rel
+			got = append(got, e.Error())
+		}
+		want := append(want[name], `inc.yaml:1:33: steps[0].test_runner_step: unknown key "colour"`)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s:\ngot  %q\nwant %q", name, got, want)
+		}
+	}
+
+	if errs := CheckConfig("bad.json", []byte(`{"input": {"path": "x",}}`)); len(errs) != 1 || errs[0].Line != 1 {
+		t.Fatalf("syntax error: %v", errs)
+	}
+}
@@ -0,0 +1,192 @@
+// Package runner holds the input and output plumbing shared by the janitor
+// command's subcommands: the config file, opening its input as a frame or a
+// chunk stream, and writing frames and streams to its output.
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	toml "github.com/pelletier/go-toml/v2"
+	iox "github.com/wdm0006/janitor/pkg/io/ioutils"
+	jsonlio "github.com/wdm0006/janitor/pkg/io/jsonlio"
+	parquetio "github.com/wdm0006/janitor/pkg/io/parquetio"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Config is a cleaning config: where to read, where to write and the steps
+// to apply in between. The doc and enum tags of its fields feed
+// ConfigSchema, as those of step params do.
+type Config struct {
+	Input struct {
+		Path           string             `json:"path" doc:"input file, glob (data/*.csv) or - for stdin"`
+		Type           string             `json:"type" enum:"csv,jsonl,parquet" doc:"input format (default csv)"`
+		HasHeader      bool               `json:"has_header" doc:"csv: the first row names the columns"`
+		Delimiter      string             `json:"delimiter" doc:"csv: field delimiter; empty to sniff it"`
+		CSVStrict      bool               `json:"csv_strict" doc:"csv: error on short or long records instead of repairing them"`
+		TimeFormats    []string           `json:"time_formats" doc:"Go layouts or unix/unix_ms; default RFC3339 and ISO dates"`
+		TimeZone       string             `json:"time_zone" doc:"zone for times without an offset (default UTC)"`
+		TrueValues     []string           `json:"true_values" doc:"boolean literals (default true/yes/y/t; add \"1\" to opt in)"`
+		FalseValues    []string           `json:"false_values" doc:"boolean literals (default false/no/n/f; add \"0\" to opt in)"`
+		Schema         iox.SchemaOverride `json:"schema" doc:"pinned column types and names; others are inferred"`
+		OnCastError    string             `json:"on_cast_error" enum:"null,keep_raw,reject,fail" doc:"what to do with values that do not fit their column type (default null)"`
+		Columns        []string           `json:"columns" doc:"jsonl: keys to read, in order (default: all, first-seen order)"`
+		FlattenDepth   int                `json:"flatten_depth" doc:"jsonl: nested object levels to expand into dotted columns (-1 = all)"`
+		Arrays         string             `json:"arrays" enum:"json,join,explode" doc:"jsonl: how arrays are read (default json)"`
+		ArraySeparator string             `json:"array_separator" doc:"jsonl: element separator for arrays \"join\" (default \",\")"`
+		SchemaDrift    string             `json:"schema_drift" enum:"ignore,union,widen,fail" doc:"scan every input file first and merge their schemas (default ignore)"`
+		DriftLog       string             `json:"drift_log" doc:"JSON file listing the schema changes found by the scan"`
+	} `json:"input" doc:"where and how to read the data"`
+	Output struct {
+		Path           string                             `json:"path" doc:"output file or - for stdout; may use {basename} and {col:Name}"`
+		Type           string                             `json:"type" enum:"csv,jsonl,parquet" doc:"output format (default csv)"`
+		Delimiter      string                             `json:"delimiter" doc:"csv: field delimiter (default ,)"`
+		PartitionBy    []string                           `json:"partition_by" doc:"columns whose values split the output into files"`
+		Compression    string                             `json:"compression" doc:"parquet: snappy (default), zstd, gzip or none"`
+		ParquetColumns map[string]parquetio.ColumnOptions `json:"parquet_columns" doc:"parquet: column type overrides (int32, date, decimal, ...)"`
+		Unflatten      bool                               `json:"unflatten" doc:"jsonl: nest dotted column names back into objects"`
+		Rejects        struct {
+			Path      string `json:"path" doc:"file for the rejected rows; may use {basename}"`
+			Type      string `json:"type" enum:"csv,jsonl" doc:"rejects format (default csv)"`
+			Delimiter string `json:"delimiter" doc:"csv: field delimiter (default ,)"`
+		} `json:"rejects" doc:"destination for rows quarantined by validators"`
+	} `json:"output" doc:"where and how to write the result"`
+	Steps []json.RawMessage `json:"steps" doc:"steps to apply, in order"`
+}
+
+// LoadConfig reads and parses the config file at path.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	err = ParseConfig(path, b, &cfg)
+	return cfg, err
+}
+
+// ParseConfig decodes a JSON, YAML or TOML config, picked by the extension
+// of path. The document is resolved before it is decoded: steps entries of
+// the form {"include": file} are replaced by the steps of that file, and
+// ${name} and ${name:-default} references in values are filled from the
+// environment or the config's vars. It is then turned into JSON, so one set
+// of json tags (and the step registry's JSON params) serves every format.
+func ParseConfig(path string, b []byte, cfg *Config) error {
+	doc, err := decodeDocument(path, b)
+	if err != nil {
+		return err
+	}
+	if m, ok := doc.(map[string]any); ok {
+		x, err := newExpander(m["vars"])
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		delete(m, "vars")
+		if abs, err := filepath.Abs(path); err == nil {
+			x.files = []string{abs}
+		}
+		for _, k := range sortedKeys(m) {
+			if k == "steps" {
+				continue
+			}
+			if m[k], err = x.value(m[k], fieldType(reflect.TypeOf(*cfg), k), k); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		if steps, ok := m["steps"]; ok {
+			if m["steps"], err = x.steps(path, steps); err != nil {
+				return err
+			}
+		}
+	}
+	b, err = json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return json.Unmarshal(b, cfg)
+}
+
+// decodeDocument decodes a JSON, YAML or TOML document, picked by the
+// extension of path, into maps, slices and scalars.
+func decodeDocument(path string, b []byte) (any, error) {
+	var doc any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			return nil, err
+		}
+	case ".toml":
+		if err := toml.Unmarshal(b, &doc); err != nil {
+			return nil, err
+		}
+	default:
+		// numbers stay as written, so large integers keep their precision
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			return nil, err
+		}
+		if dec.More() {
+			return nil, fmt.Errorf("%s: invalid JSON after the top-level value", path)
+		}
+	}
+	return doc, nil
+}
+
+// CheckInput reports input settings that are invalid or do not go together,
+// before any data is read.
+func (c Config) CheckInput() error {
+	switch c.Input.Type {
+	case "", "csv", "jsonl", "parquet":
+	default:
+		return fmt.Errorf("unsupported input type %q", c.Input.Type)
+	}
+	if _, err := iox.ParseCastPolicy(c.Input.OnCastError); err != nil {
+		return err
+	}
+	if c.Input.OnCastError == string(iox.CastReject) && c.Output.Rejects.Path == "" {
+		return fmt.Errorf("on_cast_error \"reject\" requires output.rejects.path")
+	}
+	if _, err := jsonlio.ParseArrayMode(c.Input.Arrays); err != nil {
+		return err
+	}
+	if len(c.Input.Columns) > 0 && c.Input.Type != "jsonl" {
+		return fmt.Errorf("input.columns is only supported for jsonl input")
+	}
+	policy, err := iox.ParseDriftPolicy(c.Input.SchemaDrift)
+	if err != nil {
+		return err
+	}
+	if policy != iox.DriftIgnore && c.IsStdin() {
+		return fmt.Errorf("input.schema_drift reads the input twice; stdin cannot be used")
+	}
+	return nil
+}
+
+// CheckOutput reports an unsupported output or output.rejects type.
+func (c Config) CheckOutput() error {
+	switch c.Output.Type {
+	case "", "csv", "jsonl", "parquet":
+	default:
+		return fmt.Errorf("unsupported output type %q", c.Output.Type)
+	}
+	switch c.Output.Rejects.Type {
+	case "", "csv", "jsonl":
+	default:
+		return fmt.Errorf("unsupported output.rejects type %q", c.Output.Rejects.Type)
+	}
+	return nil
+}
+
+// delimiter returns the first rune of d, or def when d is empty.
+func delimiter(d string, def rune) rune {
+	if d == "" {
+		return def
+	}
+	return rune(d[0])
+}
@@ -0,0 +1,131 @@
+package runner
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	docs := map[string]string{
+		"c.json": `{"input": {"path": "in.csv", "has_header": true}, "output": {"path": "out.parquet", "type": "parquet"},
+			"steps": [{"trim": {"column": "name"}}]}`,
+		"c.yaml": "input: {path: in.csv, has_header: true}\noutput: {path: out.parquet, type: parquet}\nsteps:\n  - trim: {column: name}\n",
+		"c.toml": "[input]\npath = \"in.csv\"\nhas_header = true\n[output]\npath = \"out.parquet\"\ntype = \"parquet\"\n[[steps]]\ntrim = {column = \"name\"}\n",
+	}
+	for name, doc := range docs {
+		var cfg Config
+		if err := ParseConfig(name, []byte(doc), &cfg); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if cfg.Input.Path != "in.csv" || !cfg.Input.HasHeader || cfg.Output.Type != "parquet" {
+			t.Fatalf("%s: input %+v output %+v", name, cfg.Input, cfg.Output)
+		}
+		var step map[string]map[string]string
+		if len(cfg.Steps) != 1 || json.Unmarshal(cfg.Steps[0], &step) != nil || step["trim"]["column"] != "name" {
+			t.Fatalf("%s: steps = %s", name, cfg.Steps)
+		}
+		if err := cfg.CheckInput(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+
+	var cfg Config
+	cfg.Input.Type = "xls"
+	if err := cfg.CheckInput(); err == nil {
+		t.Fatal("expected an error for an unsupported input type")
+	}
+	cfg.Input.Type = "csv"
+	cfg.Input.SchemaDrift = "union"
+	if err := cfg.CheckInput(); err == nil {
+		t.Fatal("expected an error for schema_drift on stdin")
+	}
+}
+
+func TestTypeFromPath(t *testing.T) {
+	got := map[string]string{}
+	for _, p := range []string{"a.csv", "a.CSV.gz", "a.ndjson", "dir/a.jsonl.gz", "a.parquet", "a.xlsx", "-"} {
+		got[p] = TypeFromPath(p)
+	}
+	want := map[string]string{"a.csv": "csv", "a.CSV.gz": "csv", "a.ndjson": "jsonl", "dir/a.jsonl.gz": "jsonl", "a.parquet": "parquet", "a.xlsx": "", "-": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestParseConfigTemplating(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"c.yaml": `vars:
+  data: /data/${DATASET}
+  header: true
+  bounds: [1, 2.5]
+input:
+  path: ${data}/in.csv
+  has_header: ${header}
+  delimiter: ${DELIM:-;}
+output:
+  path: $${literal}/${data}.parquet
+steps:
+  - test_runner_step: {column: name, value: "${MIN_AGE:-18}", bounds: "${bounds}"}
+  - include: shared/common.json
+  - test_runner_step: {column: "${COL}", expr: "${MIN_AGE:-18}"}
+`,
+		"shared/common.json": `[{"include": ["more.toml"]}, {"trim": {"column": "${DATASET}_id"}}]`,
+		"shared/more.toml":   "[[steps]]\ndedupe = {columns = \"${KEYS:-a|b}\"}\n",
+	}
+	for name, doc := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("DATASET", "sales")
+	t.Setenv("COL", "city")
+	cfg, err := LoadConfig(filepath.Join(dir, "c.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Input.Path != "/data/sales/in.csv" || !cfg.Input.HasHeader || cfg.Input.Delimiter != ";" || cfg.Output.Path != "${literal}//data/sales.parquet" {
+		t.Fatalf("input %+v output %+v", cfg.Input, cfg.Output)
+	}
+	var steps []string
+	for _, s := range cfg.Steps {
+		steps = append(steps, string(s))
+	}
+	want := []string{
+		`{"test_runner_step":{"bounds":[1,2.5],"column":"name","value":18}}`,
+		`{"dedupe":{"columns":"a|b"}}`,
+		`{"trim":{"column":"sales_id"}}`,
+		`{"test_runner_step":{"column":"city","expr":"18"}}`,
+	}
+	if !reflect.DeepEqual(steps, want) {
+		t.Fatalf("steps = %v, want %v", steps, want)
+	}
+
+	// the environment overrides vars
+	t.Setenv("header", "false")
+	if cfg, err = LoadConfig(filepath.Join(dir, "c.yaml")); err != nil || cfg.Input.HasHeader {
+		t.Fatalf("has_header = %v, %v", cfg.Input.HasHeader, err)
+	}
+
+	errs := map[string]string{
+		"unset.json":   `{"input": {"path": "${NOT_SET_ANYWHERE}"}}`,
+		"cycle.json":   `{"steps": [{"include": "cycle.json"}]}`,
+		"extra.json":   `{"steps": [{"include": "shared/more.toml", "when": "x > 1"}]}`,
+		"selfvar.json": `{"vars": {"a": "${a}"}, "input": {"path": "${a}"}}`,
+	}
+	for name, doc := range errs {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(doc), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadConfig(filepath.Join(dir, name)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package runner
+
+import (
+	"reflect"
+	"strings"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+// refPattern matches a value that is a single ${name} or ${name:-default}
+// reference, which ParseConfig types like the setting it fills.
+const refPattern = `^\$\{[A-Za-z_][A-Za-z0-9_]*(:-[^}]*)?\}$`
+
+// ConfigSchema returns the JSON Schema (draft 2020-12) of config files: the
+// settings of Config, read from its json, doc and enum tags, and for steps
+// the params of every registered step. Editors use it to complete and check
+// configs; CheckConfig checks files against it.
+func ConfigSchema() map[string]any {
+	root := typeSchema(reflect.TypeOf(Config{}))
+	props := root["properties"].(map[string]any)
+	props["steps"] = map[string]any{
+		"type":        "array",
+		"description": "steps to apply, in order",
+		"items":       stepSchema(),
+	}
+	props["vars"] = map[string]any{
+		"type":        "object",
+		"description": "values of ${name} references; an environment variable of the same name overrides them",
+	}
+	props["$schema"] = map[string]any{"type": "string", "description": "JSON Schema of this file, for editors"}
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["title"] = "janitor config"
+	return root
+}
+
+// stepSchema is the schema of one entry of steps: a step name with its
+// params and an optional when, or an include.
+func stepSchema() map[string]any {
+	props := map[string]any{
+		"when": map[string]any{"type": "string", "description": "expression limiting the step to the rows where it is true"},
+		"include": map[string]any{
+			"description": "file, or list of files, whose steps replace this entry",
+			"anyOf": []any{
+				map[string]any{"type": "string"},
+				map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			},
+		},
+	}
+	for _, st := range j.Steps() {
+		params := map[string]any{}
+		var required []string
+		for _, p := range st.Params {
+			params[p.Name] = propertySchema(p.Type, p.Doc, p.Enum)
+			if p.Required {
+				required = append(required, p.Name)
+			}
+		}
+		s := map[string]any{
+			// YAML writes a step without params as "- dedupe:"
+			"type":                 []any{"object", "null"},
+			"description":          st.Doc,
+			"properties":           params,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		props[st.Name] = s
+	}
+	return map[string]any{
+		"type":                 "object",
+		"description":          "a step name with its params, or an include",
+		"properties":           props,
+		"additionalProperties": false,
+		"minProperties":        1,
+	}
+}
+
+// typeSchema returns the schema of values that decode into t.
+func typeSchema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch typ := (j.ParamSpec{Type: t}).JSONType(); typ {
+	case "any":
+		return map[string]any{}
+	case "string":
+		return map[string]any{"type": "string"}
+	case "array":
+		return templated(map[string]any{"type": "array", "items": typeSchema(t.Elem())})
+	case "object":
+		if t.Kind() == reflect.Map {
+			return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+		}
+		props := map[string]any{}
+		for _, f := range jsonFields(t) {
+			var enum []string
+			if e := f.Tag.Get("enum"); e != "" {
+				enum = strings.Split(e, ",")
+			}
+			props[f.Name] = propertySchema(f.Type, f.Tag.Get("doc"), enum)
+		}
+		return map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	default:
+		return templated(map[string]any{"type": typ})
+	}
+}
+
+// propertySchema is typeSchema with a description and allowed values.
+func propertySchema(t reflect.Type, doc string, enum []string) map[string]any {
+	s := typeSchema(t)
+	if len(enum) > 0 {
+		s = templated(map[string]any{"type": "string", "enum": enum})
+	}
+	if doc != "" {
+		s["description"] = doc
+	}
+	return s
+}
+
+// templated lets a ${name} reference stand for a value of schema s.
+func templated(s map[string]any) map[string]any {
+	return map[string]any{"anyOf": []any{s, map[string]any{"type": "string", "pattern": refPattern}}}
+}
+
+// jsonFields returns the fields of struct t by their json names, including
+// those of embedded structs; Name holds the json name.
+func jsonFields(t reflect.Type) []reflect.StructField {
+	var out []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			out = append(out, jsonFields(f.Type)...)
+			continue
+		}
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name != "" {
+			f.Name = name
+		}
+		out = append(out, f)
+	}
+	return out
+}
@@ -0,0 +1,142 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+
+	csvio "github.com/wdm0006/janitor/pkg/io/csvio"
+	iox "github.com/wdm0006/janitor/pkg/io/ioutils"
+	jsonlio "github.com/wdm0006/janitor/pkg/io/jsonlio"
+	parquetio "github.com/wdm0006/janitor/pkg/io/parquetio"
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+// Merged is the schema every input file is read with under
+// input.schema_drift, built by ScanInputs.
+type Merged struct {
+	target j.Schema            // merged schema plus <col>__raw columns for keep_raw
+	types  j.Schema            // merged schema
+	files  map[string]j.Schema // each file's own scanned schema
+}
+
+// Schema returns the schema every file is aligned to.
+func (m *Merged) Schema() j.Schema { return m.target }
+
+// ScanDrift applies the config's schema_drift policy: every input file is
+// scanned first so that all of them are read with one merged schema. It
+// returns nil under the default policy, ignore. The schema changes found are
+// written to input.drift_log and, when log is set, to log.
+func ScanDrift(cfg Config, log io.Writer) (*Merged, error) {
+	policy, err := iox.ParseDriftPolicy(cfg.Input.SchemaDrift)
+	if err != nil || policy == iox.DriftIgnore {
+		return nil, err
+	}
+	if cfg.IsStdin() {
+		return nil, fmt.Errorf("input.schema_drift reads the input twice; stdin cannot be used")
+	}
+	paths, err := InputPaths(cfg)
+	if err != nil {
+		return nil, err
+	}
+	drift := iox.NewDrift(policy)
+	merged, err := ScanInputs(cfg, paths, drift)
+	if err != nil {
+		return nil, err
+	}
+	if log != nil {
+		for _, e := range drift.Events {
+			fmt.Fprintf(log, "schema drift: %s\n", e)
+		}
+	}
+	if cfg.Input.DriftLog != "" {
+		if err := drift.WriteLog(cfg.Input.DriftLog); err != nil {
+			return nil, err
+		}
+	}
+	return merged, nil
+}
+
+// ScanInputs reads every input file once and merges their schemas under the
+// drift policy.
+func ScanInputs(cfg Config, paths []string, drift *iox.Drift) (*Merged, error) {
+	ms := &Merged{files: map[string]j.Schema{}}
+	var all j.Schema
+	for _, in := range paths {
+		var s j.Schema
+		var err error
+		switch cfg.Input.Type {
+		case "", "csv":
+			s, err = csvio.ScanSchema(in, CSVReaderOptions(cfg, 100), drift)
+		case "jsonl":
+			s, err = jsonlio.ScanSchema(in, JSONLReaderOptions(cfg, 100), drift)
+		case "parquet":
+			var pr *parquetio.Reader
+			if pr, err = parquetio.OpenReader(in, ParquetReaderOptions(cfg)); err == nil {
+				s = pr.Schema()
+				_ = pr.Close()
+			}
+		default:
+			return nil, fmt.Errorf("unsupported input type %q", cfg.Input.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", in, err)
+		}
+		ms.files[in] = s
+		if all, err = drift.Merge(all, s, in, 0); err != nil {
+			return nil, err
+		}
+	}
+	ms.types = iox.FillUnknownKinds(all)
+	policy, _ := iox.ParseCastPolicy(cfg.Input.OnCastError)
+	ms.target = iox.NewCastErrors(policy, nil).Schema(ms.types)
+	return ms, nil
+}
+
+// driftConfig returns cfg with input.schema extended so that file in is read
+// with the merged column types; columns the user pinned keep their override.
+func driftConfig(cfg Config, ms *Merged, in string) Config {
+	if ms == nil {
+		return cfg
+	}
+	o := iox.SchemaOverride{Columns: append([]iox.ColumnOverride(nil), cfg.Input.Schema.Columns...)}
+	pinned := map[string]bool{}
+	for _, c := range o.Columns {
+		if c.Rename != "" {
+			pinned[c.Rename] = true
+		} else {
+			pinned[c.Name] = true
+		}
+	}
+	for _, cs := range ms.files[in].Columns {
+		i := ms.types.Index(cs.Name)
+		if pinned[cs.Name] || i < 0 {
+			continue
+		}
+		o.Columns = append(o.Columns, iox.ColumnOverride{Name: cs.Name, Type: ms.types.Columns[i].Type})
+	}
+	cfg.Input.Schema = o
+	return cfg
+}
+
+// alignedSource conforms each chunk of one file to the merged schema.
+type alignedSource struct {
+	StreamSource
+	schema j.Schema
+}
+
+func (a alignedSource) Next() (*j.Frame, error) {
+	f, err := a.StreamSource.Next()
+	if err != nil {
+		return nil, err
+	}
+	return iox.Align(f, a.schema)
+}
+
+func (a alignedSource) Schema() j.Schema { return a.schema }
+
+func (a alignedSource) CastFailures() map[string]int {
+	if cr, ok := a.StreamSource.(interface{ CastFailures() map[string]int }); ok {
+		return cr.CastFailures()
+	}
+	return nil
+}
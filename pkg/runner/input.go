@@ -0,0 +1,310 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	csvio "github.com/wdm0006/janitor/pkg/io/csvio"
+	iox "github.com/wdm0006/janitor/pkg/io/ioutils"
+	jsonlio "github.com/wdm0006/janitor/pkg/io/jsonlio"
+	parquetio "github.com/wdm0006/janitor/pkg/io/parquetio"
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+// CSVReaderOptions, JSONLReaderOptions and ParquetReaderOptions map the input
+// config onto each reader's options.
+func CSVReaderOptions(cfg Config, sampleRows int) csvio.ReaderOptions {
+	in := cfg.Input
+	return csvio.ReaderOptions{HasHeader: in.HasHeader, Delimiter: delimiter(in.Delimiter, 0), SampleRows: sampleRows, Strict: in.CSVStrict,
+		TimeFormats: in.TimeFormats, TimeZone: in.TimeZone, TrueValues: in.TrueValues, FalseValues: in.FalseValues, Schema: in.Schema,
+		OnCastError: iox.CastPolicy(in.OnCastError)}
+}
+
+func JSONLReaderOptions(cfg Config, sampleRows int) jsonlio.ReaderOptions {
+	in := cfg.Input
+	return jsonlio.ReaderOptions{SampleRows: sampleRows,
+		TimeFormats: in.TimeFormats, TimeZone: in.TimeZone, TrueValues: in.TrueValues, FalseValues: in.FalseValues, Schema: in.Schema,
+		OnCastError: iox.CastPolicy(in.OnCastError), Columns: in.Columns,
+		FlattenDepth: in.FlattenDepth, Arrays: jsonlio.ArrayMode(in.Arrays), ArraySeparator: in.ArraySeparator}
+}
+
+func ParquetReaderOptions(cfg Config) parquetio.ReaderOptions {
+	in := cfg.Input
+	return parquetio.ReaderOptions{Schema: in.Schema,
+		TimeFormats: in.TimeFormats, TimeZone: in.TimeZone, TrueValues: in.TrueValues, FalseValues: in.FalseValues,
+		OnCastError: iox.CastPolicy(in.OnCastError)}
+}
+
+// CastSummary formats the per-column cast failures of a reader as
+// "col=n, ...", or "" when there were none or the reader does not count them.
+func CastSummary(r any) string {
+	cr, ok := r.(interface{ CastFailures() map[string]int })
+	if !ok {
+		return ""
+	}
+	counts := cr.CastFailures()
+	names := make([]string, 0, len(counts))
+	for k := range counts {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, k := range names {
+		parts[i] = fmt.Sprintf("%s=%d", k, counts[k])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// IsStdin reports whether the input is read from stdin, which can be read
+// only once.
+func (c Config) IsStdin() bool { return c.Input.Path == "-" || c.Input.Path == "" }
+
+// TypeFromPath returns the input or output type a path's extension names:
+// csv, jsonl or parquet, looking past a .gz suffix. It returns "" for other
+// extensions.
+func TypeFromPath(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".gz" {
+		ext = strings.ToLower(filepath.Ext(strings.TrimSuffix(path, filepath.Ext(path))))
+	}
+	switch ext {
+	case ".csv":
+		return "csv"
+	case ".jsonl", ".ndjson":
+		return "jsonl"
+	case ".parquet", ".pq":
+		return "parquet"
+	}
+	return ""
+}
+
+// InferSchema returns the schema the first input file is read with, inferred
+// from its first sampleRows rows.
+func InferSchema(cfg Config, sampleRows int) (j.Schema, error) {
+	paths, err := InputPaths(cfg)
+	if err != nil {
+		return j.Schema{}, err
+	}
+	switch cfg.Input.Type {
+	case "", "csv":
+		rdr, f, err := csvio.Open(paths[0], CSVReaderOptions(cfg, sampleRows))
+		if err != nil {
+			return j.Schema{}, err
+		}
+		if f != nil {
+			defer func() { _ = f.Close() }()
+		}
+		s, _, err := rdr.InferSchema()
+		return s, err
+	case "jsonl":
+		jr, jf, err := jsonlio.Open(paths[0], JSONLReaderOptions(cfg, sampleRows))
+		if err != nil {
+			return j.Schema{}, err
+		}
+		if jf != nil {
+			defer func() { _ = jf.Close() }()
+		}
+		return jr.InferSchema()
+	case "parquet":
+		pr, err := parquetio.OpenReader(paths[0], ParquetReaderOptions(cfg))
+		if err != nil {
+			return j.Schema{}, err
+		}
+		defer func() { _ = pr.Close() }()
+		return pr.Schema(), nil
+	default:
+		return j.Schema{}, fmt.Errorf("unsupported input type %q", cfg.Input.Type)
+	}
+}
+
+// ReadFrame reads the whole input into one frame; the files of a glob are
+// read in order and concatenated, so they must share a schema unless a
+// merged schema is given. Rows the reader rejects under on_cast_error
+// "reject" go to rejects; with a merged schema the frame is aligned to it.
+// When log is set, a summary of each read is written to it.
+func ReadFrame(cfg Config, merged *Merged, rejects j.ChunkSink, log io.Writer) (*j.Frame, error) {
+	paths, err := InputPaths(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var frame *j.Frame
+	for _, in := range paths {
+		f, err := readFile(cfg, in, merged, rejects, log)
+		if err != nil {
+			return nil, err
+		}
+		if frame == nil {
+			frame = f
+			continue
+		}
+		if err := frame.Append(f); err != nil {
+			return nil, fmt.Errorf("%s: schema differs from %s; set input.schema_drift to read the files together (%w)", in, paths[0], err)
+		}
+	}
+	return frame, nil
+}
+
+// readFile reads input file in into one frame, as ReadFrame.
+func readFile(cfg Config, in string, merged *Merged, rejects j.ChunkSink, log io.Writer) (*j.Frame, error) {
+	var frame *j.Frame
+	switch cfg.Input.Type {
+	case "", "csv":
+		opt := CSVReaderOptions(driftConfig(cfg, merged, in), 100)
+		opt.Rejects = rejects
+		rdr, file, err := csvio.Open(in, opt)
+		if err != nil {
+			return nil, err
+		}
+		if file != nil {
+			defer func() { _ = file.Close() }()
+		}
+		schema, _, err := rdr.InferSchema()
+		if err != nil {
+			return nil, err
+		}
+		if frame, err = rdr.ReadAll(schema); err != nil {
+			return nil, err
+		}
+		if log != nil {
+			fmt.Fprintf(log, "read csv: rows=%d cols=%d from %s\n", frame.Rows(), len(schema.Columns), in)
+			if w := rdr.Warnings(); w != "" {
+				fmt.Fprintf(log, "csv repair summary: %s\n", w)
+			}
+			if c := CastSummary(rdr); c != "" {
+				fmt.Fprintf(log, "cast failures: %s\n", c)
+			}
+		}
+	case "jsonl":
+		opt := JSONLReaderOptions(driftConfig(cfg, merged, in), 100)
+		opt.Rejects = rejects
+		jr, jf, err := jsonlio.Open(in, opt)
+		if err != nil {
+			return nil, err
+		}
+		if jf != nil {
+			defer func() { _ = jf.Close() }()
+		}
+		schema, err := jr.InferSchema()
+		if err != nil {
+			return nil, err
+		}
+		if frame, err = jr.ReadAll(schema); err != nil {
+			return nil, err
+		}
+		if log != nil {
+			fmt.Fprintf(log, "read jsonl: rows=%d cols=%d from %s\n", frame.Rows(), len(schema.Columns), in)
+			if c := CastSummary(jr); c != "" {
+				fmt.Fprintf(log, "cast failures: %s\n", c)
+			}
+		}
+	case "parquet":
+		opt := ParquetReaderOptions(driftConfig(cfg, merged, in))
+		opt.Rejects = rejects
+		pr, err := parquetio.OpenReader(in, opt)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = pr.Close() }()
+		if frame, err = pr.ReadAll(); err != nil {
+			return nil, err
+		}
+		if log != nil {
+			fmt.Fprintf(log, "read parquet: rows=%d cols=%d from %s\n", frame.Rows(), len(frame.Schema().Columns), in)
+			if c := CastSummary(pr); c != "" {
+				fmt.Fprintf(log, "cast failures: %s\n", c)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported input type %q", cfg.Input.Type)
+	}
+	if merged != nil {
+		return iox.Align(frame, merged.target)
+	}
+	return frame, nil
+}
+
+// StreamSource is a chunked reader that knows its schema up front.
+type StreamSource interface {
+	j.ChunkSource
+	Schema() j.Schema
+}
+
+// InputPaths expands a glob in input.path; other paths are returned as is.
+func InputPaths(cfg Config) ([]string, error) {
+	if !HasWildcards(cfg.Input.Path) {
+		return []string{cfg.Input.Path}, nil
+	}
+	matches, _ := filepath.Glob(cfg.Input.Path)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no files matched input path pattern")
+	}
+	return matches, nil
+}
+
+// HasWildcards reports whether path is a glob.
+func HasWildcards(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// OpenStream opens one input file as a chunk source of the configured type.
+// Rows the reader rejects under on_cast_error "reject" go to rejects. With a
+// merged schema every chunk is aligned to it.
+func OpenStream(cfg Config, in string, chunkSize int, rejects j.ChunkSink, merged *Merged) (StreamSource, func() error, error) {
+	if merged != nil {
+		sr, closeIn, err := OpenStream(driftConfig(cfg, merged, in), in, chunkSize, rejects, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		return alignedSource{StreamSource: sr, schema: merged.target}, closeIn, nil
+	}
+	switch cfg.Input.Type {
+	case "", "csv":
+		opt := CSVReaderOptions(cfg, 100)
+		opt.Rejects = rejects
+		sr, f, err := csvio.NewStreamReader(in, opt, chunkSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		return sr, func() error {
+			if f == nil {
+				return nil
+			}
+			return f.Close()
+		}, nil
+	case "jsonl":
+		opt := JSONLReaderOptions(cfg, 100)
+		opt.Rejects = rejects
+		sr, f, err := jsonlio.NewStreamReader(in, opt, chunkSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		return sr, f.Close, nil
+	case "parquet":
+		opt := ParquetReaderOptions(cfg)
+		opt.Rejects = rejects
+		sr, err := parquetio.NewStreamReader(in, opt, chunkSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		return sr, sr.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported input type %q", cfg.Input.Type)
+	}
+}
+
+// PeekSchema opens the first input file for its schema alone.
+func PeekSchema(cfg Config, chunkSize int, merged *Merged) (j.Schema, error) {
+	paths, err := InputPaths(cfg)
+	if err != nil {
+		return j.Schema{}, err
+	}
+	sr, closeIn, err := OpenStream(cfg, paths[0], chunkSize, nil, merged)
+	if err != nil {
+		return j.Schema{}, err
+	}
+	defer func() { _ = closeIn() }()
+	return sr.Schema(), nil
+}
@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	parquetio "github.com/wdm0006/janitor/pkg/io/parquetio"
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+func TestReadFrameGlob(t *testing.T) {
+	dir := t.TempDir()
+	s := j.Schema{Columns: []j.ColumnSchema{{Name: "id", Type: j.KindInt}, {Name: "name", Type: j.KindString}}}
+	for i, names := range [][]string{{"a", "b"}, {"c"}} {
+		f := j.NewFrame(s)
+		for r, name := range names {
+			f.AppendNullRow()
+			_ = f.SetCell(r, "id", int64(i*10+r))
+			_ = f.SetCell(r, "name", name)
+		}
+		if err := parquetio.WriteAll(filepath.Join(dir, fmt.Sprintf("part%d.parquet", i+1)), f, parquetio.WriterOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var cfg Config
+	cfg.Input.Type = "parquet"
+	cfg.Input.Path = filepath.Join(dir, "part*.parquet")
+	frame, err := ReadFrame(cfg, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	col, _ := frame.ColumnByName("name")
+	var got []any
+	for i := 0; i < frame.Rows(); i++ {
+		got = append(got, j.Value(col, i))
+	}
+	if want := []any{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("names %v, want %v", got, want)
+	}
+
+	// CSV files with different columns need a drift policy
+	for name, data := range map[string]string{"a.csv": "id,x\n1,2\n", "b.csv": "id,y\n3,4\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg = Config{}
+	cfg.Input.Path = filepath.Join(dir, "*.csv")
+	cfg.Input.HasHeader = true
+	if _, err := ReadFrame(cfg, nil, nil, nil); err == nil || !strings.Contains(err.Error(), "schema_drift") {
+		t.Fatalf("expected a schema drift error, got %v", err)
+	}
+	cfg.Input.SchemaDrift = "union"
+	merged, err := ScanDrift(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame, err = ReadFrame(cfg, merged, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := frame.Schema().Names(); frame.Rows() != 2 || !reflect.DeepEqual(got, []string{"id", "x", "y"}) {
+		t.Fatalf("union read %d rows of %v", frame.Rows(), got)
+	}
+}
@@ -0,0 +1,139 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	csvio "github.com/wdm0006/janitor/pkg/io/csvio"
+	jsonlio "github.com/wdm0006/janitor/pkg/io/jsonlio"
+	parquetio "github.com/wdm0006/janitor/pkg/io/parquetio"
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+func ParquetWriterOptions(cfg Config) parquetio.WriterOptions {
+	return parquetio.WriterOptions{Compression: cfg.Output.Compression, Columns: cfg.Output.ParquetColumns}
+}
+
+// WriteFrame writes f to output.path as the configured output type.
+func WriteFrame(cfg Config, f *j.Frame) error {
+	switch cfg.Output.Type {
+	case "", "csv":
+		return csvio.WriteAll(cfg.Output.Path, f, csvio.WriterOptions{Delimiter: delimiter(cfg.Output.Delimiter, ',')})
+	case "jsonl":
+		return jsonlio.WriteAll(cfg.Output.Path, f, jsonlio.WriterOptions{Unflatten: cfg.Output.Unflatten})
+	case "parquet":
+		return parquetio.WriteAll(cfg.Output.Path, f, ParquetWriterOptions(cfg))
+	default:
+		return fmt.Errorf("unsupported output type %q", cfg.Output.Type)
+	}
+}
+
+// SinkFunc opens a streaming writer for path; schema is that of the frames
+// it will be given.
+type SinkFunc func(path string, schema j.Schema) (j.ChunkSink, error)
+
+// SinkFactory returns a constructor for streaming writers of the configured
+// output type.
+func SinkFactory(cfg Config) (SinkFunc, error) {
+	switch cfg.Output.Type {
+	case "", "csv":
+		opt := csvio.WriterOptions{Delimiter: delimiter(cfg.Output.Delimiter, ',')}
+		return func(path string, schema j.Schema) (j.ChunkSink, error) {
+			return csvio.NewStreamWriter(path, schema, opt)
+		}, nil
+	case "jsonl":
+		opt := jsonlio.WriterOptions{Unflatten: cfg.Output.Unflatten}
+		return func(path string, schema j.Schema) (j.ChunkSink, error) { return jsonlio.NewStreamWriter(path, opt) }, nil
+	case "parquet":
+		opt := ParquetWriterOptions(cfg)
+		return func(path string, schema j.Schema) (j.ChunkSink, error) {
+			return parquetio.NewStreamWriter(path, schema, opt)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported output type %q for streaming", cfg.Output.Type)
+	}
+}
+
+// ExpandBasename replaces {basename} in path with the name of input file in,
+// without its extension.
+func ExpandBasename(path, in string) string {
+	if !strings.Contains(path, "{basename}") {
+		return path
+	}
+	base := filepath.Base(in)
+	return strings.ReplaceAll(path, "{basename}", strings.TrimSuffix(base, filepath.Ext(base)))
+}
+
+// RejectSink returns the sink for quarantined rows configured in
+// output.rejects, or nil if there is none. {basename} in the path is expanded
+// from in.
+func RejectSink(cfg Config, in string) j.ChunkSink {
+	rc := cfg.Output.Rejects
+	if rc.Path == "" {
+		return nil
+	}
+	path := ExpandBasename(rc.Path, in)
+	return &lazySink{open: func(schema j.Schema) (j.ChunkSink, error) {
+		if rc.Type == "jsonl" {
+			return jsonlio.NewStreamWriter(path, jsonlio.WriterOptions{})
+		}
+		return csvio.NewStreamWriter(path, schema, csvio.WriterOptions{Delimiter: delimiter(rc.Delimiter, ',')})
+	}}
+}
+
+// lazySink creates its underlying sink on the first write, so the sink takes
+// the schema of the first frame (reject frames carry extra columns). Later
+// frames may lack some of its columns, when steps between validators drop
+// them. If nothing is written, no file is created.
+type lazySink struct {
+	open func(schema j.Schema) (j.ChunkSink, error)
+	sink j.ChunkSink
+}
+
+func (l *lazySink) Write(f *j.Frame) error {
+	if l.sink == nil {
+		s, err := l.open(f.Schema())
+		if err != nil {
+			return err
+		}
+		l.sink = s
+	}
+	return l.sink.Write(f)
+}
+
+func (l *lazySink) Close() error {
+	if l.sink == nil {
+		return nil
+	}
+	return l.sink.Close()
+}
+
+// WriteReport writes the validation report to path ("-" for stdout). A nil
+// report writes nothing.
+func WriteReport(path string, r *j.ValidationReport) error {
+	if r == nil {
+		return nil
+	}
+	if path == "-" {
+		return r.WriteJSON(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := r.WriteJSON(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Discard is a sink that drops every frame, for runs that only check data.
+var Discard j.ChunkSink = discard{}
+
+type discard struct{}
+
+func (discard) Write(*j.Frame) error { return nil }
+func (discard) Close() error         { return nil }
@@ -0,0 +1,135 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+// ParseStep turns a step given on the command line into an entry of a
+// config's steps, so it is decoded and built like the steps of a config file.
+// spec is either a JSON object, as in a config, or name[:args], where args is
+// a comma-separated list of columns and key=value params:
+//
+//	trim:name                       {"trim": {"column": "name"}}
+//	select:id,name                  {"select": {"columns": ["id", "name"]}}
+//	impute_constant:age,value=0     {"impute_constant": {"column": "age", "value": 0}}
+//	validate_in:status,values=a|b   {"validate_in": {"column": "status", "values": ["a", "b"]}}
+//	filter:expr=age >= 18           {"filter": {"expr": "age >= 18"}}
+//	trim:name,when=country == 'US'  {"trim": {"column": "name"}, "when": "country == 'US'"}
+//
+// Commas inside quotes or brackets do not split args. Values are typed by
+// the step's params: string params take the value as is, list params split
+// it on |, and others read it as JSON when it parses.
+func ParseStep(spec string) (json.RawMessage, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "{") {
+		var probe map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(spec), &probe); err != nil {
+			return nil, fmt.Errorf("step %s: %v", spec, err)
+		}
+		return json.RawMessage(spec), nil
+	}
+	name, args, _ := strings.Cut(spec, ":")
+	if name == "" {
+		return nil, fmt.Errorf("step %q: no step name", spec)
+	}
+	types := map[string]string{}
+	elems := map[string]string{}
+	info, known := j.LookupStep(name)
+	if known {
+		for _, p := range info.Params {
+			types[p.Name] = p.JSONType()
+			if types[p.Name] == "array" {
+				elems[p.Name] = j.ParamSpec{Type: p.Type.Elem()}.JSONType()
+			}
+		}
+	}
+	params := map[string]json.RawMessage{}
+	step := map[string]any{name: params}
+	var columns []string
+	for _, arg := range splitArgs(args) {
+		key, val, ok := strings.Cut(arg, "=")
+		if !ok {
+			columns = append(columns, strings.TrimSpace(arg))
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if key == "when" {
+			step["when"] = val
+			continue
+		}
+		if _, dup := params[key]; dup {
+			return nil, fmt.Errorf("step %q: param %q given twice", spec, key)
+		}
+		params[key] = paramValue(val, types[key], elems[key])
+	}
+	if len(columns) > 0 {
+		_, hasColumn := types["column"]
+		_, hasColumns := types["columns"]
+		switch {
+		case known && !hasColumn && !hasColumns:
+			return nil, fmt.Errorf("step %q: %s takes no columns; give params as key=value", spec, name)
+		case len(columns) == 1 && (hasColumn || !known):
+			params["column"], _ = json.Marshal(columns[0])
+		default:
+			params["columns"], _ = json.Marshal(columns)
+		}
+	}
+	return json.Marshal(step)
+}
+
+// paramValue encodes a key=value value as the JSON type a param wants.
+func paramValue(val, typ, elem string) json.RawMessage {
+	switch {
+	case typ == "string":
+		var s string
+		if json.Unmarshal([]byte(val), &s) == nil {
+			return json.RawMessage(val)
+		}
+		b, _ := json.Marshal(val)
+		return b
+	case typ == "array" && !(strings.HasPrefix(val, "[") && json.Valid([]byte(val))):
+		parts := strings.Split(val, "|")
+		out := make([]json.RawMessage, len(parts))
+		for i, p := range parts {
+			out[i] = paramValue(p, elem, "")
+		}
+		b, _ := json.Marshal(out)
+		return b
+	case json.Valid([]byte(val)):
+		return json.RawMessage(val)
+	}
+	b, _ := json.Marshal(val)
+	return b
+}
+
+// splitArgs splits s on commas outside quotes and brackets.
+func splitArgs(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var out []string
+	var quote rune
+	depth, start := 0, 0
+	for i, c := range s {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			out = append(out, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(out, s[start:])
+}
@@ -0,0 +1,52 @@
+package runner
+
+import (
+	"testing"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+type testParams struct {
+	j.ColumnParams
+	Value  any       `json:"value"`
+	Values []string  `json:"values"`
+	Bounds []float64 `json:"bounds"`
+	Expr   string    `json:"expr"`
+}
+
+func init() {
+	j.RegisterStep("test_runner_step", "test", func(p *testParams, b *j.StepBuilder) error { return nil })
+	j.RegisterStep("test_runner_nocols", "test", func(p *struct {
+		Expr string `json:"expr"`
+	}, b *j.StepBuilder) error {
+		return nil
+	})
+}
+
+func TestParseStep(t *testing.T) {
+	cases := []struct{ spec, want string }{
+		{"test_runner_step:name", `{"test_runner_step":{"column":"name"}}`},
+		{"test_runner_step:a, b", `{"test_runner_step":{"columns":["a","b"]}}`},
+		{"test_runner_step:type:string,value=0", `{"test_runner_step":{"column":"type:string","value":0}}`},
+		{"test_runner_step:s,value=n/a,values=1|x,bounds=1|2.5", `{"test_runner_step":{"bounds":[1,2.5],"column":"s","value":"n/a","values":["1","x"]}}`},
+		{`test_runner_step:expr=x in ("a", 'b,c'),when=y > 1`, `{"test_runner_step":{"expr":"x in (\"a\", 'b,c')"},"when":"y \u003e 1"}`},
+		{"test_runner_step:re:^a{1,2}$", `{"test_runner_step":{"column":"re:^a{1,2}$"}}`},
+		{"test_runner_nocols:expr=1", `{"test_runner_nocols":{"expr":"1"}}`},
+		{"unknown_step:a", `{"unknown_step":{"column":"a"}}`},
+		{`{"trim": {"column": "x"}}`, `{"trim": {"column": "x"}}`},
+	}
+	for _, c := range cases {
+		got, err := ParseStep(c.spec)
+		if err != nil {
+			t.Fatalf("%s: %v", c.spec, err)
+		}
+		if string(got) != c.want {
+			t.Fatalf("%s = %s, want %s", c.spec, got, c.want)
+		}
+	}
+	for _, bad := range []string{"", ":a", "test_runner_nocols:a", "test_runner_step:value=1,value=2", "{nope"} {
+		if _, err := ParseStep(bad); err == nil {
+			t.Fatalf("%q: expected an error", bad)
+		}
+	}
+}
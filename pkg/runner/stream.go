@@ -0,0 +1,295 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+// FitStream makes the fitting pass of a two-pass streaming run over every
+// input file, so that glob inputs are fitted as one dataset.
+func FitStream(ctx context.Context, p *j.Pipeline, cfg Config, chunkSize int, merged *Merged) error {
+	paths, err := InputPaths(cfg)
+	if err != nil {
+		return err
+	}
+	for _, in := range paths {
+		sr, closeIn, err := OpenStream(cfg, in, chunkSize, nil, merged)
+		if err != nil {
+			return err
+		}
+		err = p.FitStream(ctx, sr)
+		_ = closeIn()
+		if err != nil {
+			return err
+		}
+	}
+	p.FinishFit()
+	return nil
+}
+
+// Progress configures the progress lines a streaming run prints every second.
+type Progress struct {
+	Log      io.Writer // where progress goes; nil disables it
+	Expected int       // expected total rows, for a bar and an ETA
+	JSON     bool      // print JSON lines instead of text
+}
+
+// progressMeter keeps the smoothed row rate between ticks.
+type progressMeter struct {
+	Progress
+	start time.Time
+	rates []float64
+}
+
+func (m *progressMeter) tick(rows int) {
+	elapsed := time.Since(m.start).Seconds()
+	m.rates = append(m.rates, float64(rows)/(elapsed+1e-9))
+	if len(m.rates) > 5 {
+		m.rates = m.rates[len(m.rates)-5:]
+	}
+	var sum float64
+	for _, r := range m.rates {
+		sum += r
+	}
+	rate := sum / float64(len(m.rates))
+	if m.Expected <= 0 {
+		if m.JSON {
+			b, _ := json.Marshal(map[string]any{"type": "progress", "rows": rows, "rate_rps": rate})
+			fmt.Fprintln(m.Log, string(b))
+		} else {
+			fmt.Fprintf(m.Log, "processed rows=%d (%.1f rows/s) ...\n", rows, rate)
+		}
+		return
+	}
+	remaining := m.Expected - rows
+	if remaining < 0 {
+		remaining = 0
+	}
+	eta := time.Duration(float64(remaining)/(rate+1e-9)) * time.Second
+	pct := float64(rows) / float64(m.Expected)
+	if pct > 1 {
+		pct = 1
+	}
+	if m.JSON {
+		b, _ := json.Marshal(map[string]any{"type": "progress", "rows": rows, "expected": m.Expected, "rate_rps": rate, "eta": int(eta.Truncate(time.Second).Seconds())})
+		fmt.Fprintln(m.Log, string(b))
+		return
+	}
+	width := 30
+	filled := int(pct * float64(width))
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", width-filled)
+	fmt.Fprintf(m.Log, "[%s] %5.1f%% rows=%d/%d (%.1f r/s) ETA=%s\n", bar, pct*100, rows, m.Expected, rate, eta.Truncate(time.Second))
+}
+
+// watch waits for the run feeding done, printing progress every second.
+// rows is read without locking, as the lines are only an estimate.
+func (prog Progress) watch(done <-chan error, rows *int) error {
+	if prog.Log == nil {
+		return <-done
+	}
+	m := &progressMeter{Progress: prog, start: time.Now()}
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-ticker.C:
+			m.tick(*rows)
+		}
+	}
+}
+
+// RunStream applies p to each chunk from src and writes it to sink, which is
+// closed at the end, printing progress as configured.
+func RunStream(ctx context.Context, p *j.Pipeline, src j.ChunkSource, sink j.ChunkSink, prog Progress) error {
+	if prog.Log == nil {
+		return j.RunStream(ctx, p, src, sink)
+	}
+	done := make(chan error, 1)
+	var rows int
+	go func() {
+		for {
+			f, err := src.Next()
+			if err == io.EOF {
+				done <- nil
+				return
+			}
+			if err != nil {
+				done <- err
+				return
+			}
+			rows += f.Rows()
+			out, err := p.Run(ctx, f)
+			if err != nil {
+				done <- err
+				return
+			}
+			if err := sink.Write(out); err != nil {
+				done <- err
+				return
+			}
+		}
+	}()
+	err := prog.watch(done, &rows)
+	// closing flushes buffered output (and writes the Parquet footer)
+	if cerr := sink.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// RunStreamPartitioned applies p to each chunk from src, splits rows by the
+// partition columns and writes each partition to a sink keyed by the
+// expanded outPath template. outPath may include placeholders like
+// {col:Name}, replaced with the partition's value of the column; without
+// them {basename} is replaced with the joined partition key.
+func RunStreamPartitioned(ctx context.Context, p *j.Pipeline, src j.ChunkSource, outPath string, makeSink SinkFunc, schema j.Schema, partCols []string, prog Progress) error {
+	sinks := map[string]j.ChunkSink{}
+	done := make(chan error, 1)
+	var rows int
+	go func() {
+		for {
+			f, err := src.Next()
+			if err == io.EOF {
+				done <- nil
+				return
+			}
+			if err != nil {
+				done <- err
+				return
+			}
+			out, err := p.Run(ctx, f)
+			if err != nil {
+				done <- err
+				return
+			}
+			for key, pf := range splitFrameByPartitions(out, partCols) {
+				path := expandOutPath(outPath, partCols, pf, key)
+				s, ok := sinks[path]
+				if !ok {
+					if s, err = makeSink(path, schema); err != nil {
+						done <- err
+						return
+					}
+					sinks[path] = s
+				}
+				if err := s.Write(pf); err != nil {
+					done <- err
+					return
+				}
+				rows += pf.Rows()
+			}
+		}
+	}()
+	err := prog.watch(done, &rows)
+	// closing flushes buffered output (and writes Parquet footers)
+	for _, s := range sinks {
+		if cerr := s.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func splitFrameByPartitions(f *j.Frame, cols []string) map[string]*j.Frame {
+	res := map[string]*j.Frame{}
+	for r := 0; r < f.Rows(); r++ {
+		key := partitionKeyForRow(f, r, cols)
+		pf, ok := res[key]
+		if !ok {
+			pf = j.NewFrame(f.Schema())
+			res[key] = pf
+		}
+		pf.AppendNullRow()
+		row := pf.Rows() - 1
+		for _, cs := range f.Schema().Columns {
+			if v, ok := cellValue(f, cs.Name, r); ok {
+				_ = pf.SetCell(row, cs.Name, v)
+			}
+		}
+	}
+	return res
+}
+
+// cellValue returns the value in row r of the named column, false for a null.
+func cellValue(f *j.Frame, name string, r int) (any, bool) {
+	col, _ := f.ColumnByName(name)
+	switch col := col.(type) {
+	case *j.FloatColumn:
+		return col.Get(r)
+	case *j.IntColumn:
+		return col.Get(r)
+	case *j.BoolColumn:
+		return col.Get(r)
+	case *j.StringColumn:
+		return col.Get(r)
+	case *j.TimeColumn:
+		return col.Get(r)
+	}
+	return nil, false
+}
+
+// partitionValue formats a partition column's value for use in a path.
+func partitionValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return sanitizePartition(v)
+	case float64:
+		return fmt.Sprintf("%.6g", v)
+	case time.Time:
+		return sanitizePartition(v.Format("2006-01-02"))
+	}
+	return fmt.Sprint(v)
+}
+
+func partitionKeyForRow(f *j.Frame, r int, cols []string) string {
+	parts := make([]string, len(cols))
+	for i, name := range cols {
+		if _, ok := f.ColumnByName(name); !ok {
+			parts[i] = "_"
+		} else if v, ok := cellValue(f, name, r); ok {
+			parts[i] = partitionValue(v)
+		} else {
+			parts[i] = "_null"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func sanitizePartition(s string) string {
+	// replace path separators and spaces
+	s = strings.ReplaceAll(s, "/", "-")
+	s = strings.ReplaceAll(s, "\\", "-")
+	s = strings.ReplaceAll(s, " ", "_")
+	return s
+}
+
+func expandOutPath(tmpl string, cols []string, f *j.Frame, key string) string {
+	out := tmpl
+	for _, name := range cols {
+		placeholder := "{col:" + name + "}"
+		if !strings.Contains(out, placeholder) {
+			continue
+		}
+		// all rows of a partition share the value; take the first row's
+		val := ""
+		if f.Rows() > 0 {
+			if v, ok := cellValue(f, name, 0); ok {
+				val = partitionValue(v)
+			}
+		}
+		out = strings.ReplaceAll(out, placeholder, val)
+	}
+	// if no {col:} placeholders, fall back to the joined key
+	if out == tmpl {
+		out = strings.ReplaceAll(out, "{basename}", key)
+	}
+	return out
+}
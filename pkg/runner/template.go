@@ -0,0 +1,329 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+// expander resolves the templating of a config document: ${name} and
+// ${name:-default} references in its values, read from the environment and
+// the config's vars, and {"include": path} entries of its steps.
+type expander struct {
+	vars      map[string]any
+	resolving map[string]bool // vars being expanded, to stop reference cycles
+	files     []string        // the including files, to stop include cycles
+}
+
+func newExpander(vars any) (*expander, error) {
+	x := &expander{vars: map[string]any{}, resolving: map[string]bool{}}
+	if vars == nil {
+		return x, nil
+	}
+	m, ok := vars.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("vars: want an object of names to values")
+	}
+	for name, v := range m {
+		if !validVarName(name) {
+			return nil, fmt.Errorf("vars: invalid name %q", name)
+		}
+		x.vars[name] = v
+	}
+	return x, nil
+}
+
+// lookup returns the value of a reference. The environment overrides vars,
+// so vars are defaults that a deployment can change.
+func (x *expander) lookup(name string) (string, bool, error) {
+	if v, ok := os.LookupEnv(name); ok {
+		return v, true, nil
+	}
+	v, ok := x.vars[name]
+	if !ok {
+		return "", false, nil
+	}
+	s, isString := v.(string)
+	if !isString {
+		// numbers, booleans and lists keep their JSON form
+		b, err := json.Marshal(v)
+		return string(b), true, err
+	}
+	if x.resolving[name] {
+		return "", false, fmt.Errorf("vars: %s refers to itself", name)
+	}
+	x.resolving[name] = true
+	defer delete(x.resolving, name)
+	s, _, err := x.interpolate(s)
+	if err != nil {
+		return "", false, fmt.Errorf("vars: %s: %w", name, err)
+	}
+	return s, true, nil
+}
+
+// interpolate replaces the references in s; $${ is a literal ${. whole
+// reports whether s is a single reference, whose value may then be typed.
+func (x *expander) interpolate(s string) (string, bool, error) {
+	if !strings.Contains(s, "${") {
+		return s, false, nil
+	}
+	var out strings.Builder
+	refs, lit := 0, false
+	for rest := s; rest != ""; {
+		i := strings.Index(rest, "${")
+		if i < 0 {
+			out.WriteString(rest)
+			lit = true
+			break
+		}
+		if i > 0 && rest[i-1] == '$' {
+			out.WriteString(rest[:i-1] + "${")
+			rest = rest[i+2:]
+			lit = true
+			continue
+		}
+		if i > 0 {
+			out.WriteString(rest[:i])
+			lit = true
+		}
+		end := strings.IndexByte(rest[i:], '}')
+		if end < 0 {
+			return "", false, fmt.Errorf("unterminated reference in %q", s)
+		}
+		ref := rest[i+2 : i+end]
+		rest = rest[i+end+1:]
+		name, def, hasDef := strings.Cut(ref, ":-")
+		if !validVarName(name) {
+			return "", false, fmt.Errorf("invalid reference ${%s}", ref)
+		}
+		v, ok, err := x.lookup(name)
+		if err != nil {
+			return "", false, err
+		}
+		switch {
+		case hasDef && v == "":
+			v = def
+		case !ok:
+			return "", false, fmt.Errorf("${%s} is not set; set it in the environment or vars, or give a default: ${%s:-value}", name, name)
+		}
+		out.WriteString(v)
+		refs++
+	}
+	return out.String(), refs == 1 && !lit, nil
+}
+
+func validVarName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		letter := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !letter && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+var rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+
+// value resolves the references in v, which decodes into t (nil when
+// unknown). A string that is a single reference is typed like t, as the
+// values of --step are, so "${MIN_AGE}" can fill a number.
+func (x *expander) value(v any, t reflect.Type, at string) (any, error) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch v := v.(type) {
+	case string:
+		s, whole, err := x.interpolate(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", at, err)
+		}
+		if !whole {
+			return s, nil
+		}
+		var typ, elem string
+		if t != nil && t != rawMessageType {
+			typ = j.ParamSpec{Type: t}.JSONType()
+			if typ == "array" {
+				elem = j.ParamSpec{Type: t.Elem()}.JSONType()
+			}
+		}
+		var out any
+		if err := json.Unmarshal(paramValue(s, typ, elem), &out); err != nil {
+			return nil, fmt.Errorf("%s: %w", at, err)
+		}
+		return out, nil
+	case []any:
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		for i := range v {
+			var err error
+			if v[i], err = x.value(v[i], elem, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return nil, err
+			}
+		}
+	case map[string]any:
+		if t == rawMessageType {
+			return x.step(v, at)
+		}
+		for _, k := range sortedKeys(v) {
+			var err error
+			if v[k], err = x.value(v[k], fieldType(t, k), at+"."+k); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
+
+// step resolves a step's params, typed by the step registry.
+func (x *expander) step(m map[string]any, at string) (any, error) {
+	for _, name := range sortedKeys(m) {
+		var err error
+		if name == "when" {
+			m[name], err = x.value(m[name], reflect.TypeOf(""), at+".when")
+		} else if params, ok := m[name].(map[string]any); ok {
+			types := map[string]reflect.Type{}
+			if info, known := j.LookupStep(name); known {
+				for _, p := range info.Params {
+					types[p.Name] = p.Type
+				}
+			}
+			for _, k := range sortedKeys(params) {
+				if params[k], err = x.value(params[k], types[k], at+"."+name+"."+k); err != nil {
+					break
+				}
+			}
+		} else {
+			m[name], err = x.value(m[name], nil, at+"."+name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// steps resolves the steps of the config file at path, replacing each
+// {"include": path} entry with the steps of that file. Errors name the file
+// and the index of the step in it.
+func (x *expander) steps(path string, v any) ([]any, error) {
+	list, ok := v.([]any)
+	if !ok {
+		if v == nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s: steps: want a list of steps", path)
+	}
+	var out []any
+	for i, e := range list {
+		at := fmt.Sprintf("steps[%d]", i)
+		m, isMap := e.(map[string]any)
+		inc, isInclude := m["include"]
+		if !isMap || !isInclude {
+			s, err := x.value(e, rawMessageType, at)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			out = append(out, s)
+			continue
+		}
+		if len(m) != 1 {
+			return nil, fmt.Errorf("%s: %s: include takes no other keys", path, at)
+		}
+		inc, err := x.value(inc, reflect.TypeOf([]string(nil)), at+".include")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		files, ok := inc.([]any)
+		if !ok {
+			files = []any{inc}
+		}
+		for _, f := range files {
+			name, ok := f.(string)
+			if !ok || name == "" {
+				return nil, fmt.Errorf("%s: %s: include wants a file path or a list of them", path, at)
+			}
+			if !filepath.IsAbs(name) {
+				name = filepath.Join(filepath.Dir(path), name)
+			}
+			steps, err := x.include(name)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", path, at, err)
+			}
+			out = append(out, steps...)
+		}
+	}
+	return out, nil
+}
+
+// include reads the steps of an included file: a JSON or YAML list of
+// steps, or a document with a steps key.
+func (x *expander) include(path string) ([]any, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range x.files {
+		if f == abs {
+			return nil, fmt.Errorf("include %s: includes itself", path)
+		}
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("include: %w", err)
+	}
+	doc, err := decodeDocument(path, b)
+	if err != nil {
+		return nil, fmt.Errorf("include %s: %w", path, err)
+	}
+	if m, ok := doc.(map[string]any); ok {
+		for k := range m {
+			if k != "steps" {
+				return nil, fmt.Errorf("include %s: only steps can be included, found %q", path, k)
+			}
+		}
+		doc = m["steps"]
+	}
+	x.files = append(x.files, abs)
+	defer func() { x.files = x.files[:len(x.files)-1] }()
+	return x.steps(path, doc)
+}
+
+// fieldType returns the type of the value of key k in t: the field with
+// that json name, for a struct, or the element type of a map.
+func fieldType(t reflect.Type, k string) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		return t.Elem()
+	case reflect.Struct:
+		for _, f := range jsonFields(t) {
+			if f.Name == k {
+				return f.Type
+			}
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
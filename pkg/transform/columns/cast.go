@@ -0,0 +1,151 @@
+package columns
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	iox "github.com/wdm0006/janitor/pkg/io/ioutils"
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+// Cast converts Column to kind To. Format is the layout for string <-> time
+// casts (Go layout or iox.EpochSeconds/EpochMillis when parsing; the default
+// time formats or RFC3339 otherwise). Values that do not convert are handled
+// by OnError: iox.CastNull (default), iox.CastKeepRaw, which also keeps their
+// text in <Column>__raw, or iox.CastFail. iox.CastReject is not supported.
+// A missing column is skipped.
+type Cast struct {
+	Column  string
+	To      j.Kind
+	Format  string
+	OnError iox.CastPolicy
+
+	times *iox.TimeParser
+	bools *iox.BoolParser
+}
+
+func (c *Cast) Name() string { return "cast" }
+
+// CheckSchema reports a missing column, which Apply skips.
+func (c *Cast) CheckSchema(in j.Schema) error { return j.CheckColumn(in, c.Column) }
+
+func (c *Cast) OutputSchema(in j.Schema) (j.Schema, error) {
+	if err := c.check(); err != nil {
+		return j.Schema{}, err
+	}
+	i := in.Index(c.Column)
+	if i < 0 {
+		return in, nil
+	}
+	out := j.Schema{Columns: append([]j.ColumnSchema(nil), in.Columns...)}
+	out.Columns[i].Type = c.To
+	if c.OnError != iox.CastFail {
+		out.Columns[i].Nullable = true
+	}
+	if c.OnError == iox.CastKeepRaw {
+		raw := c.Column + iox.RawSuffix
+		if k := out.Index(raw); k < 0 {
+			out.Columns = append(out.Columns, j.ColumnSchema{Name: raw, Type: j.KindString, Nullable: true})
+		} else if out.Columns[k].Type != j.KindString {
+			return j.Schema{}, fmt.Errorf("column %q is %s, want string", raw, out.Columns[k].Type)
+		}
+	}
+	return out, nil
+}
+
+func (c *Cast) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
+	schema, err := c.OutputSchema(f.Schema())
+	if err != nil {
+		return nil, err
+	}
+	col, ok := f.ColumnByName(c.Column)
+	if !ok {
+		return f, nil
+	}
+	if err := c.parsers(); err != nil {
+		return nil, err
+	}
+	out, err := j.NewFrameFromColumns(j.Schema{Columns: []j.ColumnSchema{{Name: c.Column, Type: c.To, Nullable: true}}},
+		[]j.Column{j.NewNullColumn(c.Column, c.To, f.Rows())})
+	if err != nil {
+		return nil, err
+	}
+	var raw *j.StringColumn
+	if c.OnError == iox.CastKeepRaw {
+		name := c.Column + iox.RawSuffix
+		rc, ok := f.ColumnByName(name)
+		if !ok {
+			rc = j.NewNullColumn(name, j.KindString, f.Rows())
+			if err := f.AddColumn(j.ColumnSchema{Name: name, Type: j.KindString, Nullable: true}, rc); err != nil {
+				return nil, err
+			}
+		}
+		raw = rc.(*j.StringColumn)
+	}
+	for i := 0; i < f.Rows(); i++ {
+		v := j.Value(col, i)
+		if v == nil {
+			continue
+		}
+		x, ok := c.convert(v)
+		if !ok {
+			text, _ := iox.Convert(v, j.KindString, c.times, c.bools)
+			switch c.OnError {
+			case iox.CastFail:
+				return nil, fmt.Errorf("column %q: cannot cast %q to %s", c.Column, text, c.To)
+			case iox.CastKeepRaw:
+				raw.Set(i, text.(string))
+			}
+			continue
+		}
+		if err := out.SetCell(i, c.Column, x); err != nil {
+			return nil, err
+		}
+	}
+	casted, _ := out.ColumnByName(c.Column)
+	if err := f.ReplaceColumn(c.Column, schema.Columns[schema.Index(c.Column)], casted); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (c *Cast) convert(v any) (any, bool) {
+	if t, ok := v.(time.Time); ok && c.To == j.KindString && c.Format != "" {
+		return t.Format(c.Format), true
+	}
+	return iox.Convert(v, c.To, c.times, c.bools)
+}
+
+func (c *Cast) check() error {
+	switch c.To {
+	case j.KindBool, j.KindInt, j.KindFloat, j.KindString, j.KindTime:
+	default:
+		return fmt.Errorf("column %q: cast needs a target type", c.Column)
+	}
+	switch c.OnError {
+	case "", iox.CastNull, iox.CastKeepRaw, iox.CastFail:
+		return nil
+	}
+	return fmt.Errorf("column %q: on_error %q is not supported by cast (want null, keep_raw or fail)", c.Column, c.OnError)
+}
+
+func (c *Cast) parsers() error {
+	if c.times != nil {
+		return nil
+	}
+	var formats []string
+	if c.Format != "" {
+		formats = []string{c.Format}
+	}
+	times, err := iox.NewTimeParser(formats, "")
+	if err != nil {
+		return err
+	}
+	bools, err := iox.NewBoolParser(nil, nil)
+	if err != nil {
+		return err
+	}
+	c.times, c.bools = times, bools
+	return nil
+}
@@ -0,0 +1,153 @@
+// Package columns has steps that change which columns a frame has: select,
+// drop, rename, reorder, cast and derive.
+package columns
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+// Select keeps only Columns, in the order given. A missing column is an error.
+type Select struct{ Columns []string }
+
+func (s *Select) Name() string { return "select" }
+
+func (s *Select) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
+	return f.Select(s.Columns...)
+}
+
+func (s *Select) OutputSchema(in j.Schema) (j.Schema, error) {
+	var out j.Schema
+	seen := map[string]bool{}
+	for _, name := range s.Columns {
+		i := in.Index(name)
+		if i < 0 {
+			return j.Schema{}, fmt.Errorf("unknown column: %s", name)
+		}
+		if seen[name] {
+			return j.Schema{}, fmt.Errorf("column %q selected twice", name)
+		}
+		seen[name] = true
+		out.Columns = append(out.Columns, in.Columns[i])
+	}
+	return out, nil
+}
+
+// Drop removes Columns; columns the frame does not have are skipped.
+type Drop struct{ Columns []string }
+
+func (d *Drop) Name() string { return "drop" }
+
+func (d *Drop) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
+	for _, name := range d.Columns {
+		if _, ok := f.ColumnByName(name); !ok {
+			continue
+		}
+		if err := f.DropColumn(name); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (d *Drop) OutputSchema(in j.Schema) (j.Schema, error) {
+	drop := map[string]bool{}
+	for _, name := range d.Columns {
+		drop[name] = true
+	}
+	var out j.Schema
+	for _, cs := range in.Columns {
+		if !drop[cs.Name] {
+			out.Columns = append(out.Columns, cs)
+		}
+	}
+	return out, nil
+}
+
+// Rename renames columns, old name to new. All renames happen at once, so
+// names can be swapped; columns the frame does not have are skipped. A new
+// name that collides with a column left in place is an error.
+type Rename struct{ Columns map[string]string }
+
+func (r *Rename) Name() string { return "rename" }
+
+func (r *Rename) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
+	if _, err := r.OutputSchema(f.Schema()); err != nil {
+		return nil, err
+	}
+	var olds []string
+	for old := range r.Columns {
+		if _, ok := f.ColumnByName(old); ok && r.Columns[old] != old {
+			olds = append(olds, old)
+		}
+	}
+	sort.Strings(olds)
+	// move every renamed column out of the way first so swaps cannot collide
+	for i, old := range olds {
+		if err := f.RenameColumn(old, tempName(i)); err != nil {
+			return nil, err
+		}
+	}
+	for i, old := range olds {
+		if err := f.RenameColumn(tempName(i), r.Columns[old]); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (r *Rename) OutputSchema(in j.Schema) (j.Schema, error) {
+	out := j.Schema{Columns: append([]j.ColumnSchema(nil), in.Columns...)}
+	seen := map[string]bool{}
+	for i, cs := range out.Columns {
+		if name, ok := r.Columns[cs.Name]; ok {
+			if name == "" {
+				return j.Schema{}, fmt.Errorf("column %q: empty new name", cs.Name)
+			}
+			out.Columns[i].Name = name
+		}
+		if seen[out.Columns[i].Name] {
+			return j.Schema{}, fmt.Errorf("column %q already exists", out.Columns[i].Name)
+		}
+		seen[out.Columns[i].Name] = true
+	}
+	return out, nil
+}
+
+func tempName(i int) string {
+	return fmt.Sprintf("\x00rename%d", i)
+}
+
+// Reorder moves Columns to the front in the order given; the other columns
+// follow in their current order. A missing column is an error.
+type Reorder struct{ Columns []string }
+
+func (r *Reorder) Name() string { return "reorder" }
+
+func (r *Reorder) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
+	s, err := r.OutputSchema(f.Schema())
+	if err != nil {
+		return nil, err
+	}
+	return f.Select(s.Names()...)
+}
+
+func (r *Reorder) OutputSchema(in j.Schema) (j.Schema, error) {
+	out, err := (&Select{Columns: r.Columns}).OutputSchema(in)
+	if err != nil {
+		return j.Schema{}, err
+	}
+	first := map[string]bool{}
+	for _, name := range r.Columns {
+		first[name] = true
+	}
+	for _, cs := range in.Columns {
+		if !first[cs.Name] {
+			out.Columns = append(out.Columns, cs)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,158 @@
+package columns
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wdm0006/janitor/pkg/expr"
+	iox "github.com/wdm0006/janitor/pkg/io/ioutils"
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+func stringFrame(t *testing.T, names []string, rows ...[]string) *j.Frame {
+	t.Helper()
+	s := j.Schema{}
+	for _, n := range names {
+		s.Columns = append(s.Columns, j.ColumnSchema{Name: n, Type: j.KindString, Nullable: true})
+	}
+	f := j.NewFrame(s)
+	for r, row := range rows {
+		f.AppendNullRow()
+		for i, v := range row {
+			if v == "" {
+				continue
+			}
+			if err := f.SetCell(r, names[i], v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return f
+}
+
+func TestSelectDropRenameReorder(t *testing.T) {
+	ctx := context.Background()
+	f := stringFrame(t, []string{"a", "b", "c", "d"}, []string{"1", "2", "3", "4"})
+	p := j.NewPipeline().
+		Add(&Drop{Columns: []string{"d", "missing"}}).
+		Add(&Rename{Columns: map[string]string{"a": "b", "b": "a"}}).
+		Add(&Reorder{Columns: []string{"c"}}).
+		Add(&Select{Columns: []string{"c", "b"}})
+	want, err := p.OutputSchema(f.Schema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := p.Run(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out.Schema(), want) {
+		t.Fatalf("schema = %+v, OutputSchema said %+v", out.Schema(), want)
+	}
+	if got := out.Schema().Names(); !reflect.DeepEqual(got, []string{"c", "b"}) {
+		t.Fatalf("columns = %v", got)
+	}
+	b, _ := out.ColumnByName("b")
+	if v := j.Value(b, 0); v != "1" {
+		t.Fatalf("b = %v, want the old a", v)
+	}
+
+	if _, err := (&Select{Columns: []string{"x"}}).Apply(ctx, out); err == nil {
+		t.Fatal("expected an error selecting a missing column")
+	}
+	if _, err := (&Rename{Columns: map[string]string{"c": "b"}}).Apply(ctx, out); err == nil {
+		t.Fatal("expected an error renaming onto an existing column")
+	}
+}
+
+func TestCast(t *testing.T) {
+	ctx := context.Background()
+	f := stringFrame(t, []string{"age", "at"},
+		[]string{"42", "18/10/2026"},
+		[]string{"n/a", "bad"},
+		[]string{"", ""})
+
+	age := &Cast{Column: "age", To: j.KindInt, OnError: iox.CastKeepRaw}
+	at := &Cast{Column: "at", To: j.KindTime, Format: "02/01/2006"}
+	p := j.NewPipeline().Add(age).Add(at)
+	want, err := p.OutputSchema(f.Schema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := p.Run(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out.Schema(), want) {
+		t.Fatalf("schema = %+v, OutputSchema said %+v", out.Schema(), want)
+	}
+	ac, _ := out.ColumnByName("age")
+	raw, _ := out.ColumnByName("age" + iox.RawSuffix)
+	if j.Value(ac, 0) != int64(42) || j.Value(ac, 1) != nil || j.Value(raw, 1) != "n/a" || j.Value(raw, 0) != nil {
+		t.Fatalf("age = %v %v, raw = %v %v", j.Value(ac, 0), j.Value(ac, 1), j.Value(raw, 0), j.Value(raw, 1))
+	}
+	tc, _ := out.ColumnByName("at")
+	if v := j.Value(tc, 0); v != time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC) {
+		t.Fatalf("at = %v", v)
+	}
+	if j.Value(tc, 1) != nil || j.Value(tc, 2) != nil {
+		t.Fatal("unparsed and null times should be null")
+	}
+
+	back := &Cast{Column: "at", To: j.KindString, Format: "2006/01/02"}
+	if _, err := back.Apply(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	tc, _ = out.ColumnByName("at")
+	if v := j.Value(tc, 0); v != "2026/10/18" {
+		t.Fatalf("formatted time = %v", v)
+	}
+
+	g := stringFrame(t, []string{"n"}, []string{"1"}, []string{"x"})
+	if _, err := (&Cast{Column: "n", To: j.KindInt, OnError: iox.CastFail}).Apply(ctx, g); err == nil {
+		t.Fatal("expected an error under on_error fail")
+	}
+	if _, err := (&Cast{Column: "n", To: j.KindInt, OnError: iox.CastReject}).OutputSchema(g.Schema()); err == nil {
+		t.Fatal("expected reject to be refused")
+	}
+}
+
+func TestDerive(t *testing.T) {
+	ctx := context.Background()
+	f := stringFrame(t, []string{"first", "last"}, []string{" Ann", "Lee "}, []string{"Bob", ""})
+	full, err := expr.Parse(`trim(first) + " " + trim(last)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := expr.Parse(`len(first)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := j.NewPipeline().Add(&Derive{Column: "full", Expr: full}).Add(&Derive{Column: "first", Expr: n})
+	want, err := p.OutputSchema(f.Schema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := p.Run(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out.Schema(), want) {
+		t.Fatalf("schema = %+v, OutputSchema said %+v", out.Schema(), want)
+	}
+	c, _ := out.ColumnByName("full")
+	if j.Value(c, 0) != "Ann Lee" || j.Value(c, 1) != nil {
+		t.Fatalf("full = %v, %v", j.Value(c, 0), j.Value(c, 1))
+	}
+	c, _ = out.ColumnByName("first")
+	if c.Kind() != j.KindInt || j.Value(c, 0) != int64(4) {
+		t.Fatalf("first = %v (%s)", j.Value(c, 0), c.Kind())
+	}
+
+	bad, _ := expr.Parse(`last * 2`)
+	if _, err := (&Derive{Column: "x", Expr: bad}).OutputSchema(f.Schema()); err == nil {
+		t.Fatal("expected a type error")
+	}
+}
@@ -0,0 +1,50 @@
+package columns
+
+import (
+	"context"
+
+	"github.com/wdm0006/janitor/pkg/expr"
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+// Derive sets Column to the value of Expr for every row. A new column is
+// appended; an existing one is replaced in place and may change type. The
+// result is nullable.
+type Derive struct {
+	Column string
+	Expr   *expr.Expr
+}
+
+func (d *Derive) Name() string { return "derive" }
+
+func (d *Derive) OutputSchema(in j.Schema) (j.Schema, error) {
+	kind, err := d.Expr.Type(in)
+	if err != nil {
+		return j.Schema{}, err
+	}
+	out := j.Schema{Columns: append([]j.ColumnSchema(nil), in.Columns...)}
+	cs := j.ColumnSchema{Name: d.Column, Type: kind, Nullable: true}
+	if i := out.Index(d.Column); i >= 0 {
+		out.Columns[i] = cs
+	} else {
+		out.Columns = append(out.Columns, cs)
+	}
+	return out, nil
+}
+
+func (d *Derive) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
+	col, err := d.Expr.Eval(f)
+	if err != nil {
+		return nil, err
+	}
+	cs := j.ColumnSchema{Name: d.Column, Type: col.Kind(), Nullable: true}
+	if _, ok := f.ColumnByName(d.Column); ok {
+		err = f.ReplaceColumn(d.Column, cs, col)
+	} else {
+		err = f.AddColumn(cs, col)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,88 @@
+package columns
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wdm0006/janitor/pkg/expr"
+	iox "github.com/wdm0006/janitor/pkg/io/ioutils"
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+type listParams struct {
+	Columns j.Selector `json:"columns" required:"true" doc:"names, globs (*_id), re:<regexp> or type:<type>"`
+}
+
+type renameParams struct {
+	Columns map[string]string `json:"columns" required:"true" doc:"old name to new name"`
+}
+
+type castParams struct {
+	j.ColumnParams
+	To      string `json:"to" required:"true" doc:"bool, int, float, string or time"`
+	Format  string `json:"format" doc:"layout for string to time casts and back"`
+	OnError string `json:"on_error" enum:"null,keep_raw,fail" doc:"null (default), keep_raw or fail"`
+}
+
+type deriveParams struct {
+	Column string `json:"column" required:"true" doc:"column to add or replace"`
+	Expr   string `json:"expr" required:"true" doc:"expression giving the value of each row"`
+}
+
+func init() {
+	j.RegisterStep("select", "Keep only these columns, in this order", func(p *listParams, b *j.StepBuilder) error {
+		names, err := b.Columns(p.Columns)
+		if err != nil {
+			return err
+		}
+		b.Add(&Select{Columns: names}, "select:"+strings.Join(names, ","))
+		return nil
+	})
+	j.RegisterStep("drop", "Remove these columns; missing ones are skipped", func(p *listParams, b *j.StepBuilder) error {
+		names, err := b.Columns(p.Columns)
+		if err != nil {
+			return err
+		}
+		b.Add(&Drop{Columns: names}, "drop:"+strings.Join(names, ","))
+		return nil
+	})
+	j.RegisterStep("rename", "Rename columns, all at once", func(p *renameParams, b *j.StepBuilder) error {
+		b.Add(&Rename{Columns: p.Columns}, "rename")
+		return nil
+	})
+	j.RegisterStep("reorder", "Move these columns to the front", func(p *listParams, b *j.StepBuilder) error {
+		names, err := b.Columns(p.Columns)
+		if err != nil {
+			return err
+		}
+		b.Add(&Reorder{Columns: names}, "reorder:"+strings.Join(names, ","))
+		return nil
+	})
+	j.RegisterStep("cast", "Convert a column to another type", func(p *castParams, b *j.StepBuilder) error {
+		kind, err := j.ParseKind(p.To)
+		if err != nil {
+			return err
+		}
+		policy, err := iox.ParseCastPolicy(p.OnError)
+		if err != nil {
+			return err
+		}
+		if policy == iox.CastReject {
+			return fmt.Errorf("on_error %q is not supported by cast (want null, keep_raw or fail)", p.OnError)
+		}
+		return b.EachColumn(p.ColumnParams, func(c string) j.Transform {
+			return &Cast{Column: c, To: kind, Format: p.Format, OnError: policy}
+		})
+	})
+	j.RegisterStep("derive", "Set a column to the value of an expression", func(p *deriveParams, b *j.StepBuilder) error {
+		if p.Column == "" {
+			return fmt.Errorf("column is required")
+		}
+		e, err := expr.Parse(p.Expr)
+		if err != nil {
+			return err
+		}
+		b.Add(&Derive{Column: p.Column, Expr: e}, "derive:"+p.Column)
+		return nil
+	})
+}
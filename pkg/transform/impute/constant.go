@@ -2,6 +2,7 @@ package impute
 
 import (
 	"context"
+	"fmt"
 	j "github.com/wdm0006/janitor/pkg/janitor"
 )
 
@@ -13,6 +14,33 @@ type Constant struct {
 
 func (t *Constant) Name() string { return "impute_constant" }
 
+// CheckSchema reports a missing or unsupported column and a Value that does
+// not suit its type.
+func (t *Constant) CheckSchema(in j.Schema) error {
+	if err := j.CheckColumn(in, t.Column, j.KindFloat, j.KindInt, j.KindString, j.KindBool); err != nil {
+		return err
+	}
+	if t.Value == nil {
+		return fmt.Errorf("value is required")
+	}
+	kind := in.Columns[in.Index(t.Column)].Type
+	ok := false
+	switch v := t.Value.(type) {
+	case int, int64:
+		ok = kind == j.KindFloat || kind == j.KindInt
+	case float64:
+		ok = kind == j.KindFloat || kind == j.KindInt && v == float64(int64(v))
+	case string:
+		ok = kind == j.KindString
+	case bool:
+		ok = kind == j.KindBool
+	}
+	if !ok {
+		return fmt.Errorf("value %v does not suit %s column %q", t.Value, kind, t.Column)
+	}
+	return nil
+}
+
 func (t *Constant) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
 	col, ok := f.ColumnByName(t.Column)
 	if !ok {
@@ -67,3 +67,51 @@ func TestMedian(t *testing.T) {
 		}
 	}
 }
+
+func TestFitUsesAllChunks(t *testing.T) {
+	s := j.Schema{Columns: []j.ColumnSchema{{Name: "x", Type: j.KindFloat, Nullable: true}}}
+	chunk := func(vals ...any) *j.Frame {
+		f := j.NewFrame(s)
+		for i, v := range vals {
+			f.AppendNullRow()
+			_ = f.SetCell(i, "x", v)
+		}
+		return f
+	}
+	mean := &Mean{Column: "x"}
+	median := &Median{Column: "x"}
+	for _, f := range []*j.Frame{chunk(1.0, 2.0), chunk(9.0, nil)} {
+		if err := mean.Fit(context.Background(), f); err != nil {
+			t.Fatal(err)
+		}
+		if err := median.Fit(context.Background(), f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the chunk being applied has no values of its own; fills must come from fitted state
+	for _, tc := range []struct {
+		tf   j.Transform
+		want float64
+	}{{mean, 4}, {median, 2}} {
+		f := chunk(nil)
+		if _, err := tc.tf.Apply(context.Background(), f); err != nil {
+			t.Fatal(err)
+		}
+		col, _ := f.ColumnByName("x")
+		if v, _ := col.(*j.FloatColumn).Get(0); v != tc.want {
+			t.Fatalf("%s: expected %v, got %v", tc.tf.Name(), tc.want, v)
+		}
+	}
+	median.FinishFit()
+	if median.floats != nil || median.ints != nil {
+		t.Fatal("median kept its fitted values after FinishFit")
+	}
+	f := chunk(nil)
+	if _, err := median.Apply(context.Background(), f); err != nil {
+		t.Fatal(err)
+	}
+	col, _ := f.ColumnByName("x")
+	if v, _ := col.(*j.FloatColumn).Get(0); v != 2 {
+		t.Fatalf("after FinishFit: expected 2, got %v", v)
+	}
+}
@@ -2,58 +2,103 @@ package impute
 
 import (
 	"context"
+	"encoding/json"
 	j "github.com/wdm0006/janitor/pkg/janitor"
 )
 
-type Mean struct{ Column string }
+// Mean fills nulls with the column mean. Unfitted, the mean is computed from
+// the frame being applied; after Fit it is the mean over every fitted frame.
+type Mean struct {
+	Column string
+
+	fitted bool
+	sum    float64
+	n      int
+}
 
 func (t *Mean) Name() string { return "impute_mean" }
 
+func (t *Mean) CheckSchema(in j.Schema) error {
+	return j.CheckColumn(in, t.Column, j.KindFloat, j.KindInt)
+}
+
+// Fit accumulates the column sum and count from f.
+func (t *Mean) Fit(ctx context.Context, f *j.Frame) error {
+	t.fitted = true
+	if col, ok := f.ColumnByName(t.Column); ok {
+		t.sum, t.n = accumulateSum(col, t.sum, t.n)
+	}
+	return nil
+}
+
+type meanState struct {
+	Sum float64 `json:"sum"`
+	N   int     `json:"n"`
+}
+
+func (t *Mean) MarshalState() (json.RawMessage, error) {
+	if !t.fitted {
+		return nil, nil
+	}
+	return json.Marshal(meanState{Sum: t.sum, N: t.n})
+}
+
+func (t *Mean) UnmarshalState(b json.RawMessage) error {
+	var s meanState
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	t.fitted, t.sum, t.n = true, s.Sum, s.N
+	return nil
+}
+
 func (t *Mean) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
 	col, ok := f.ColumnByName(t.Column)
 	if !ok {
 		return f, nil
 	}
+	sum, n := t.sum, t.n
+	if !t.fitted {
+		sum, n = accumulateSum(col, 0, 0)
+	}
+	if n == 0 {
+		return f, nil
+	}
+	mean := sum / float64(n)
 	switch c := col.(type) {
 	case *j.FloatColumn:
-		var sum float64
-		var n int
 		for i := 0; i < c.Len(); i++ {
-			if !c.IsNull(i) {
-				v, _ := c.Get(i)
-				sum += v
-				n++
+			if c.IsNull(i) {
+				c.Set(i, mean)
 			}
 		}
-		if n == 0 {
-			return f, nil
-		}
-		mean := sum / float64(n)
+	case *j.IntColumn:
+		// round to nearest
 		for i := 0; i < c.Len(); i++ {
 			if c.IsNull(i) {
-				c.Set(i, mean)
+				c.Set(i, int64(mean+0.5))
 			}
 		}
-	case *j.IntColumn:
-		var sum int64
-		var n int
+	}
+	return f, nil
+}
+
+func accumulateSum(col j.Column, sum float64, n int) (float64, int) {
+	switch c := col.(type) {
+	case *j.FloatColumn:
 		for i := 0; i < c.Len(); i++ {
-			if !c.IsNull(i) {
-				v, _ := c.Get(i)
+			if v, ok := c.Get(i); ok {
 				sum += v
 				n++
 			}
 		}
-		if n == 0 {
-			return f, nil
-		}
-		mean := float64(sum) / float64(n)
-		// round to nearest
+	case *j.IntColumn:
 		for i := 0; i < c.Len(); i++ {
-			if c.IsNull(i) {
-				c.Set(i, int64(mean+0.5))
+			if v, ok := c.Get(i); ok {
+				sum += float64(v)
+				n++
 			}
 		}
 	}
-	return f, nil
+	return sum, n
 }
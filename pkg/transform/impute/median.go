@@ -2,68 +2,181 @@ package impute
 
 import (
 	"context"
+	"encoding/json"
 	j "github.com/wdm0006/janitor/pkg/janitor"
 	"sort"
 )
 
-type Median struct{ Column string }
+// Median fills nulls with the column median. After Fit the median is exact
+// over every fitted frame, so the non-null values seen are retained until
+// FinishFit; the median is computed once per round of fitting and cached.
+type Median struct {
+	Column string
+
+	fitted   bool
+	finished bool // FinishFit dropped floats and ints; med is the fit
+	floats   []float64
+	ints     []int64
+	med      *medianState // the median of floats and ints, once computed
+}
 
 func (t *Median) Name() string { return "impute_median" }
 
+func (t *Median) CheckSchema(in j.Schema) error {
+	return j.CheckColumn(in, t.Column, j.KindFloat, j.KindInt)
+}
+
+// Fit accumulates the non-null column values from f. After FinishFit or
+// UnmarshalState it continues from the median as a single value.
+func (t *Median) Fit(ctx context.Context, f *j.Frame) error {
+	t.fitted = true
+	if t.finished {
+		t.floats, t.ints = t.med.values()
+		t.finished = false
+	}
+	t.med = nil
+	if col, ok := f.ColumnByName(t.Column); ok {
+		t.floats, t.ints = collectValues(col, t.floats, t.ints)
+	}
+	return nil
+}
+
+// FinishFit computes the median and drops the values it was taken from.
+func (t *Median) FinishFit() {
+	if !t.fitted {
+		return
+	}
+	t.median()
+	t.floats, t.ints, t.finished = nil, nil, true
+}
+
+type medianState struct {
+	Float *float64 `json:"float,omitempty"`
+	Int   *int64   `json:"int,omitempty"`
+}
+
+func newMedianState(floats []float64, ints []int64) medianState {
+	var s medianState
+	if len(floats) > 0 {
+		med := medianFloat(floats)
+		s.Float = &med
+	}
+	if len(ints) > 0 {
+		med := medianInt(ints)
+		s.Int = &med
+	}
+	return s
+}
+
+// values returns the median as a single retained value, whose median is itself.
+func (s medianState) values() ([]float64, []int64) {
+	var floats []float64
+	var ints []int64
+	if s.Float != nil {
+		floats = []float64{*s.Float}
+	}
+	if s.Int != nil {
+		ints = []int64{*s.Int}
+	}
+	return floats, ints
+}
+
+// median returns the fitted median, computing it on first use after Fit.
+// The values are left sorted, so recomputing after more fitting is cheap.
+func (t *Median) median() medianState {
+	if t.med == nil {
+		s := newMedianState(t.floats, t.ints)
+		t.med = &s
+	}
+	return *t.med
+}
+
+// MarshalState records only the median, not the values it was computed from.
+func (t *Median) MarshalState() (json.RawMessage, error) {
+	if !t.fitted {
+		return nil, nil
+	}
+	return json.Marshal(t.median())
+}
+
+// UnmarshalState restores a saved median.
+func (t *Median) UnmarshalState(b json.RawMessage) error {
+	var s medianState
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	t.fitted, t.finished, t.floats, t.ints, t.med = true, true, nil, nil, &s
+	return nil
+}
+
 func (t *Median) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
 	col, ok := f.ColumnByName(t.Column)
 	if !ok {
 		return f, nil
 	}
+	var s medianState
+	if t.fitted {
+		s = t.median()
+	} else {
+		s = newMedianState(collectValues(col, nil, nil))
+	}
 	switch c := col.(type) {
 	case *j.FloatColumn:
-		vals := make([]float64, 0, c.Len())
+		if s.Float == nil {
+			return f, nil
+		}
 		for i := 0; i < c.Len(); i++ {
-			if !c.IsNull(i) {
-				v, _ := c.Get(i)
-				vals = append(vals, v)
+			if c.IsNull(i) {
+				c.Set(i, *s.Float)
 			}
 		}
-		if len(vals) == 0 {
+	case *j.IntColumn:
+		if s.Int == nil {
 			return f, nil
 		}
-		sort.Float64s(vals)
-		var med float64
-		mid := len(vals) / 2
-		if len(vals)%2 == 0 {
-			med = (vals[mid-1] + vals[mid]) / 2
-		} else {
-			med = vals[mid]
-		}
 		for i := 0; i < c.Len(); i++ {
 			if c.IsNull(i) {
-				c.Set(i, med)
+				c.Set(i, *s.Int)
 			}
 		}
-	case *j.IntColumn:
-		vals := make([]int64, 0, c.Len())
+	}
+	return f, nil
+}
+
+func collectValues(col j.Column, floats []float64, ints []int64) ([]float64, []int64) {
+	switch c := col.(type) {
+	case *j.FloatColumn:
 		for i := 0; i < c.Len(); i++ {
-			if !c.IsNull(i) {
-				v, _ := c.Get(i)
-				vals = append(vals, v)
+			if v, ok := c.Get(i); ok {
+				floats = append(floats, v)
 			}
 		}
-		if len(vals) == 0 {
-			return f, nil
-		}
-		sort.Slice(vals, func(i, j int) bool { return vals[i] < vals[j] })
-		mid := len(vals) / 2
-		var med int64
-		if len(vals)%2 == 0 {
-			med = (vals[mid-1] + vals[mid]) / 2
-		} else {
-			med = vals[mid]
-		}
+	case *j.IntColumn:
 		for i := 0; i < c.Len(); i++ {
-			if c.IsNull(i) {
-				c.Set(i, med)
+			if v, ok := c.Get(i); ok {
+				ints = append(ints, v)
 			}
 		}
 	}
-	return f, nil
+	return floats, ints
+}
+
+// medianFloat sorts vals in place and returns its median; vals must be non-empty.
+func medianFloat(vals []float64) float64 {
+	sort.Float64s(vals)
+	mid := len(vals) / 2
+	if len(vals)%2 == 0 {
+		return (vals[mid-1] + vals[mid]) / 2
+	}
+	return vals[mid]
+}
+
+// medianInt sorts vals in place and returns its median; vals must be non-empty.
+func medianInt(vals []int64) int64 {
+	sort.Slice(vals, func(i, j int) bool { return vals[i] < vals[j] })
+	mid := len(vals) / 2
+	if len(vals)%2 == 0 {
+		return (vals[mid-1] + vals[mid]) / 2
+	}
+	return vals[mid]
 }
@@ -2,57 +2,135 @@ package impute
 
 import (
 	"context"
+	"encoding/json"
 	j "github.com/wdm0006/janitor/pkg/janitor"
 )
 
-type Mode struct{ Column string }
+// Mode fills nulls with the most frequent value. Ties go to the value that
+// reached the winning count first. After Fit the counts span every fitted frame.
+type Mode struct {
+	Column string
 
-func (t *Mode) Name() string { return "impute_mode" }
+	fitted bool
+	counts modeCounts
+}
 
-func (t *Mode) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
-	col, ok := f.ColumnByName(t.Column)
-	if !ok {
-		return f, nil
-	}
+type modeCounts struct {
+	strs    map[string]int
+	ints    map[int64]int
+	bestStr string
+	bestInt int64
+	bestc   int
+}
+
+func (m *modeCounts) add(col j.Column) {
 	switch c := col.(type) {
 	case *j.StringColumn:
-		counts := map[string]int{}
-		var best string
-		var bestc int
+		if m.strs == nil {
+			m.strs = map[string]int{}
+		}
 		for i := 0; i < c.Len(); i++ {
 			if c.IsNull(i) {
 				continue
 			}
 			v, _ := c.Get(i)
-			counts[v]++
-			if counts[v] > bestc {
-				bestc = counts[v]
-				best = v
-			}
-		}
-		for i := 0; i < c.Len(); i++ {
-			if c.IsNull(i) {
-				c.Set(i, best)
+			m.strs[v]++
+			if m.strs[v] > m.bestc {
+				m.bestc = m.strs[v]
+				m.bestStr = v
 			}
 		}
 	case *j.IntColumn:
-		counts := map[int64]int{}
-		var best int64
-		var bestc int
+		if m.ints == nil {
+			m.ints = map[int64]int{}
+		}
 		for i := 0; i < c.Len(); i++ {
 			if c.IsNull(i) {
 				continue
 			}
 			v, _ := c.Get(i)
-			counts[v]++
-			if counts[v] > bestc {
-				bestc = counts[v]
-				best = v
+			m.ints[v]++
+			if m.ints[v] > m.bestc {
+				m.bestc = m.ints[v]
+				m.bestInt = v
+			}
+		}
+	}
+}
+
+func (t *Mode) Name() string { return "impute_mode" }
+
+func (t *Mode) CheckSchema(in j.Schema) error {
+	return j.CheckColumn(in, t.Column, j.KindString, j.KindInt)
+}
+
+// Fit accumulates value counts from f.
+func (t *Mode) Fit(ctx context.Context, f *j.Frame) error {
+	t.fitted = true
+	if col, ok := f.ColumnByName(t.Column); ok {
+		t.counts.add(col)
+	}
+	return nil
+}
+
+type modeState struct {
+	String *string `json:"string,omitempty"`
+	Int    *int64  `json:"int,omitempty"`
+	Count  int     `json:"count"`
+}
+
+// MarshalState records only the winning value, not the full frequency table.
+func (t *Mode) MarshalState() (json.RawMessage, error) {
+	if !t.fitted {
+		return nil, nil
+	}
+	s := modeState{Count: t.counts.bestc}
+	if t.counts.strs != nil {
+		s.String = &t.counts.bestStr
+	}
+	if t.counts.ints != nil {
+		s.Int = &t.counts.bestInt
+	}
+	return json.Marshal(s)
+}
+
+func (t *Mode) UnmarshalState(b json.RawMessage) error {
+	var s modeState
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	t.fitted = true
+	t.counts = modeCounts{bestc: s.Count}
+	if s.String != nil {
+		t.counts.bestStr = *s.String
+	}
+	if s.Int != nil {
+		t.counts.bestInt = *s.Int
+	}
+	return nil
+}
+
+func (t *Mode) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
+	col, ok := f.ColumnByName(t.Column)
+	if !ok {
+		return f, nil
+	}
+	counts := &t.counts
+	if !t.fitted {
+		counts = &modeCounts{}
+		counts.add(col)
+	}
+	switch c := col.(type) {
+	case *j.StringColumn:
+		for i := 0; i < c.Len(); i++ {
+			if c.IsNull(i) {
+				c.Set(i, counts.bestStr)
 			}
 		}
+	case *j.IntColumn:
 		for i := 0; i < c.Len(); i++ {
 			if c.IsNull(i) {
-				c.Set(i, best)
+				c.Set(i, counts.bestInt)
 			}
 		}
 	}
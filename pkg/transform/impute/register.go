@@ -0,0 +1,23 @@
+package impute
+
+import j "github.com/wdm0006/janitor/pkg/janitor"
+
+type constantParams struct {
+	j.ColumnParams
+	Value any `json:"value" required:"true" doc:"fill value, of the column's type"`
+}
+
+func init() {
+	j.RegisterStep("impute_constant", "Fill nulls with a constant", func(p *constantParams, b *j.StepBuilder) error {
+		return b.EachColumn(p.ColumnParams, func(c string) j.Transform { return &Constant{Column: c, Value: p.Value} })
+	})
+	j.RegisterStep("impute_mean", "Fill nulls with the column mean (int and float columns)", func(p *j.ColumnParams, b *j.StepBuilder) error {
+		return b.EachColumn(*p, func(c string) j.Transform { return &Mean{Column: c} })
+	})
+	j.RegisterStep("impute_median", "Fill nulls with the column median (int and float columns)", func(p *j.ColumnParams, b *j.StepBuilder) error {
+		return b.EachColumn(*p, func(c string) j.Transform { return &Median{Column: c} })
+	})
+	j.RegisterStep("impute_mode", "Fill nulls with the most frequent value (string and int columns)", func(p *j.ColumnParams, b *j.StepBuilder) error {
+		return b.EachColumn(*p, func(c string) j.Transform { return &Mode{Column: c} })
+	})
+}
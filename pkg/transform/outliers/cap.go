@@ -2,6 +2,7 @@ package outliers
 
 import (
 	"context"
+	"fmt"
 	j "github.com/wdm0006/janitor/pkg/janitor"
 )
 
@@ -13,6 +14,16 @@ type Cap struct {
 
 func (t *Cap) Name() string { return "cap_range" }
 
+func (t *Cap) CheckSchema(in j.Schema) error {
+	if t.Min == nil && t.Max == nil {
+		return fmt.Errorf("min or max is required")
+	}
+	if t.Min != nil && t.Max != nil && *t.Min > *t.Max {
+		return fmt.Errorf("min %g is above max %g", *t.Min, *t.Max)
+	}
+	return j.CheckColumn(in, t.Column, j.KindFloat, j.KindInt)
+}
+
 func (t *Cap) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
 	col, ok := f.ColumnByName(t.Column)
 	if !ok {
@@ -0,0 +1,15 @@
+package outliers
+
+import j "github.com/wdm0006/janitor/pkg/janitor"
+
+type capParams struct {
+	j.ColumnParams
+	Min *float64 `json:"min" doc:"lower bound"`
+	Max *float64 `json:"max" doc:"upper bound"`
+}
+
+func init() {
+	j.RegisterStep("cap_range", "Clamp numbers to [min, max] (int and float columns)", func(p *capParams, b *j.StepBuilder) error {
+		return b.EachColumn(p.ColumnParams, func(c string) j.Transform { return &Cap{Column: c, Min: p.Min, Max: p.Max} })
+	})
+}
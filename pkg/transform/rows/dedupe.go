@@ -0,0 +1,139 @@
+package rows
+
+import (
+	"context"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+// Dedupe keeps the first row of each distinct key, the values of Columns (all
+// columns when empty). Keys seen in earlier frames are remembered, so in a
+// stream a duplicate is dropped whichever chunk it falls in. Up to MaxKeys
+// keys (default DefaultMaxKeys) are held in memory; the rest spill to a
+// temporary directory under SpillDir (default os.TempDir()), removed by Close.
+type Dedupe struct {
+	Columns  []string
+	MaxKeys  int
+	SpillDir string
+
+	seen *keyStore
+}
+
+func (t *Dedupe) Name() string { return "dedupe" }
+
+func (t *Dedupe) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
+	keys, err := rowKeys(f, t.Columns)
+	if err != nil {
+		return nil, err
+	}
+	if t.seen == nil {
+		t.seen = newKeyStore(t.MaxKeys, t.SpillDir)
+	}
+	_, found, err := t.seen.lookup(keys)
+	if err != nil {
+		return nil, err
+	}
+	mask := make([]bool, len(keys))
+	local := map[key]bool{}
+	for i, k := range keys {
+		if found[i] || local[k] {
+			continue
+		}
+		local[k], mask[i] = true, true
+	}
+	for k := range local {
+		if err := t.seen.put(k, 0); err != nil {
+			return nil, err
+		}
+	}
+	return f.Filter(mask)
+}
+
+// Reset forgets the keys seen so far.
+func (t *Dedupe) Reset() { _ = t.Close() }
+
+// Close removes spill files and forgets the keys seen so far.
+func (t *Dedupe) Close() error {
+	if t.seen == nil {
+		return nil
+	}
+	return t.seen.close()
+}
+
+// DedupeLast keeps the last row of each distinct key, the values of Columns
+// (all columns when empty). Which row is last depends on the whole input, so
+// DedupeLast is a Fitter: Fit records the position of each key's last row
+// across every fitted frame, and Apply keeps the rows at those positions,
+// counting rows across calls from the first Apply. Unfitted, Apply keeps the
+// last row of each key within the frame. MaxKeys and SpillDir bound memory
+// as for Dedupe.
+type DedupeLast struct {
+	Columns  []string
+	MaxKeys  int
+	SpillDir string
+
+	last   *keyStore
+	fitted bool
+	fitRow int64 // rows seen by Fit
+	row    int64 // rows seen by Apply
+}
+
+func (t *DedupeLast) Name() string { return "dedupe" }
+
+// Fit records the position of the last row of each key in f.
+func (t *DedupeLast) Fit(ctx context.Context, f *j.Frame) error {
+	keys, err := rowKeys(f, t.Columns)
+	if err != nil {
+		return err
+	}
+	if t.last == nil {
+		t.last = newKeyStore(t.MaxKeys, t.SpillDir)
+	}
+	t.fitted = true
+	for i, k := range keys {
+		if err := t.last.put(k, t.fitRow+int64(i)); err != nil {
+			return err
+		}
+	}
+	t.fitRow += int64(len(keys))
+	return nil
+}
+
+func (t *DedupeLast) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
+	keys, err := rowKeys(f, t.Columns)
+	if err != nil {
+		return nil, err
+	}
+	mask := make([]bool, len(keys))
+	if !t.fitted {
+		last := map[key]int{}
+		for i, k := range keys {
+			last[k] = i
+		}
+		for _, i := range last {
+			mask[i] = true
+		}
+		return f.Filter(mask)
+	}
+	rows, found, err := t.last.lookup(keys)
+	if err != nil {
+		return nil, err
+	}
+	for i := range keys {
+		mask[i] = found[i] && rows[i] == t.row+int64(i)
+	}
+	t.row += int64(len(keys))
+	return f.Filter(mask)
+}
+
+// Reset restarts the row count of Apply; fitted positions are kept.
+func (t *DedupeLast) Reset() { t.row = 0 }
+
+// Close removes spill files and forgets the fitted positions.
+func (t *DedupeLast) Close() error {
+	t.fitted, t.fitRow, t.row = false, 0, 0
+	if t.last == nil {
+		return nil
+	}
+	return t.last.close()
+}
@@ -0,0 +1,224 @@
+// Package rows has steps that remove rows from a frame: filter, drop_nulls
+// and dedupe.
+package rows
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	iox "github.com/wdm0006/janitor/pkg/io/ioutils"
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+// Filter keeps the rows matching Where.
+type Filter struct{ Where j.Predicate }
+
+func (t *Filter) Name() string { return "filter" }
+
+// OutputSchema checks Where against in; filtering keeps the schema.
+func (t *Filter) OutputSchema(in j.Schema) (j.Schema, error) {
+	if err := t.Where.Check(in); err != nil {
+		return j.Schema{}, err
+	}
+	return in, nil
+}
+
+func (t *Filter) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
+	mask, err := t.Where.Mask(f)
+	if err != nil {
+		return nil, err
+	}
+	return f.Filter(mask)
+}
+
+// Comparison operators for Compare.
+const (
+	OpEq      = "=="
+	OpNe      = "!="
+	OpLt      = "<"
+	OpLe      = "<="
+	OpGt      = ">"
+	OpGe      = ">="
+	OpIn      = "in"
+	OpNotIn   = "not_in"
+	OpIsNull  = "is_null"
+	OpNotNull = "not_null"
+)
+
+// Compare is a Predicate comparing Column with Value. Value is converted to
+// the column's kind (strings are parsed as times for time columns); for OpIn
+// and OpNotIn it is a list. Null cells match only OpIsNull.
+type Compare struct {
+	Column string
+	Op     string
+	Value  any
+}
+
+// Check validates the operator and value against schema.
+func (c *Compare) Check(schema j.Schema) error {
+	_, err := c.values(schema)
+	return err
+}
+
+func (c *Compare) Mask(f *j.Frame) ([]bool, error) {
+	vals, err := c.values(f.Schema())
+	if err != nil {
+		return nil, err
+	}
+	col, _ := f.ColumnByName(c.Column)
+	mask := make([]bool, f.Rows())
+	for i := range mask {
+		v := j.Value(col, i)
+		switch c.Op {
+		case OpIsNull:
+			mask[i] = v == nil
+			continue
+		case OpNotNull:
+			mask[i] = v != nil
+			continue
+		}
+		if v == nil {
+			continue
+		}
+		switch c.Op {
+		case OpIn, OpNotIn:
+			found := false
+			for _, x := range vals {
+				if compare(v, x) == 0 {
+					found = true
+					break
+				}
+			}
+			mask[i] = found == (c.Op == OpIn)
+		default:
+			d := compare(v, vals[0])
+			switch c.Op {
+			case OpEq:
+				mask[i] = d == 0
+			case OpNe:
+				mask[i] = d != 0
+			case OpLt:
+				mask[i] = d < 0
+			case OpLe:
+				mask[i] = d <= 0
+			case OpGt:
+				mask[i] = d > 0
+			case OpGe:
+				mask[i] = d >= 0
+			}
+		}
+	}
+	return mask, nil
+}
+
+// values checks c against schema and returns its operands converted to the
+// column's kind.
+func (c *Compare) values(schema j.Schema) ([]any, error) {
+	i := schema.Index(c.Column)
+	if i < 0 {
+		return nil, fmt.Errorf("unknown column: %s", c.Column)
+	}
+	kind := schema.Columns[i].Type
+	var raw []any
+	switch c.Op {
+	case OpIsNull, OpNotNull:
+		return nil, nil
+	case OpEq, OpNe, OpLt, OpLe, OpGt, OpGe:
+		if c.Value == nil {
+			return nil, fmt.Errorf("column %q: %s needs a value (use %s or %s for nulls)", c.Column, c.Op, OpIsNull, OpNotNull)
+		}
+		if kind == j.KindBool && c.Op != OpEq && c.Op != OpNe {
+			return nil, fmt.Errorf("column %q: %s does not apply to bool", c.Column, c.Op)
+		}
+		raw = []any{c.Value}
+	case OpIn, OpNotIn:
+		list, ok := c.Value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("column %q: %s needs a list of values", c.Column, c.Op)
+		}
+		raw = list
+	default:
+		return nil, fmt.Errorf("column %q: unknown operator %q", c.Column, c.Op)
+	}
+	times, err := iox.NewTimeParser(nil, "")
+	if err != nil {
+		return nil, err
+	}
+	bools, err := iox.NewBoolParser(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]any, len(raw))
+	for k, v := range raw {
+		if n, ok := v.(int); ok {
+			v = int64(n)
+		}
+		x, ok := iox.Convert(v, kind, times, bools)
+		if !ok {
+			return nil, fmt.Errorf("column %q: value %v does not convert to %s", c.Column, v, kind)
+		}
+		out[k] = x
+	}
+	return out, nil
+}
+
+// compare orders two non-null values of the same kind.
+func compare(a, b any) int {
+	switch x := a.(type) {
+	case int64:
+		y := b.(int64)
+		return cmp3(x < y, x > y)
+	case float64:
+		y := b.(float64)
+		return cmp3(x < y, x > y)
+	case string:
+		return strings.Compare(x, b.(string))
+	case time.Time:
+		return x.Compare(b.(time.Time))
+	case bool:
+		y := b.(bool)
+		return cmp3(!x && y, x && !y)
+	}
+	return 0
+}
+
+func cmp3(less, more bool) int {
+	switch {
+	case less:
+		return -1
+	case more:
+		return 1
+	}
+	return 0
+}
+
+// DropNulls drops rows with a null in any of Columns, or in any column when
+// Columns is empty. Columns the frame does not have are skipped.
+type DropNulls struct{ Columns []string }
+
+func (t *DropNulls) Name() string { return "drop_nulls" }
+
+func (t *DropNulls) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
+	names := t.Columns
+	if len(names) == 0 {
+		names = f.Schema().Names()
+	}
+	mask := make([]bool, f.Rows())
+	for i := range mask {
+		mask[i] = true
+	}
+	for _, name := range names {
+		col, ok := f.ColumnByName(name)
+		if !ok {
+			continue
+		}
+		for i := range mask {
+			if col.IsNull(i) {
+				mask[i] = false
+			}
+		}
+	}
+	return f.Filter(mask)
+}
@@ -0,0 +1,235 @@
+package rows
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+// DefaultMaxKeys is the number of keys a dedupe step keeps in memory before
+// spilling to disk.
+const DefaultMaxKeys = 1 << 20
+
+const recordSize = 24 // 16-byte key hash, 8-byte row number
+
+// key is a 128-bit FNV-1a hash of a row's key columns.
+type key [16]byte
+
+// rowKeys hashes the cells of cols for every row of f.
+func rowKeys(f *j.Frame, names []string) ([]key, error) {
+	if len(names) == 0 {
+		names = f.Schema().Names()
+	}
+	cols := make([]j.Column, len(names))
+	for i, name := range names {
+		c, ok := f.ColumnByName(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown column: %s", name)
+		}
+		cols[i] = c
+	}
+	keys := make([]key, f.Rows())
+	h := fnv.New128a()
+	var buf [9]byte
+	for r := range keys {
+		h.Reset()
+		for _, c := range cols {
+			buf[0] = byte(c.Kind())
+			n := 9
+			switch v := j.Value(c, r).(type) {
+			case nil:
+				buf[0] |= 0x80
+				n = 1
+			case int64:
+				binary.LittleEndian.PutUint64(buf[1:], uint64(v))
+			case float64:
+				binary.LittleEndian.PutUint64(buf[1:], math.Float64bits(v))
+			case bool:
+				buf[1], n = 0, 2
+				if v {
+					buf[1] = 1
+				}
+			case string:
+				binary.LittleEndian.PutUint64(buf[1:], uint64(len(v)))
+				_, _ = h.Write(buf[:9])
+				_, _ = h.Write([]byte(v))
+				continue
+			case time.Time:
+				binary.LittleEndian.PutUint64(buf[1:], uint64(v.UnixNano()))
+			}
+			_, _ = h.Write(buf[:n])
+		}
+		h.Sum(keys[r][:0])
+	}
+	return keys, nil
+}
+
+// keyStore maps key hashes to row numbers. Up to max entries are held in
+// memory; beyond that they are written out as a run: a file of entries
+// sorted by key, with the first key of each block kept in memory. A lookup
+// reads at most one block of each run, so it costs the same however many
+// keys have spilled.
+type keyStore struct {
+	max   int
+	dir   string // parent of the spill directory; "" means os.TempDir()
+	spill string // spill directory, created on first spill
+	mem   map[key]int64
+	runs  []spillRun // oldest first
+}
+
+// spillRun is a sorted file of n entries; index holds the first key of each
+// block of runBlock entries.
+type spillRun struct {
+	path  string
+	n     int
+	index []key
+}
+
+const runBlock = 128
+
+func newKeyStore(max int, dir string) *keyStore {
+	if max <= 0 {
+		max = DefaultMaxKeys
+	}
+	return &keyStore{max: max, dir: dir, mem: map[key]int64{}}
+}
+
+// lookup returns the newest row stored for each of keys, and whether it was
+// found.
+func (s *keyStore) lookup(keys []key) ([]int64, []bool, error) {
+	rows := make([]int64, len(keys))
+	found := make([]bool, len(keys))
+	var misses []int
+	for i, k := range keys {
+		if r, ok := s.mem[k]; ok {
+			rows[i], found[i] = r, true
+		} else if len(s.runs) > 0 {
+			misses = append(misses, i)
+		}
+	}
+	// in key order, misses falling in the same block share one read
+	sort.Slice(misses, func(a, b int) bool { return lessKey(keys[misses[a]], keys[misses[b]]) })
+	for r := len(s.runs) - 1; r >= 0 && len(misses) > 0; r-- {
+		var err error
+		if misses, err = s.runs[r].lookup(keys, misses, rows, found); err != nil {
+			return nil, nil, err
+		}
+	}
+	return rows, found, nil
+}
+
+// lookup looks up the keys at idx, sorted by key, filling rows and found. It
+// returns those not in the run.
+func (run *spillRun) lookup(keys []key, idx []int, rows []int64, found []bool) ([]int, error) {
+	f, err := os.Open(run.path)
+	if err != nil {
+		return nil, fmt.Errorf("dedupe spill: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	rest := idx[:0]
+	buf := make([]byte, runBlock*recordSize)
+	var block []byte
+	loaded := -1
+	for _, i := range idx {
+		k := keys[i]
+		b := sort.Search(len(run.index), func(x int) bool { return lessKey(k, run.index[x]) }) - 1
+		if b < 0 {
+			rest = append(rest, i)
+			continue
+		}
+		if b != loaded {
+			n := min(runBlock, run.n-b*runBlock)
+			block = buf[:n*recordSize]
+			if _, err := f.ReadAt(block, int64(b*runBlock*recordSize)); err != nil {
+				return nil, fmt.Errorf("dedupe spill: %w", err)
+			}
+			loaded = b
+		}
+		n := len(block) / recordSize
+		e := sort.Search(n, func(x int) bool { return bytes.Compare(block[x*recordSize:x*recordSize+16], k[:]) >= 0 })
+		if e < n && bytes.Equal(block[e*recordSize:e*recordSize+16], k[:]) {
+			rows[i], found[i] = int64(binary.LittleEndian.Uint64(block[e*recordSize+16:])), true
+		} else {
+			rest = append(rest, i)
+		}
+	}
+	return rest, nil
+}
+
+func lessKey(a, b key) bool { return bytes.Compare(a[:], b[:]) < 0 }
+
+// put records row for k, replacing any earlier row.
+func (s *keyStore) put(k key, row int64) error {
+	s.mem[k] = row
+	if len(s.mem) <= s.max {
+		return nil
+	}
+	return s.flush()
+}
+
+// flush writes the in-memory entries as a new run and empties memory. Newer
+// runs replace the entries of older ones.
+func (s *keyStore) flush() error {
+	if s.spill == "" {
+		dir, err := os.MkdirTemp(s.dir, "janitor-dedupe-")
+		if err != nil {
+			return fmt.Errorf("dedupe spill: %w", err)
+		}
+		s.spill = dir
+	}
+	ks := make([]key, 0, len(s.mem))
+	for k := range s.mem {
+		ks = append(ks, k)
+	}
+	sort.Slice(ks, func(a, b int) bool { return lessKey(ks[a], ks[b]) })
+	run := spillRun{path: filepath.Join(s.spill, fmt.Sprintf("%06d.keys", len(s.runs))), n: len(ks)}
+	f, err := os.OpenFile(run.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("dedupe spill: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	var rec [recordSize]byte
+	for i, k := range ks {
+		if i%runBlock == 0 {
+			run.index = append(run.index, k)
+		}
+		copy(rec[:16], k[:])
+		binary.LittleEndian.PutUint64(rec[16:], uint64(s.mem[k]))
+		if _, err = w.Write(rec[:]); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("dedupe spill: %w", err)
+	}
+	s.runs = append(s.runs, run)
+	s.mem = map[key]int64{}
+	return nil
+}
+
+// close removes the spill directory and empties the store.
+func (s *keyStore) close() error {
+	s.mem = map[key]int64{}
+	s.runs = nil
+	if s.spill == "" {
+		return nil
+	}
+	err := os.RemoveAll(s.spill)
+	s.spill = ""
+	return err
+}
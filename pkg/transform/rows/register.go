@@ -0,0 +1,69 @@
+package rows
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wdm0006/janitor/pkg/expr"
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+type filterParams struct {
+	Expr   string `json:"expr" doc:"keep rows where this expression is true"`
+	Column string `json:"column" doc:"column to compare, instead of expr"`
+	Op     string `json:"op" enum:"==,!=,<,<=,>,>=,in,not_in,is_null,not_null" doc:"==, !=, <, <=, >, >=, in, not_in, is_null or not_null"`
+	Value  any    `json:"value" doc:"value to compare with, converted to the column type; a list for in and not_in"`
+}
+
+type dropNullsParams struct {
+	Columns j.Selector `json:"columns" doc:"columns that must not be null (default: all)"`
+}
+
+type dedupeParams struct {
+	Columns  j.Selector `json:"columns" doc:"key columns (default: all)"`
+	Keep     string     `json:"keep" enum:"first,last" doc:"first (default) or last"`
+	MaxKeys  int        `json:"max_keys" doc:"keys kept in memory before spilling to disk (default 1048576)"`
+	SpillDir string     `json:"spill_dir" doc:"directory for spill files (default: the system temp directory)"`
+}
+
+func init() {
+	j.RegisterStep("filter", "Keep rows matching an expression or a column comparison", func(p *filterParams, b *j.StepBuilder) error {
+		if p.Expr == "" {
+			b.Add(&Filter{Where: &Compare{Column: p.Column, Op: p.Op, Value: p.Value}}, "filter:"+p.Column)
+			return nil
+		}
+		if p.Column != "" {
+			return fmt.Errorf("use either expr or column/op/value")
+		}
+		e, err := expr.Parse(p.Expr)
+		if err != nil {
+			return err
+		}
+		b.Add(&Filter{Where: e}, "filter:"+p.Expr)
+		return nil
+	})
+	j.RegisterStep("drop_nulls", "Drop rows with a null in any of these columns", func(p *dropNullsParams, b *j.StepBuilder) error {
+		names, err := b.Columns(p.Columns)
+		if err != nil {
+			return err
+		}
+		b.Add(&DropNulls{Columns: names}, "drop_nulls:"+strings.Join(names, ","))
+		return nil
+	})
+	j.RegisterStep("dedupe", "Keep one row per distinct key", func(p *dedupeParams, b *j.StepBuilder) error {
+		names, err := b.Columns(p.Columns)
+		if err != nil {
+			return err
+		}
+		label := "dedupe:" + strings.Join(names, ",")
+		switch p.Keep {
+		case "", "first":
+			b.Add(&Dedupe{Columns: names, MaxKeys: p.MaxKeys, SpillDir: p.SpillDir}, label)
+		case "last":
+			b.Add(&DedupeLast{Columns: names, MaxKeys: p.MaxKeys, SpillDir: p.SpillDir}, label)
+		default:
+			return fmt.Errorf("unknown keep %q (want first or last)", p.Keep)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,198 @@
+package rows
+
+import (
+	"context"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+
+	j "github.com/wdm0006/janitor/pkg/janitor"
+)
+
+// frame builds an (id int, name string) frame; a negative id or empty name is null.
+func frame(t *testing.T, ids []int64, names []string) *j.Frame {
+	t.Helper()
+	f := j.NewFrame(j.Schema{Columns: []j.ColumnSchema{
+		{Name: "id", Type: j.KindInt, Nullable: true},
+		{Name: "name", Type: j.KindString, Nullable: true},
+	}})
+	for i := range ids {
+		f.AppendNullRow()
+		if ids[i] >= 0 {
+			_ = f.SetCell(i, "id", ids[i])
+		}
+		if names[i] != "" {
+			_ = f.SetCell(i, "name", names[i])
+		}
+	}
+	return f
+}
+
+func column(f *j.Frame, name string) []any {
+	c, _ := f.ColumnByName(name)
+	out := make([]any, f.Rows())
+	for i := range out {
+		out[i] = j.Value(c, i)
+	}
+	return out
+}
+
+type chunks struct{ frames []*j.Frame }
+
+func (c *chunks) Next() (*j.Frame, error) {
+	if len(c.frames) == 0 {
+		return nil, io.EOF
+	}
+	f := c.frames[0]
+	c.frames = c.frames[1:]
+	return f, nil
+}
+
+func TestFilterAndDropNulls(t *testing.T) {
+	ctx := context.Background()
+	f := frame(t, []int64{1, 2, -1, 4}, []string{"a", "", "c", "d"})
+	out, err := (&Filter{Where: &Compare{Column: "id", Op: OpGe, Value: 2.0}}).Apply(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := column(out, "id"); !reflect.DeepEqual(got, []any{int64(2), int64(4)}) {
+		t.Fatalf("id >= 2 kept %v", got)
+	}
+	out, err = (&Filter{Where: &Compare{Column: "name", Op: OpNotIn, Value: []any{"a", "d"}}}).Apply(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := column(out, "name"); !reflect.DeepEqual(got, []any{"c"}) {
+		t.Fatalf("name not in (a, d) kept %v", got)
+	}
+	if _, err := (&Filter{Where: &Compare{Column: "id", Op: OpEq, Value: "x"}}).Apply(ctx, f); err == nil {
+		t.Fatal("expected an error comparing an int column with a string")
+	}
+	out, err = (&DropNulls{Columns: []string{"name"}}).Apply(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Rows() != 3 {
+		t.Fatalf("drop_nulls(name) kept %d rows", out.Rows())
+	}
+	out, err = (&DropNulls{}).Apply(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := column(out, "id"); !reflect.DeepEqual(got, []any{int64(1), int64(4)}) {
+		t.Fatalf("drop_nulls() kept %v", got)
+	}
+}
+
+func TestDedupeAcrossChunks(t *testing.T) {
+	ctx := context.Background()
+	d := &Dedupe{Columns: []string{"name"}, MaxKeys: 2, SpillDir: t.TempDir()}
+	p := j.NewPipeline().Add(d)
+	var kept []any
+	for _, f := range []*j.Frame{
+		frame(t, []int64{1, 2, 3}, []string{"a", "b", "a"}),
+		frame(t, []int64{4, 5, 6}, []string{"c", "b", "d"}),
+		frame(t, []int64{7, 8}, []string{"", ""}),
+	} {
+		out, err := p.Run(ctx, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		kept = append(kept, column(out, "id")...)
+	}
+	if want := []any{int64(1), int64(2), int64(4), int64(6), int64(7)}; !reflect.DeepEqual(kept, want) {
+		t.Fatalf("kept %v, want %v", kept, want)
+	}
+	if d.seen.spill == "" {
+		t.Fatal("expected keys to spill past MaxKeys")
+	}
+	dir := d.seen.spill
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("spill directory left behind: %v", err)
+	}
+}
+
+func TestKeyStoreRuns(t *testing.T) {
+	s := newKeyStore(300, t.TempDir())
+	defer func() { _ = s.close() }()
+	want := map[key]int64{}
+	var keys []key
+	for i := 0; i < 2000; i++ {
+		var k key
+		k[0], k[1], k[15] = byte(i*7), byte(i), byte(i>>8)
+		row := int64(i)
+		if i%3 == 0 && i > 0 {
+			// store an earlier key again; the newest row wins
+			k, row = keys[i/2], int64(10000+i)
+		} else {
+			keys = append(keys, k)
+		}
+		if err := s.put(k, row); err != nil {
+			t.Fatal(err)
+		}
+		want[k] = row
+	}
+	if len(s.runs) < 2 {
+		t.Fatalf("expected several runs, got %d", len(s.runs))
+	}
+	absent := key{0xff, 0xff, 0xff}
+	rows, found, err := s.lookup(append(keys, absent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, k := range keys {
+		if !found[i] || rows[i] != want[k] {
+			t.Fatalf("key %d: got %d (found %v), want %d", i, rows[i], found[i], want[k])
+		}
+	}
+	if found[len(keys)] {
+		t.Fatal("found a key never stored")
+	}
+}
+
+func TestDedupeLastStreaming(t *testing.T) {
+	ctx := context.Background()
+	input := func() *chunks {
+		return &chunks{frames: []*j.Frame{
+			frame(t, []int64{1, 2, 3}, []string{"a", "b", "a"}),
+			frame(t, []int64{4, 5}, []string{"c", "a"}),
+		}}
+	}
+	d := &DedupeLast{Columns: []string{"name"}, MaxKeys: 1, SpillDir: t.TempDir()}
+	// a keep-first dedupe on id before the fitter must start fresh in the transform pass
+	p := j.NewPipeline().Add(&Dedupe{Columns: []string{"id"}}).Add(d)
+	defer func() { _ = p.Close() }()
+	if err := p.FitStream(ctx, input()); err != nil {
+		t.Fatal(err)
+	}
+	p.FinishFit()
+	var kept []any
+	src := input()
+	for {
+		f, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		out, err := p.Run(ctx, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		kept = append(kept, column(out, "id")...)
+	}
+	if want := []any{int64(2), int64(4), int64(5)}; !reflect.DeepEqual(kept, want) {
+		t.Fatalf("kept %v, want %v", kept, want)
+	}
+
+	unfitted := &DedupeLast{Columns: []string{"name"}}
+	out, err := unfitted.Apply(ctx, frame(t, []int64{1, 2, 3}, []string{"a", "b", "a"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := column(out, "id"); !reflect.DeepEqual(got, []any{int64(2), int64(3)}) {
+		t.Fatalf("unfitted kept %v", got)
+	}
+}
@@ -10,6 +10,8 @@ type Lower struct{ Column string }
 
 func (t *Lower) Name() string { return "lower" }
 
+func (t *Lower) CheckSchema(in j.Schema) error { return j.CheckColumn(in, t.Column, j.KindString) }
+
 func (t *Lower) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
 	col, ok := f.ColumnByName(t.Column)
 	if !ok {
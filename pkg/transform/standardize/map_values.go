@@ -2,6 +2,7 @@ package standardize
 
 import (
 	"context"
+	"fmt"
 	j "github.com/wdm0006/janitor/pkg/janitor"
 )
 
@@ -12,6 +13,13 @@ type MapValues struct {
 
 func (t *MapValues) Name() string { return "map_values" }
 
+func (t *MapValues) CheckSchema(in j.Schema) error {
+	if len(t.Map) == 0 {
+		return fmt.Errorf("map is required")
+	}
+	return j.CheckColumn(in, t.Column, j.KindString)
+}
+
 func (t *MapValues) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
 	col, ok := f.ColumnByName(t.Column)
 	if !ok {
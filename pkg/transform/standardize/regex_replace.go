@@ -15,6 +15,13 @@ type RegexReplace struct {
 
 func (t *RegexReplace) Name() string { return "regex_replace" }
 
+func (t *RegexReplace) CheckSchema(in j.Schema) error {
+	if _, err := regexp.Compile(t.Pattern); err != nil {
+		return err
+	}
+	return j.CheckColumn(in, t.Column, j.KindString)
+}
+
 func (t *RegexReplace) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
 	if t.re == nil {
 		re, err := regexp.Compile(t.Pattern)
@@ -0,0 +1,31 @@
+package standardize
+
+import j "github.com/wdm0006/janitor/pkg/janitor"
+
+type regexReplaceParams struct {
+	j.ColumnParams
+	Pattern string `json:"pattern" required:"true" doc:"regular expression (Go RE2 syntax)"`
+	Replace string `json:"replace" doc:"replacement; $1 or ${name} insert groups"`
+}
+
+type mapValuesParams struct {
+	j.ColumnParams
+	Map map[string]string `json:"map" required:"true" doc:"old value to new value; other values are kept"`
+}
+
+func init() {
+	j.RegisterStep("trim", "Strip leading and trailing whitespace (string columns)", func(p *j.ColumnParams, b *j.StepBuilder) error {
+		return b.EachColumn(*p, func(c string) j.Transform { return &Trim{Column: c} })
+	})
+	j.RegisterStep("lower", "Lowercase strings (string columns)", func(p *j.ColumnParams, b *j.StepBuilder) error {
+		return b.EachColumn(*p, func(c string) j.Transform { return &Lower{Column: c} })
+	})
+	j.RegisterStep("regex_replace", "Replace regular expression matches (string columns)", func(p *regexReplaceParams, b *j.StepBuilder) error {
+		return b.EachColumn(p.ColumnParams, func(c string) j.Transform {
+			return &RegexReplace{Column: c, Pattern: p.Pattern, Replace: p.Replace}
+		})
+	})
+	j.RegisterStep("map_values", "Replace values by lookup (string columns)", func(p *mapValuesParams, b *j.StepBuilder) error {
+		return b.EachColumn(p.ColumnParams, func(c string) j.Transform { return &MapValues{Column: c, Map: p.Map} })
+	})
+}
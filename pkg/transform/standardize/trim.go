@@ -10,6 +10,8 @@ type Trim struct{ Column string }
 
 func (t *Trim) Name() string { return "trim" }
 
+func (t *Trim) CheckSchema(in j.Schema) error { return j.CheckColumn(in, t.Column, j.KindString) }
+
 func (t *Trim) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
 	col, ok := f.ColumnByName(t.Column)
 	if !ok {
@@ -9,6 +9,7 @@ import (
 type InSet struct {
 	Column string
 	Values map[string]struct{}
+	OnFail j.Action // what a Pipeline does with failing rows; default fail
 }
 
 func NewInSet(col string, vals []string) *InSet {
@@ -21,27 +22,45 @@ func NewInSet(col string, vals []string) *InSet {
 
 func (t *InSet) Name() string { return "validate_in" }
 
-func (t *InSet) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
+func (t *InSet) CheckSchema(in j.Schema) error {
+	if len(t.Values) == 0 {
+		return fmt.Errorf("values is required")
+	}
+	return j.CheckColumn(in, t.Column, j.KindString)
+}
+
+func (t *InSet) FailAction() j.Action { return t.OnFail }
+
+// Check reports every non-null value outside the allowed set.
+func (t *InSet) Check(ctx context.Context, f *j.Frame) ([]j.Violation, error) {
 	col, ok := f.ColumnByName(t.Column)
 	if !ok {
-		return f, nil
+		return nil, nil
 	}
 	sc, ok := col.(*j.StringColumn)
 	if !ok {
-		return f, nil
+		return nil, nil
 	}
-	var bad int
+	var vs []j.Violation
 	for i := 0; i < sc.Len(); i++ {
 		if sc.IsNull(i) {
 			continue
 		}
 		v, _ := sc.Get(i)
 		if _, ok := t.Values[v]; !ok {
-			bad++
+			vs = append(vs, j.Violation{Row: i, Column: t.Column, Value: v, Reason: "not in allowed set"})
 		}
 	}
-	if bad > 0 {
-		return f, fmt.Errorf("validate_in: column %s has %d values outside allowed set", t.Column, bad)
+	return vs, nil
+}
+
+func (t *InSet) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
+	vs, err := t.Check(ctx, f)
+	if err != nil {
+		return f, err
+	}
+	if len(vs) > 0 {
+		return f, fmt.Errorf("validate_in: column %s has %d values outside allowed set", t.Column, len(vs))
 	}
 	return f, nil
 }
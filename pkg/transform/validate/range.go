@@ -4,52 +4,76 @@ import (
 	"context"
 	"fmt"
 	j "github.com/wdm0006/janitor/pkg/janitor"
+	"strconv"
 )
 
 type Range struct {
 	Column string
 	Min    *float64
 	Max    *float64
+	OnFail j.Action // what a Pipeline does with failing rows; default fail
 }
 
 func (t *Range) Name() string { return "validate_range" }
 
-func (t *Range) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
+func (t *Range) CheckSchema(in j.Schema) error {
+	if err := checkBounds(t.Min, t.Max); err != nil {
+		return err
+	}
+	return j.CheckColumn(in, t.Column, j.KindFloat, j.KindInt)
+}
+
+// checkBounds requires at least one of min and max, in order.
+func checkBounds(min, max *float64) error {
+	if min == nil && max == nil {
+		return fmt.Errorf("min or max is required")
+	}
+	if min != nil && max != nil && *min > *max {
+		return fmt.Errorf("min %g is above max %g", *min, *max)
+	}
+	return nil
+}
+
+func (t *Range) FailAction() j.Action { return t.OnFail }
+
+// Check reports every non-null value outside [Min, Max].
+func (t *Range) Check(ctx context.Context, f *j.Frame) ([]j.Violation, error) {
 	col, ok := f.ColumnByName(t.Column)
 	if !ok {
-		return f, nil
+		return nil, nil
+	}
+	var vs []j.Violation
+	check := func(i int, v float64, raw string) {
+		if t.Min != nil && v < *t.Min {
+			vs = append(vs, j.Violation{Row: i, Column: t.Column, Value: raw, Reason: fmt.Sprintf("below min %g", *t.Min)})
+		} else if t.Max != nil && v > *t.Max {
+			vs = append(vs, j.Violation{Row: i, Column: t.Column, Value: raw, Reason: fmt.Sprintf("above max %g", *t.Max)})
+		}
 	}
-	var bad int
 	switch c := col.(type) {
 	case *j.FloatColumn:
 		for i := 0; i < c.Len(); i++ {
-			if c.IsNull(i) {
-				continue
-			}
-			v, _ := c.Get(i)
-			if t.Min != nil && v < *t.Min {
-				bad++
-			}
-			if t.Max != nil && v > *t.Max {
-				bad++
+			if v, ok := c.Get(i); ok {
+				check(i, v, strconv.FormatFloat(v, 'g', -1, 64))
 			}
 		}
 	case *j.IntColumn:
 		for i := 0; i < c.Len(); i++ {
-			if c.IsNull(i) {
-				continue
-			}
-			v, _ := c.Get(i)
-			if t.Min != nil && float64(v) < *t.Min {
-				bad++
-			}
-			if t.Max != nil && float64(v) > *t.Max {
-				bad++
+			if v, ok := c.Get(i); ok {
+				check(i, float64(v), strconv.FormatInt(v, 10))
 			}
 		}
 	}
-	if bad > 0 {
-		return f, fmt.Errorf("validate_range: column %s has %d out-of-range values", t.Column, bad)
+	return vs, nil
+}
+
+func (t *Range) Apply(ctx context.Context, f *j.Frame) (*j.Frame, error) {
+	vs, err := t.Check(ctx, f)
+	if err != nil {
+		return f, err
+	}
+	if len(vs) > 0 {
+		return f, fmt.Errorf("validate_range: column %s has %d out-of-range values", t.Column, len(vs))
 	}
 	return f, nil
 }
@@ -0,0 +1,39 @@
+package validate
+
+import j "github.com/wdm0006/janitor/pkg/janitor"
+
+type inSetParams struct {
+	j.ColumnParams
+	Values []string `json:"values" required:"true" doc:"allowed values"`
+	OnFail string   `json:"on_fail" enum:"fail,warn,quarantine" doc:"fail (default), warn or quarantine"`
+}
+
+type rangeParams struct {
+	j.ColumnParams
+	Min    *float64 `json:"min" doc:"lowest allowed value"`
+	Max    *float64 `json:"max" doc:"highest allowed value"`
+	OnFail string   `json:"on_fail" enum:"fail,warn,quarantine" doc:"fail (default), warn or quarantine"`
+}
+
+func init() {
+	j.RegisterStep("validate_in", "Check that strings are in an allowed set", func(p *inSetParams, b *j.StepBuilder) error {
+		act, err := j.ParseAction(p.OnFail)
+		if err != nil {
+			return err
+		}
+		return b.EachColumn(p.ColumnParams, func(c string) j.Transform {
+			t := NewInSet(c, p.Values)
+			t.OnFail = act
+			return t
+		})
+	})
+	j.RegisterStep("validate_range", "Check that numbers are within [min, max]", func(p *rangeParams, b *j.StepBuilder) error {
+		act, err := j.ParseAction(p.OnFail)
+		if err != nil {
+			return err
+		}
+		return b.EachColumn(p.ColumnParams, func(c string) j.Transform {
+			return &Range{Column: c, Min: p.Min, Max: p.Max, OnFail: act}
+		})
+	})
+}